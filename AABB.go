@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB is an axis-aligned bounding box
+type AABB struct {
+	Min mgl32.Vec3
+	Max mgl32.Vec3
+}
+
+// InfiniteAABB contains every point
+var InfiniteAABB = AABB{
+	Min: mgl32.Vec3{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32},
+	Max: mgl32.Vec3{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32},
+}
+
+// NewAABB creates an AABB from its center and the half-extent along each axis
+func NewAABB(center, size mgl32.Vec3) AABB {
+	return AABB{
+		Min: center.Sub(size),
+		Max: center.Add(size),
+	}
+}
+
+func (box AABB) Center() mgl32.Vec3 {
+	return box.Min.Add(box.Max).Mul(0.5)
+}
+
+// Size returns the half-extent along each axis
+func (box AABB) Size() mgl32.Vec3 {
+	return box.Max.Sub(box.Min).Mul(0.5)
+}
+
+func (box AABB) Union(other AABB) AABB {
+	for i := 0; i < 3; i++ {
+		box.Min[i] = float32(math.Min(float64(box.Min[i]), float64(other.Min[i])))
+		box.Max[i] = float32(math.Max(float64(box.Max[i]), float64(other.Max[i])))
+	}
+	return box
+}
+
+// transformBounds rotates then moves every corner of an AABB, and finds the
+// AABB around them
+func transformBounds(bounds AABB, rot mgl32.Quat, pos mgl32.Vec3) AABB {
+	result := AABB{}
+	for corner := 0; corner < 8; corner++ {
+		point := bounds.Min
+		for i := 0; i < 3; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				point[i] = bounds.Max[i]
+			}
+		}
+		point = pos.Add(rot.Rotate(point))
+		if corner == 0 {
+			result = AABB{Min: point, Max: point}
+		} else {
+			result = result.Union(AABB{Min: point, Max: point})
+		}
+	}
+	return result
+}
+
+func (box AABB) Overlaps(other AABB) bool {
+	for i := 0; i < 3; i++ {
+		if box.Min[i] > other.Max[i] || box.Max[i] < other.Min[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (box AABB) Contains(point mgl32.Vec3) bool {
+	for i := 0; i < 3; i++ {
+		if point[i] < box.Min[i] || point[i] > box.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectRay finds where a ray enters the box using the slab method. The
+// direction doesn't need to be normalized, distances are in multiples of it
+func (box AABB) IntersectRay(origin, direction mgl32.Vec3, maxDist float32) (float32, bool) {
+	tmin := float32(0.0)
+	tmax := maxDist
+
+	for i := 0; i < 3; i++ {
+		if direction[i] == 0.0 {
+			if origin[i] < box.Min[i] || origin[i] > box.Max[i] {
+				return 0.0, false
+			}
+			continue
+		}
+
+		inv := 1.0 / direction[i]
+		t1 := (box.Min[i] - origin[i]) * inv
+		t2 := (box.Max[i] - origin[i]) * inv
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tmin {
+			tmin = t1
+		}
+		if t2 < tmax {
+			tmax = t2
+		}
+		if tmin > tmax {
+			return 0.0, false
+		}
+	}
+	return tmin, true
+}
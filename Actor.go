@@ -7,23 +7,21 @@ import (
 
 type Transform struct {
 	Position mgl32.Vec3
-	Rotation mgl32.Vec3
+	Rotation mgl32.Quat
 	Scale    mgl32.Vec3
 }
 
 func NewTransform() Transform {
 	return Transform{
 		Position: mgl32.Vec3{0, 0, 0},
-		Rotation: mgl32.Vec3{0, 0, 0},
+		Rotation: mgl32.QuatIdent(),
 		Scale:    mgl32.Vec3{1, 1, 1},
 	}
 }
 
 func (t Transform) GetMatrix() mgl32.Mat4 {
 	return mgl32.Translate3D(t.Position.X(), t.Position.Y(), t.Position.Z()).
-		Mul4(mgl32.Rotate3DX(t.Rotation.X()).Mat4()).
-		Mul4(mgl32.Rotate3DX(t.Rotation.Y()).Mat4()).
-		Mul4(mgl32.Rotate3DX(t.Rotation.Z()).Mat4()).
+		Mul4(t.Rotation.Mat4()).
 		Mul4(mgl32.Scale3D(t.Scale.X(), t.Scale.Y(), t.Scale.Z()))
 }
 
@@ -31,13 +29,20 @@ type Actor struct {
 	Transform Transform
 	RigidBody *RigidBody
 
-	models []*Model
+	models []actorModel
+}
+
+// actorModel is a Model drawn by an Actor, placed relative to it by transform.
+// Models can be shared between Actors, each with their own transform
+type actorModel struct {
+	model     *Model
+	transform mgl32.Mat4
 }
 
 func NewActor() *Actor {
 	actor := &Actor{
 		Transform: NewTransform(),
-		models:    []*Model{},
+		models:    []actorModel{},
 		RigidBody: NewRigidBody(),
 	}
 	actor.RigidBody.Parent = actor
@@ -51,13 +56,18 @@ func (actor *Actor) Cleanup() {
 	actor.RigidBody = nil
 
 	for i := range actor.models {
-		actor.models[i].Cleanup()
-		actor.models[i] = nil
+		actor.models[i].model.Cleanup()
+		actor.models[i].model = nil
 	}
 }
 
 func (actor *Actor) AddModel(model *Model) {
-	actor.models = append(actor.models, model)
+	actor.AddModelAt(model, mgl32.Ident4())
+}
+
+// AddModelAt adds a Model placed relative to the Actor by transform
+func (actor *Actor) AddModelAt(model *Model, transform mgl32.Mat4) {
+	actor.models = append(actor.models, actorModel{model: model, transform: transform})
 }
 
 func (actor *Actor) Update(delta, elapsed float32) {
@@ -67,10 +77,10 @@ func (actor *Actor) Update(delta, elapsed float32) {
 func (actor *Actor) Render(shader *Shader) {
 	shader.Use()
 
-	model := actor.Transform.GetMatrix()
-	gl.UniformMatrix4fv(shader.GetUniformLocation("u_Model"), 1, false, &model[0])
-
+	transform := actor.Transform.GetMatrix()
 	for i := 0; i < len(actor.models); i++ {
-		actor.models[i].Render(shader)
+		model := transform.Mul4(actor.models[i].transform)
+		gl.UniformMatrix4fv(shader.GetUniformLocation("u_Model"), 1, false, &model[0])
+		actor.models[i].model.Render(shader)
 	}
 }
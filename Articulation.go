@@ -0,0 +1,514 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// How many times each step refines its guess at the new velocities
+const articulationIterations = 3
+
+// JointType is how an ArticulationLink moves relative to its parent
+type JointType int
+
+const (
+	// RevoluteJoint turns about Axis
+	RevoluteJoint JointType = iota
+	// PrismaticJoint slides along Axis
+	PrismaticJoint
+	// FixedJoint holds the link still relative to its parent
+	FixedJoint
+)
+
+// ArticulationLink is one body of an Articulation, joined to its parent by a
+// joint with a single degree of freedom
+type ArticulationLink struct {
+	// Actor is drawn and collided where the link is. Its RigidBody gives the
+	// link its Mass, CenterOfMass and Inertia, and shouldn't have gravity of
+	// its own, as the Articulation applies Gravity
+	Actor  *Actor
+	Parent *ArticulationLink
+	Joint  JointType
+
+	// JointOffset and JointRotation place the joint in the parent's frame.
+	// The link's frame starts out lined up with the joint's
+	JointOffset   mgl32.Vec3
+	JointRotation mgl32.Quat
+	// Axis is what the joint turns about or slides along, in the joint's frame
+	Axis mgl32.Vec3
+
+	// Position is the joint's angle in radians, or how far it has slid, and
+	// Velocity is how fast that changes per frame
+	Position float32
+	Velocity float32
+	// Force is the torque, or force, driving the joint. It keeps being
+	// applied until it is changed
+	Force float32
+	// Damping resists the joint moving
+	Damping float32
+
+	// DriveStiffness and DriveDamping pull the joint towards Target like a
+	// spring, to use the joint as a servo
+	Target         float32
+	DriveStiffness float32
+	DriveDamping   float32
+
+	// Limited keeps Position between Lower and Upper
+	Limited bool
+	Lower   float32
+	Upper   float32
+
+	// Pose in the world
+	rotation mgl32.Quat
+	origin   mgl32.Vec3
+
+	// Featherstone's quantities, all in world space about the root's origin
+	motion       spatialVector // S, the motion of a unit of Velocity
+	velocity     spatialVector
+	bias         spatialVector // c, from velocity changing direction
+	inertia      spatialMatrix // I^A, the articulated body inertia
+	biasForce    spatialVector // p^A, the articulated body bias force
+	inertiaAxis  spatialVector // U = I^A S
+	axisInertia  float32       // D = S U
+	jointForce   float32       // u = tau - S p^A
+	acceleration spatialVector
+	// How fast Velocity is changing, and what it was at the start of the step
+	jointAcceleration float32
+	stepVelocity      float32
+	// Held still for the step, as it is against a limit
+	locked bool
+
+	// Impulses from contacts, about the center of mass
+	impulse        mgl32.Vec3
+	angularImpulse mgl32.Vec3
+	// What the RigidBody's velocity was set to, to find what contacts changed
+	// it by
+	bodyVelocity        mgl32.Vec3
+	bodyAngularVelocity mgl32.Vec3
+
+	// The impulse the parent passed on through the joint during the last
+	// Update, as a torque about origin and a force. Each step's share is
+	// turned into the link's frame as it was then
+	jointImpulse spatialVector
+}
+
+// Articulation is a tree of bodies joined by revolute and prismatic joints,
+// simulated in reduced coordinates with Featherstone's articulated body
+// algorithm. Joints are exact, where constraints between separate RigidBodies
+// drift apart. The link Actors go in the World as normal, contacts push on
+// their RigidBodies and are passed on to the Articulation
+type Articulation struct {
+	// Links has parents before their children, and the root first
+	Links []*ArticulationLink
+	// FixedBase pins the root in place, like a robot arm bolted down.
+	// Otherwise the root moves freely, like the hips of a ragdoll
+	FixedBase bool
+
+	Gravity mgl32.Vec3
+	// Substeps is how many steps are taken every Update. More are more
+	// accurate and steadier with stiff drives, but links resting on something
+	// sink into it a little further
+	Substeps int
+
+	// Velocity of the root's origin and AngularVelocity of the root, per
+	// frame like a RigidBody's
+	Velocity        mgl32.Vec3
+	AngularVelocity mgl32.Vec3
+}
+
+// NewArticulation creates an Articulation with root as its only link, where
+// it is now
+func NewArticulation(root *Actor, fixedBase bool) *Articulation {
+	link := &ArticulationLink{
+		Actor:         root,
+		Joint:         FixedJoint,
+		JointRotation: mgl32.QuatIdent(),
+		rotation:      root.Transform.Rotation,
+		origin:        root.Transform.Position,
+	}
+	if fixedBase {
+		root.RigidBody.Mass = math.MaxFloat32
+	}
+
+	return &Articulation{
+		Links:     []*ArticulationLink{link},
+		FixedBase: fixedBase,
+		Gravity:   mgl32.Vec3{0, -9.81, 0},
+		Substeps:  1,
+	}
+}
+
+// Root returns the first link, which every other link hangs from
+func (a *Articulation) Root() *ArticulationLink {
+	return a.Links[0]
+}
+
+// AddLink joins actor to parent with a joint at offset in the parent's frame,
+// turning about or sliding along axis. The actor is moved to where the joint
+// puts it, and stops colliding with parent
+func (a *Articulation) AddLink(parent *ArticulationLink, actor *Actor, joint JointType, offset, axis mgl32.Vec3) *ArticulationLink {
+	link := &ArticulationLink{
+		Actor:         actor,
+		Parent:        parent,
+		Joint:         joint,
+		JointOffset:   offset,
+		JointRotation: mgl32.QuatIdent(),
+		Axis:          axis,
+	}
+	a.Links = append(a.Links, link)
+	actor.RigidBody.IgnoreCollision(parent.Actor.RigidBody, true)
+
+	a.place()
+	actor.Transform.Position = link.origin
+	actor.Transform.Rotation = link.rotation
+	return link
+}
+
+// Update steps the joints forwards by a frame, and moves the link Actors to
+// match. It runs after the Actors have moved, so the contacts found this frame
+// are where the links now are
+func (a *Articulation) Update(delta, elapsed float32) {
+	if delta <= 0.0 || len(a.Links) == 0 {
+		return
+	}
+
+	substeps := a.Substeps
+	if substeps < 1 {
+		substeps = 1
+	}
+	// Velocities are per frame, but forces accelerate per second, so forces
+	// are scaled by the length of a frame
+	scale := elapsed / delta
+	for _, link := range a.Links {
+		link.jointImpulse = spatialVector{}
+	}
+	for i := 0; i < substeps; i++ {
+		a.step(delta/float32(substeps), scale)
+	}
+
+	a.place()
+	a.updateVelocities(a.Root().origin)
+	for _, link := range a.Links {
+		link.Actor.Transform.Position = link.origin
+		link.Actor.Transform.Rotation = link.rotation
+	}
+	a.updateBodies()
+}
+
+// resolveContacts picks up what the contact solver did to each link's
+// RigidBody, as if it were free, and passes it on to the joints as impulses
+func (a *Articulation) resolveContacts() {
+	if len(a.Links) == 0 {
+		return
+	}
+
+	touched := false
+	for i, link := range a.Links {
+		link.impulse = mgl32.Vec3{}
+		link.angularImpulse = mgl32.Vec3{}
+		body := link.Actor.RigidBody
+		if (i == 0 && a.FixedBase) || body.InverseMass() == 0.0 {
+			continue
+		}
+
+		link.impulse = body.Velocity.Sub(link.bodyVelocity).Mul(body.Mass)
+		link.angularImpulse = body.InverseInertia().Inv().Mul3x1(body.AngularVelocity.Sub(link.bodyAngularVelocity))
+		touched = touched || link.impulse.Len() > 0.0 || link.angularImpulse.Len() > 0.0
+	}
+	if !touched {
+		return
+	}
+
+	// With the links still, the articulated body algorithm turns impulses
+	// straight into changes in velocity
+	origin := a.Root().origin
+	a.updateVelocities(origin)
+	for _, link := range a.Links {
+		link.stepVelocity = link.Velocity
+		link.locked = false
+	}
+	a.solve(origin, true, 0.0, 1.0)
+	for a.lockLimits(1.0) {
+		a.solve(origin, true, 0.0, 1.0)
+	}
+	a.addJointImpulses(origin, 1.0)
+
+	root := a.Root()
+	if !a.FixedBase {
+		a.AngularVelocity = a.AngularVelocity.Add(root.acceleration.Angular)
+		a.Velocity = a.Velocity.Add(root.acceleration.Linear)
+	}
+	for _, link := range a.Links[1:] {
+		link.Velocity += link.jointAcceleration
+		link.limit()
+	}
+
+	a.updateVelocities(origin)
+	a.updateBodies()
+}
+
+// updateBodies sets the velocity of each link's RigidBody to match the link,
+// and records it to find what contacts change it by
+func (a *Articulation) updateBodies() {
+	root := a.Root()
+	for i, link := range a.Links {
+		body := link.Actor.RigidBody
+		body.Velocity = mgl32.Vec3{}
+		body.AngularVelocity = mgl32.Vec3{}
+		if i > 0 || !a.FixedBase {
+			center := body.WorldCenterOfMass()
+			body.AngularVelocity = link.velocity.Angular
+			body.Velocity = link.velocity.Linear.Add(link.velocity.Angular.Cross(center.Sub(root.origin)))
+		}
+		link.bodyVelocity = body.Velocity
+		link.bodyAngularVelocity = body.AngularVelocity
+	}
+}
+
+// place works out where every link is from the joint positions
+func (a *Articulation) place() {
+	for _, link := range a.Links[1:] {
+		parent := link.Parent
+		jointRotation := parent.rotation.Mul(link.JointRotation)
+		jointOrigin := parent.origin.Add(parent.rotation.Rotate(link.JointOffset))
+		axis := link.Axis.Normalize()
+
+		link.rotation = jointRotation
+		link.origin = jointOrigin
+		switch link.Joint {
+		case RevoluteJoint:
+			link.rotation = jointRotation.Mul(mgl32.QuatRotate(link.Position, axis))
+		case PrismaticJoint:
+			link.origin = jointOrigin.Add(jointRotation.Rotate(axis).Mul(link.Position))
+		}
+	}
+}
+
+// updateVelocities works out each link's motion subspace and spatial
+// velocity, about origin, going out from the root
+func (a *Articulation) updateVelocities(origin mgl32.Vec3) {
+	root := a.Root()
+	root.velocity = spatialVector{a.AngularVelocity, a.Velocity.Add(a.AngularVelocity.Cross(origin.Sub(root.origin)))}
+	root.bias = spatialVector{}
+
+	for _, link := range a.Links[1:] {
+		parent := link.Parent
+		axis := parent.rotation.Mul(link.JointRotation).Rotate(link.Axis.Normalize())
+
+		switch link.Joint {
+		case RevoluteJoint:
+			// Turning about the joint moves the origin sideways
+			link.motion = spatialVector{axis, link.origin.Sub(origin).Cross(axis)}
+		case PrismaticJoint:
+			link.motion = spatialVector{mgl32.Vec3{}, axis}
+		default:
+			link.motion = spatialVector{}
+		}
+
+		jointVelocity := link.motion.Mul(link.Velocity)
+		link.velocity = parent.velocity.Add(jointVelocity)
+		link.bias = link.velocity.crossMotion(jointVelocity)
+	}
+}
+
+// solve runs the articulated body algorithm about origin, finding the
+// acceleration of every link and joint under gravity, joint forces scaled by
+// forceScale, and the links' velocities, over a step of dt. When impulsive, it
+// finds what the contact impulses do to still links instead
+func (a *Articulation) solve(origin mgl32.Vec3, impulsive bool, dt, forceScale float32) {
+	// Inertia and bias force of each link on its own
+	for i, link := range a.Links {
+		link.inertia = spatialMatrix{}
+		link.biasForce = spatialVector{}
+		if i == 0 && a.FixedBase {
+			continue
+		}
+
+		body := link.Actor.RigidBody
+		rot := link.rotation.Mat4().Mat3()
+		center := link.origin.Add(link.rotation.Rotate(body.CenterOfMass)).Sub(origin)
+		inertia := rot.Mul3(body.localInertia()).Mul3(rot.Transpose())
+		link.inertia = spatialInertia(body.Mass, center, inertia)
+
+		if impulsive {
+			link.biasForce = spatialVector{link.angularImpulse, mgl32.Vec3{}}.Add(spatialForceAt(link.impulse, center)).Mul(-1.0)
+		} else {
+			gravity := spatialForceAt(a.Gravity.Mul(body.Mass), center).Mul(forceScale)
+			link.biasForce = link.velocity.crossForce(link.inertia.Mul(link.velocity)).Sub(gravity)
+		}
+	}
+
+	// Fold each link into its parent, from the leaves in
+	for i := len(a.Links) - 1; i > 0; i-- {
+		link := a.Links[i]
+		bias := link.bias
+		if impulsive {
+			bias = spatialVector{}
+		}
+		inertia := link.inertia
+		biasForce := link.biasForce.Add(inertia.Mul(bias))
+
+		if link.Joint != FixedJoint && !link.locked {
+			link.inertiaAxis = link.inertia.Mul(link.motion)
+			link.axisInertia = link.motion.Dot(link.inertiaAxis)
+			tau := float32(0.0)
+			if !impulsive {
+				// Springs and damping push on where the joint will be at the
+				// end of the step, not where it is now, so they can be as
+				// stiff as they like. That is the same as the joint being
+				// heavier by the part that depends on its acceleration
+				damping := link.Damping + link.DriveDamping
+				tau = link.Force + link.DriveStiffness*(link.Target-link.Position-link.stepVelocity*dt) - damping*link.stepVelocity
+				link.axisInertia += (link.DriveStiffness*dt + damping) * dt * forceScale
+			}
+			link.jointForce = tau*forceScale - link.motion.Dot(link.biasForce)
+
+			if link.axisInertia > 0.0 {
+				inertia = inertia.Sub(spatialOuter(link.inertiaAxis, link.inertiaAxis).Scale(1.0 / link.axisInertia))
+				biasForce = link.biasForce.Add(inertia.Mul(bias)).
+					Add(link.inertiaAxis.Mul(link.jointForce / link.axisInertia))
+			}
+		}
+
+		link.Parent.inertia = link.Parent.inertia.Add(inertia)
+		link.Parent.biasForce = link.Parent.biasForce.Add(biasForce)
+	}
+
+	// Accelerations, from the root out
+	root := a.Root()
+	root.acceleration = spatialVector{}
+	if !a.FixedBase {
+		if acceleration, ok := root.inertia.Solve(root.biasForce.Mul(-1.0)); ok {
+			root.acceleration = acceleration
+		}
+	}
+	for _, link := range a.Links[1:] {
+		acceleration := link.Parent.acceleration
+		if !impulsive {
+			acceleration = acceleration.Add(link.bias)
+		}
+		link.jointAcceleration = 0.0
+		if link.Joint != FixedJoint && !link.locked && link.axisInertia > 0.0 {
+			link.jointAcceleration = (link.jointForce - link.inertiaAxis.Dot(acceleration)) / link.axisInertia
+			acceleration = acceleration.Add(link.motion.Mul(link.jointAcceleration))
+		}
+		link.acceleration = acceleration
+	}
+}
+
+// step moves everything on by dt frames, then works out how fast it is going
+// there. Like a RigidBody, it moves before it speeds up, so the velocities the
+// contact solver leaves are what the links move with next
+func (a *Articulation) step(dt, forceScale float32) {
+	root := a.Root()
+	if !a.FixedBase {
+		root.origin = root.origin.Add(a.Velocity.Mul(dt))
+		spin := mgl32.Quat{W: 0.0, V: a.AngularVelocity.Mul(dt * 0.5)}
+		root.rotation = root.rotation.Add(spin.Mul(root.rotation)).Normalize()
+	}
+	for _, link := range a.Links[1:] {
+		if link.Joint != FixedJoint {
+			link.Position += link.Velocity * dt
+			link.limit()
+		}
+	}
+
+	origin := root.origin
+	a.place()
+
+	// Coriolis forces grow with velocity, and pushing velocity on with the
+	// forces from the start of the step gains energy until it blows up. So
+	// the forces are found halfway between the old and new velocities, by
+	// guessing the new velocities a few times
+	velocity, angularVelocity := a.Velocity, a.AngularVelocity
+	nextVelocity, nextAngularVelocity := velocity, angularVelocity
+	for _, link := range a.Links {
+		link.stepVelocity = link.Velocity
+		link.locked = false
+	}
+
+	for it := 0; it < articulationIterations; it++ {
+		a.Velocity = velocity.Add(nextVelocity).Mul(0.5)
+		a.AngularVelocity = angularVelocity.Add(nextAngularVelocity).Mul(0.5)
+		a.updateVelocities(origin)
+		a.solve(origin, false, dt, forceScale)
+		for a.lockLimits(dt) {
+			a.solve(origin, false, dt, forceScale)
+		}
+
+		// The root's acceleration is that of the point fixed at its origin,
+		// which the root's origin moves away from
+		if !a.FixedBase {
+			nextVelocity = velocity.Add(root.acceleration.Linear.Add(a.AngularVelocity.Cross(a.Velocity)).Mul(dt))
+			nextAngularVelocity = angularVelocity.Add(root.acceleration.Angular.Mul(dt))
+		}
+		for _, link := range a.Links {
+			link.Velocity = link.stepVelocity + link.jointAcceleration*dt*0.5
+		}
+	}
+
+	a.Velocity, a.AngularVelocity = nextVelocity, nextAngularVelocity
+	for _, link := range a.Links {
+		link.Velocity = link.stepVelocity + link.jointAcceleration*dt
+		link.limit()
+	}
+	a.addJointImpulses(origin, dt)
+}
+
+// addJointImpulses adds what each joint passed on over dt, after solve about
+// origin, to its jointImpulse. The parent pushes on everything past the joint
+// with whatever it takes to accelerate it as solve found
+func (a *Articulation) addJointImpulses(origin mgl32.Vec3, dt float32) {
+	for _, link := range a.Links {
+		force := link.inertia.Mul(link.acceleration).Add(link.biasForce).Mul(dt)
+		// Move the torque from about origin to about the link's origin
+		force.Angular = force.Angular.Sub(link.origin.Sub(origin).Cross(force.Linear))
+		toLink := link.rotation.Conjugate()
+		link.jointImpulse = link.jointImpulse.Add(spatialVector{toLink.Rotate(force.Angular), toLink.Rotate(force.Linear)})
+	}
+}
+
+// lockLimits locks every joint against a limit that solve would push further
+// past it over dt, so the next solve holds it still and the other links take
+// its share instead. It returns whether any were locked
+func (a *Articulation) lockLimits(dt float32) bool {
+	locked := false
+	for _, link := range a.Links[1:] {
+		velocity := link.stepVelocity + link.jointAcceleration*dt
+		if !link.locked && link.Limited &&
+			((link.Position <= link.Lower && velocity < 0.0) || (link.Position >= link.Upper && velocity > 0.0)) {
+			link.locked = true
+			locked = true
+		}
+	}
+	return locked
+}
+
+// limit keeps Position between Lower and Upper, and stops Velocity taking it
+// any further past them
+func (link *ArticulationLink) limit() {
+	if !link.Limited {
+		return
+	}
+	if link.Position <= link.Lower {
+		link.Position = link.Lower
+		link.Velocity = float32(math.Max(float64(link.Velocity), 0.0))
+	} else if link.Position >= link.Upper {
+		link.Position = link.Upper
+		link.Velocity = float32(math.Min(float64(link.Velocity), 0.0))
+	}
+}
+
+func (w *World) AddArticulation(articulation *Articulation) {
+	w.Articulations = append(w.Articulations, articulation)
+}
+
+func (w *World) RemoveArticulation(articulation *Articulation) {
+	for i := range w.Articulations {
+		if w.Articulations[i] == articulation {
+			w.Articulations = append(w.Articulations[:i], w.Articulations[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// articulationEnergy returns the kinetic and potential energy of every link
+// that moves. Velocities are per frame, so gravity is too, scaled by the
+// length of a frame
+func articulationEnergy(a *Articulation, elapsed float32) float32 {
+	energy := float32(0.0)
+	for _, link := range a.Links[1:] {
+		body := link.Actor.RigidBody
+		velocity := body.Velocity
+		spin := body.AngularVelocity
+		rotation := link.Actor.Transform.Rotation.Mat4().Mat3()
+		inertia := rotation.Mul3(body.Inertia).Mul3(rotation.Transpose())
+
+		energy += 0.5*body.Mass*velocity.Dot(velocity) + 0.5*spin.Dot(inertia.Mul3x1(spin))
+		energy -= body.Mass * a.Gravity.Mul(elapsed).Dot(body.WorldCenterOfMass())
+	}
+	return energy
+}
+
+func TestPendulumEnergy(t *testing.T) {
+	bead := func(w *World, radius, density float32) *Actor {
+		actor := NewActor()
+		actor.Transform.Scale = mgl32.Vec3{radius, radius, radius}
+		actor.RigidBody.Collider = SphereCollider{Radius: radius}
+		actor.RigidBody.SetDensity(density)
+		w.AddActor(actor)
+		return actor
+	}
+
+	tests := []struct {
+		name     string
+		substeps int
+		start    float32
+		// How far the energy can wander, as a fraction of what the bob gains
+		// falling to the bottom. A step is a large part of a swing, so a
+		// single one gets the energy a few percent out each way
+		tolerance float64
+	}{
+		{"level", 1, 0, 0.1},
+		{"level substepped", 4, 0, 0.03},
+		{"nearly upright", 4, 1.5, 0.05},
+	}
+	for _, test := range tests {
+		w := NewWorld()
+		post := NewActor()
+		w.AddActor(post)
+
+		// A heavy bob fixed 4 out along a light arm turning about the post
+		pendulum := NewArticulation(post, true)
+		pendulum.Substeps = test.substeps
+		arm := pendulum.AddLink(pendulum.Root(), bead(w, 0.25, 0.1), RevoluteJoint, mgl32.Vec3{}, mgl32.Vec3{0, 0, 1})
+		arm.Position = test.start
+		bob := pendulum.AddLink(arm, bead(w, 1, 1), FixedJoint, mgl32.Vec3{4, 0, 0}, mgl32.Vec3{})
+		w.AddArticulation(pendulum)
+		w.Update(0.0, 0.0)
+
+		start := articulationEnergy(pendulum, 1.0/60.0)
+		lowest := float32(math.MaxFloat32)
+		for i := 0; i < 600; i++ {
+			w.Update(1.0, 1.0/60.0)
+			lowest = float32(math.Min(float64(lowest), float64(bob.Actor.Transform.Position.Y())))
+
+			energy := articulationEnergy(pendulum, 1.0/60.0)
+			if diff := math.Abs(float64(energy - start)); diff > test.tolerance*float64(bob.Actor.RigidBody.Mass*9.81*4/60) {
+				t.Fatalf("%s: energy went from %v to %v after %d frames", test.name, start, energy, i)
+			}
+		}
+		if lowest > -3.9 {
+			t.Errorf("%s: bob only swung down to %v, want -4", test.name, lowest)
+		}
+		if length := bob.Actor.Transform.Position.Len(); math.Abs(float64(length-4)) > 1e-4 {
+			t.Errorf("%s: bob is %v from the post, want 4", test.name, length)
+		}
+	}
+}
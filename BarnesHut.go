@@ -0,0 +1,188 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Past this depth bodies are too close together to split, and share a leaf
+const octreeMaxDepth = 32
+
+type octreeNode struct {
+	Center   mgl32.Vec3
+	HalfSize float32
+
+	Mass         float32
+	CenterOfMass mgl32.Vec3
+
+	// Index of each child, or -1, leaves have no children
+	Children [8]int
+	Leaf     bool
+	// The only body in a leaf, nil if a leaf holds more than one
+	Body *RigidBody
+}
+
+// Octree is a Barnes-Hut tree, where every node knows the total mass and
+// center of mass of the bodies inside of it. Distant groups of bodies can then
+// be treated as a single body when summing up gravity
+type Octree struct {
+	nodes []octreeNode
+}
+
+func NewOctree() *Octree {
+	return &Octree{
+		nodes: []octreeNode{},
+	}
+}
+
+// Build rebuilds the tree from scratch around every body with a finite mass
+func (tree *Octree) Build(actors []*Actor) {
+	tree.nodes = tree.nodes[:0]
+
+	bounds := AABB{}
+	first := true
+	for i := range actors {
+		if actors[i].RigidBody.InverseMass() == 0.0 {
+			continue
+		}
+		pos := actors[i].Transform.Position
+		if first {
+			bounds = AABB{Min: pos, Max: pos}
+			first = false
+		} else {
+			bounds = bounds.Union(AABB{Min: pos, Max: pos})
+		}
+	}
+	if first {
+		return
+	}
+
+	size := bounds.Size()
+	halfSize := float32(math.Max(float64(size[0]), math.Max(float64(size[1]), float64(size[2]))))
+	tree.addNode(bounds.Center(), halfSize+1.0)
+
+	for i := range actors {
+		rb := actors[i].RigidBody
+		if rb.InverseMass() == 0.0 {
+			continue
+		}
+		tree.insert(0, rb, actors[i].Transform.Position, 0)
+	}
+}
+
+func (tree *Octree) addNode(center mgl32.Vec3, halfSize float32) int {
+	tree.nodes = append(tree.nodes, octreeNode{
+		Center:   center,
+		HalfSize: halfSize,
+		Children: [8]int{-1, -1, -1, -1, -1, -1, -1, -1},
+		Leaf:     true,
+	})
+	return len(tree.nodes) - 1
+}
+
+func (tree *Octree) childFor(node int, pos mgl32.Vec3) int {
+	n := &tree.nodes[node]
+
+	octant := 0
+	offset := mgl32.Vec3{}
+	for i := 0; i < 3; i++ {
+		offset[i] = -n.HalfSize * 0.5
+		if pos[i] >= n.Center[i] {
+			octant |= 1 << uint(i)
+			offset[i] = n.HalfSize * 0.5
+		}
+	}
+
+	if n.Children[octant] < 0 {
+		child := tree.addNode(n.Center.Add(offset), n.HalfSize*0.5)
+		// addNode may have moved the nodes
+		tree.nodes[node].Children[octant] = child
+	}
+	return tree.nodes[node].Children[octant]
+}
+
+func (tree *Octree) insert(node int, rb *RigidBody, pos mgl32.Vec3, depth int) {
+	n := &tree.nodes[node]
+	empty := n.Mass == 0.0
+
+	oldMass := n.Mass
+	oldCenterOfMass := n.CenterOfMass
+	n.Mass += rb.Mass
+	n.CenterOfMass = oldCenterOfMass.Mul(oldMass).Add(pos.Mul(rb.Mass)).Mul(1.0 / n.Mass)
+
+	if n.Leaf {
+		if empty {
+			n.Body = rb
+			return
+		}
+		if depth >= octreeMaxDepth {
+			n.Body = nil
+			return
+		}
+
+		// Split the leaf, and push the body that was here down a level
+		other := n.Body
+		n.Leaf = false
+		n.Body = nil
+		child := tree.childFor(node, oldCenterOfMass)
+		tree.insert(child, other, oldCenterOfMass, depth+1)
+	}
+
+	child := tree.childFor(node, pos)
+	tree.insert(child, rb, pos, depth+1)
+}
+
+// Acceleration sums up the gravity at pos from every body except exclude.
+// Nodes smaller than theta times their distance are treated as one body, and
+// softening stops the force from exploding as bodies pass through each other
+func (tree *Octree) Acceleration(pos mgl32.Vec3, exclude *RigidBody, g, theta, softening float32) mgl32.Vec3 {
+	accel := mgl32.Vec3{0, 0, 0}
+	if len(tree.nodes) == 0 {
+		return accel
+	}
+
+	stack := []int{0}
+	for len(stack) > 0 {
+		n := &tree.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		if n.Mass == 0.0 || (n.Leaf && n.Body == exclude) {
+			continue
+		}
+
+		diff := n.CenterOfMass.Sub(pos)
+		distSqr := diff.LenSqr() + softening*softening
+
+		if !n.Leaf && (2.0*n.HalfSize)*(2.0*n.HalfSize) >= theta*theta*distSqr {
+			for _, child := range n.Children {
+				if child >= 0 {
+					stack = append(stack, child)
+				}
+			}
+			continue
+		}
+
+		dist := float32(math.Sqrt(float64(distSqr)))
+		accel = accel.Add(diff.Mul(g * n.Mass / (distSqr * dist)))
+	}
+	return accel
+}
+
+func (w *World) applyNBodyGravity(elapsed float32) {
+	w.octree.Build(w.Actors)
+
+	accels := make([]mgl32.Vec3, len(w.Actors))
+	for i := range w.Actors {
+		rb := w.Actors[i].RigidBody
+		if rb.InverseMass() == 0.0 {
+			continue
+		}
+		accels[i] = w.octree.Acceleration(w.Actors[i].Transform.Position, rb,
+			w.GravitationalConstant, w.BarnesHutTheta, w.GravitySoftening)
+	}
+
+	for i := range w.Actors {
+		w.Actors[i].RigidBody.applyForceOverTime(accels[i], Acceleration, elapsed)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// directGravity sums up the gravity at body i from every other body
+func directGravity(actors []*Actor, i int, g, softening float32) mgl32.Vec3 {
+	accel := mgl32.Vec3{}
+	pos := actors[i].Transform.Position
+	for j := range actors {
+		if j == i {
+			continue
+		}
+		diff := actors[j].Transform.Position.Sub(pos)
+		distSqr := diff.LenSqr() + softening*softening
+		dist := float32(math.Sqrt(float64(distSqr)))
+		accel = accel.Add(diff.Mul(g * actors[j].RigidBody.Mass / (distSqr * dist)))
+	}
+	return accel
+}
+
+func TestOctreeAcceleration(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	actors := []*Actor{}
+	totalMass := float32(0.0)
+	centerOfMass := mgl32.Vec3{}
+	for i := 0; i < 200; i++ {
+		actor := NewActor()
+		actor.Transform.Position = mgl32.Vec3{
+			random.Float32()*100 - 50,
+			random.Float32()*20 - 10,
+			random.Float32()*100 - 50,
+		}
+		actor.RigidBody.Mass = 1 + random.Float32()*9
+		actors = append(actors, actor)
+		totalMass += actor.RigidBody.Mass
+		centerOfMass = centerOfMass.Add(actor.Transform.Position.Mul(actor.RigidBody.Mass))
+	}
+	centerOfMass = centerOfMass.Mul(1.0 / totalMass)
+
+	tree := NewOctree()
+	tree.Build(actors)
+	root := tree.nodes[0]
+	if math.Abs(float64(root.Mass-totalMass)) > 1e-2 {
+		t.Errorf("root has mass %v, want %v", root.Mass, totalMass)
+	}
+	if root.CenterOfMass.Sub(centerOfMass).Len() > 1e-3 {
+		t.Errorf("root has center of mass %v, want %v", root.CenterOfMass, centerOfMass)
+	}
+
+	tests := []struct {
+		theta     float32
+		tolerance float64
+	}{
+		{0.0, 1e-4},
+		{0.5, 0.02},
+		{1.0, 0.1},
+	}
+	for _, test := range tests {
+		// Compare to the typical acceleration, as bodies where the pulls
+		// nearly cancel out have a large error relative to their own
+		errSqr, accelSqr := 0.0, 0.0
+		for i := range actors {
+			want := directGravity(actors, i, 1, 1)
+			got := tree.Acceleration(actors[i].Transform.Position, actors[i].RigidBody, 1, test.theta, 1)
+			errSqr += float64(got.Sub(want).LenSqr())
+			accelSqr += float64(want.LenSqr())
+		}
+		if err := math.Sqrt(errSqr / accelSqr); err > test.tolerance {
+			t.Errorf("theta %v has a relative error of %v, want under %v", test.theta, err, test.tolerance)
+		}
+	}
+}
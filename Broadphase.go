@@ -0,0 +1,152 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type bvhNode struct {
+	Bounds AABB
+	// Children, or -1 for a leaf
+	Left  int
+	Right int
+	// Only set on leaves, Index is the body's position in World.Actors
+	Body  *RigidBody
+	Index int
+}
+
+type broadphaseItem struct {
+	Bounds AABB
+	Body   *RigidBody
+	Index  int
+}
+
+// Broadphase is a bounding volume hierarchy over every body with a Collider,
+// used to quickly find everything that might be touching a box or a ray
+type Broadphase struct {
+	nodes []bvhNode
+	root  int
+}
+
+func NewBroadphase() *Broadphase {
+	return &Broadphase{
+		nodes: []bvhNode{},
+		root:  -1,
+	}
+}
+
+// Build rebuilds the hierarchy from scratch, which is cheap enough to do
+// every step and never degrades the way refitting does
+func (bp *Broadphase) Build(actors []*Actor) {
+	items := make([]broadphaseItem, 0, len(actors))
+	for i := range actors {
+		rb := actors[i].RigidBody
+		if rb == nil || rb.Collider == nil {
+			continue
+		}
+		items = append(items, broadphaseItem{
+			Bounds: rb.Bounds(),
+			Body:   rb,
+			Index:  i,
+		})
+	}
+
+	bp.nodes = bp.nodes[:0]
+	bp.root = -1
+	if len(items) > 0 {
+		bp.root = bp.build(items)
+	}
+}
+
+func (bp *Broadphase) build(items []broadphaseItem) int {
+	if len(items) == 1 {
+		bp.nodes = append(bp.nodes, bvhNode{
+			Bounds: items[0].Bounds,
+			Left:   -1,
+			Right:  -1,
+			Body:   items[0].Body,
+			Index:  items[0].Index,
+		})
+		return len(bp.nodes) - 1
+	}
+
+	bounds := items[0].Bounds
+	centers := AABB{Min: bounds.Center(), Max: bounds.Center()}
+	for i := 1; i < len(items); i++ {
+		bounds = bounds.Union(items[i].Bounds)
+		center := items[i].Bounds.Center()
+		centers = centers.Union(AABB{Min: center, Max: center})
+	}
+
+	// Split at the median along the axis the centers are most spread out on
+	axis := 0
+	size := centers.Size()
+	if size[1] > size[axis] {
+		axis = 1
+	}
+	if size[2] > size[axis] {
+		axis = 2
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Bounds.Center()[axis] < items[j].Bounds.Center()[axis]
+	})
+	mid := len(items) / 2
+
+	node := len(bp.nodes)
+	bp.nodes = append(bp.nodes, bvhNode{Bounds: bounds})
+	left := bp.build(items[:mid])
+	right := bp.build(items[mid:])
+	bp.nodes[node].Left = left
+	bp.nodes[node].Right = right
+	return node
+}
+
+// QueryAABB calls fn for every body whose bounds overlap the box, until fn
+// returns false
+func (bp *Broadphase) QueryAABB(bounds AABB, fn func(body *RigidBody, index int) bool) {
+	if bp.root < 0 {
+		return
+	}
+
+	stack := []int{bp.root}
+	for len(stack) > 0 {
+		node := &bp.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		if !node.Bounds.Overlaps(bounds) {
+			continue
+		}
+		if node.Body != nil {
+			if !fn(node.Body, node.Index) {
+				return
+			}
+			continue
+		}
+		stack = append(stack, node.Left, node.Right)
+	}
+}
+
+// QueryRay calls fn for every body whose bounds are hit by the ray. fn returns
+// the distance to search up to from then on, so a search for the closest hit
+// can skip everything behind the best hit so far
+func (bp *Broadphase) QueryRay(origin, direction mgl32.Vec3, maxDist float32, fn func(body *RigidBody, index int) float32) {
+	if bp.root < 0 {
+		return
+	}
+
+	stack := []int{bp.root}
+	for len(stack) > 0 {
+		node := &bp.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+
+		if _, hit := node.Bounds.IntersectRay(origin, direction, maxDist); !hit {
+			continue
+		}
+		if node.Body != nil {
+			maxDist = fn(node.Body, node.Index)
+			continue
+		}
+		stack = append(stack, node.Left, node.Right)
+	}
+}
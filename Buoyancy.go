@@ -0,0 +1,298 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// WaterVolume is a ForceField filled with fluid up to the top of Region. It
+// holds bodies up with a force equal to the weight of the fluid they push out
+// of the way, and slows them down while they are in it
+type WaterVolume struct {
+	Region AABB
+	// Density of the fluid, bodies with a lower density than this float
+	Density float32
+	// Gravity should match the gravity applied to bodies
+	Gravity mgl32.Vec3
+	// Current is the velocity the fluid flows at, per frame like Velocity
+	Current mgl32.Vec3
+	// LinearDrag and AngularDrag are how much of a body's velocity, relative
+	// to the fluid, it loses every second while fully submerged
+	LinearDrag  float32
+	AngularDrag float32
+}
+
+// NewWaterVolume creates a WaterVolume with appropriate defaults
+func NewWaterVolume(region AABB, density float32) *WaterVolume {
+	return &WaterVolume{
+		Region:      region,
+		Density:     density,
+		Gravity:     mgl32.Vec3{0, -9.81, 0},
+		Current:     mgl32.Vec3{0, 0, 0},
+		LinearDrag:  1.0,
+		AngularDrag: 1.0,
+	}
+}
+
+func (field *WaterVolume) Bounds() AABB {
+	return field.Region
+}
+
+func (field *WaterVolume) Apply(rb *RigidBody, elapsed float32) {
+	transform := &rb.Parent.Transform
+	volume, center := submergedVolume(rb.Collider, transform.Position, transform.Rotation, field.Region)
+	if volume <= 0.0 {
+		return
+	}
+
+	// Push up from the middle of the submerged part, so bodies turn to float
+	// the right way up
+	buoyancy := field.Gravity.Mul(-field.Density * volume)
+	rb.ApplyForceAtPosition(buoyancy.Mul(elapsed), center, Impulse)
+
+	fraction := float32(1.0)
+	if total := ComputeMassProperties(rb.Collider, 1.0).Volume; total > 0.0 {
+		fraction = float32(math.Min(float64(volume/total), 1.0))
+	}
+
+	// Drag is applied implicitly, in the same way as damping
+	relative := rb.Velocity.Sub(field.Current)
+	keep := 1.0 / (1.0 + elapsed*field.LinearDrag*fraction)
+	rb.Velocity = field.Current.Add(relative.Mul(keep))
+	keep = 1.0 / (1.0 + elapsed*field.AngularDrag*fraction)
+	rb.AngularVelocity = rb.AngularVelocity.Mul(keep)
+}
+
+// submergedVolume finds how much of a Collider is inside region, which is
+// filled with fluid up to its top, and the center of that part
+func submergedVolume(col Collider, pos mgl32.Vec3, rot mgl32.Quat, region AABB) (float32, mgl32.Vec3) {
+	switch col := col.(type) {
+	case SphereCollider:
+		r := col.Radius
+		if pos.X()-r < region.Min.X() || pos.X()+r > region.Max.X() ||
+			pos.Z()-r < region.Min.Z() || pos.Z()+r > region.Max.Z() || pos.Y()-r < region.Min.Y() {
+			// Poking out of the sides or the bottom, it is cut up like a hull
+			return submergedPolyhedronVolume(spherePolygons(col.Radius, pos), region)
+		}
+		h := float32(math.Min(math.Max(float64(region.Max.Y()-(pos.Y()-r)), 0.0), float64(2.0*r)))
+		if h == 0.0 {
+			return 0.0, pos
+		}
+
+		// A spherical cap of height h, its center is below the sphere's
+		volume := math.Pi * h * h * (3.0*r - h) / 3.0
+		offset := 3.0 * (2.0*r - h) * (2.0*r - h) / (4.0 * (3.0*r - h))
+		return volume, pos.Sub(mgl32.Vec3{0, offset, 0})
+
+	case BoxCollider:
+		if math.Abs(float64(rot.Dot(mgl32.QuatIdent()))) <= 1.0-alignedTolerance {
+			// A turned box is cut up like any other hull
+			hull, err := NewConvexHullCollider(boxCorners(col.Size))
+			if err != nil {
+				return 0.0, pos
+			}
+			return submergedPolyhedronVolume(hullPolygons(hull, pos, rot), region)
+		}
+		// Lined up, the part inside is just where the boxes overlap
+		volume := float32(1.0)
+		center := mgl32.Vec3{}
+		for i := 0; i < 3; i++ {
+			low := float32(math.Max(float64(pos[i]-col.Size[i]), float64(region.Min[i])))
+			high := float32(math.Min(float64(pos[i]+col.Size[i]), float64(region.Max[i])))
+			if high <= low {
+				return 0.0, pos
+			}
+			volume *= high - low
+			center[i] = (low + high) * 0.5
+		}
+		return volume, center
+
+	case ConvexHullCollider:
+		return submergedPolyhedronVolume(hullPolygons(col, pos, rot), region)
+
+	case CompoundCollider:
+		total := float32(0.0)
+		center := mgl32.Vec3{}
+		for _, child := range col.Children {
+			volume, c := submergedVolume(child.Collider, pos.Add(rot.Rotate(child.Offset)), rot, region)
+			total += volume
+			center = center.Add(c.Mul(volume))
+		}
+		if total == 0.0 {
+			return 0.0, pos
+		}
+		return total, center.Mul(1.0 / total)
+	}
+	return 0.0, pos
+}
+
+// hullPolygons returns the faces of a hull where it is in the world
+func hullPolygons(hull ConvexHullCollider, pos mgl32.Vec3, rot mgl32.Quat) [][]mgl32.Vec3 {
+	polygons := make([][]mgl32.Vec3, 0, len(hull.Faces))
+	for _, face := range hull.Faces {
+		polygon := make([]mgl32.Vec3, 3)
+		for i := range face {
+			polygon[i] = pos.Add(rot.Rotate(hull.Points[face[i]]))
+		}
+		polygons = append(polygons, polygon)
+	}
+	return polygons
+}
+
+// Rings and segments of the polyhedron that stands in for a sphere when it is
+// cut up
+const (
+	sphereRings    = 12
+	sphereSegments = 24
+)
+
+// spherePolygons returns the faces of a polyhedron close to a sphere, grown a
+// little so that it has the same volume
+func spherePolygons(radius float32, center mgl32.Vec3) [][]mgl32.Vec3 {
+	point := func(ring, segment int) mgl32.Vec3 {
+		theta := math.Pi * float64(ring) / sphereRings
+		phi := 2.0 * math.Pi * float64(segment) / sphereSegments
+		return mgl32.Vec3{
+			float32(math.Sin(theta) * math.Cos(phi)),
+			float32(math.Cos(theta)),
+			float32(math.Sin(theta) * math.Sin(phi)),
+		}
+	}
+
+	polygons := make([][]mgl32.Vec3, 0, sphereRings*sphereSegments)
+	for ring := 0; ring < sphereRings; ring++ {
+		for segment := 0; segment < sphereSegments; segment++ {
+			a, b := point(ring, segment), point(ring, segment+1)
+			c, d := point(ring+1, segment+1), point(ring+1, segment)
+			switch ring {
+			case 0:
+				polygons = append(polygons, []mgl32.Vec3{a, c, d})
+			case sphereRings - 1:
+				polygons = append(polygons, []mgl32.Vec3{a, b, d})
+			default:
+				polygons = append(polygons, []mgl32.Vec3{a, b, c, d})
+			}
+		}
+	}
+
+	unit, _ := polyhedronVolume(polygons)
+	scale := radius * float32(math.Cbrt(4.0*math.Pi/3.0/float64(unit)))
+	for _, polygon := range polygons {
+		for i := range polygon {
+			polygon[i] = center.Add(polygon[i].Mul(scale))
+		}
+	}
+	return polygons
+}
+
+// submergedPolyhedronVolume cuts a closed convex polyhedron by every face of
+// region, and returns the volume and center of what is left
+func submergedPolyhedronVolume(polygons [][]mgl32.Vec3, region AABB) (float32, mgl32.Vec3) {
+	for i := 0; i < 3; i++ {
+		axis := mgl32.Vec3{}
+		axis[i] = 1.0
+		polygons = clipPolyhedron(polygons, axis, region.Max[i])
+		polygons = clipPolyhedron(polygons, axis.Mul(-1.0), -region.Min[i])
+	}
+	return polyhedronVolume(polygons)
+}
+
+// clipPolyhedron cuts a closed convex polyhedron, with faces wound
+// anticlockwise seen from outside, by the plane normal . p = offset. It keeps
+// the part behind the plane, closed off by a new face where the plane cut it
+func clipPolyhedron(polygons [][]mgl32.Vec3, normal mgl32.Vec3, offset float32) [][]mgl32.Vec3 {
+	ahead := false
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			ahead = ahead || normal.Dot(p) > offset
+		}
+	}
+	if !ahead {
+		return polygons
+	}
+
+	clipped := make([][]mgl32.Vec3, 0, len(polygons)+1)
+	cut := []mgl32.Vec3{}
+	for _, polygon := range polygons {
+		kept := []mgl32.Vec3{}
+		for i, a := range polygon {
+			b := polygon[(i+1)%len(polygon)]
+			da := normal.Dot(a) - offset
+			db := normal.Dot(b) - offset
+
+			if da <= 0.0 {
+				kept = append(kept, a)
+			}
+			if da == 0.0 {
+				cut = append(cut, a)
+			}
+			if (da < 0.0 && db > 0.0) || (da > 0.0 && db < 0.0) {
+				p := a.Add(b.Sub(a).Mul(da / (da - db)))
+				kept = append(kept, p)
+				cut = append(cut, p)
+			}
+		}
+		if len(kept) >= 3 {
+			clipped = append(clipped, kept)
+		}
+	}
+	if len(cut) < 3 {
+		return clipped
+	}
+
+	// The points where the plane cut through are on the edge of a convex
+	// polygon, so they go round it in order of their angle about its middle
+	middle := mgl32.Vec3{}
+	for _, p := range cut {
+		middle = middle.Add(p)
+	}
+	middle = middle.Mul(1.0 / float32(len(cut)))
+	u := cut[0].Sub(middle)
+	for _, p := range cut {
+		if d := p.Sub(middle); d.Len() > u.Len() {
+			u = d
+		}
+	}
+	if u.Len() == 0.0 {
+		return clipped
+	}
+	u = u.Normalize()
+	v := normal.Cross(u)
+	angle := func(p mgl32.Vec3) float64 {
+		d := p.Sub(middle)
+		return math.Atan2(float64(d.Dot(v)), float64(d.Dot(u)))
+	}
+	sort.Slice(cut, func(i, j int) bool {
+		return angle(cut[i]) < angle(cut[j])
+	})
+	return append(clipped, cut)
+}
+
+// polyhedronVolume returns the volume and center of a closed polyhedron, by
+// summing up the tetrahedra between each face and a point on it
+func polyhedronVolume(polygons [][]mgl32.Vec3) (float32, mgl32.Vec3) {
+	if len(polygons) == 0 {
+		return 0.0, mgl32.Vec3{}
+	}
+	apex := polygons[0][0]
+
+	volume := float32(0.0)
+	center := mgl32.Vec3{}
+	for _, polygon := range polygons {
+		for i := 1; i+1 < len(polygon); i++ {
+			a := polygon[0].Sub(apex)
+			b := polygon[i].Sub(apex)
+			c := polygon[i+1].Sub(apex)
+			v := a.Dot(b.Cross(c)) / 6.0
+			volume += v
+			center = center.Add(apex.Add(polygon[0]).Add(polygon[i]).Add(polygon[i+1]).Mul(v * 0.25))
+		}
+	}
+
+	if volume <= 0.0 {
+		return 0.0, apex
+	}
+	return volume, center.Mul(1.0 / volume)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSubmergedVolume(t *testing.T) {
+	sphere := SphereCollider{Radius: 1}
+	sphereVolume := float32(4.0 / 3.0 * math.Pi)
+	box := BoxCollider{Size: mgl32.Vec3{1, 2, 3}}
+	hull, _ := NewConvexHullCollider(boxCorners(box.Size))
+	spun := mgl32.QuatRotate(0.7, mgl32.Vec3{0, 1, 0})
+	tilted := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 0, 1})
+	// below is a sea filled up to surface, and pool is 2 wide and 2 deep with
+	// its surface at y = 0
+	below := func(surface float32) AABB {
+		return AABB{Min: mgl32.Vec3{-100, -100, -100}, Max: mgl32.Vec3{100, surface, 100}}
+	}
+	pool := AABB{Min: mgl32.Vec3{-1, -2, -1}, Max: mgl32.Vec3{1, 0, 1}}
+	// A cap of a unit sphere 0.1 high
+	sliver := float32(math.Pi * 0.01 * 2.9 / 3)
+
+	tests := []struct {
+		name     string
+		collider Collider
+		position mgl32.Vec3
+		rotation mgl32.Quat
+		region   AABB
+		volume   float32
+		center   mgl32.Vec3
+		// Relative to the volume. Spheres cut by the sides are cut up as
+		// polyhedra, which is close but not exact
+		tolerance float32
+	}{
+		{"sphere above", sphere, mgl32.Vec3{}, mgl32.QuatIdent(), below(-2), 0, mgl32.Vec3{}, 1e-3},
+		{"sphere half under", sphere, mgl32.Vec3{}, mgl32.QuatIdent(), below(0), sphereVolume / 2, mgl32.Vec3{0, -3.0 / 8.0, 0}, 1e-3},
+		{"sphere under", sphere, mgl32.Vec3{}, mgl32.QuatIdent(), below(5), sphereVolume, mgl32.Vec3{}, 1e-3},
+		{"box a quarter under", box, mgl32.Vec3{}, mgl32.QuatIdent(), below(-1), 12, mgl32.Vec3{0, -1.5, 0}, 1e-3},
+		{"box under", box, mgl32.Vec3{}, mgl32.QuatIdent(), below(5), 48, mgl32.Vec3{}, 1e-3},
+		{"box spun around the surface normal", box, mgl32.Vec3{}, spun, below(-1), 12, mgl32.Vec3{0, -1.5, 0}, 1e-3},
+		// Stood on its edge, the part under is a triangular prism
+		{"tilted cube half under", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.Vec3{}, tilted, below(0), 4, mgl32.Vec3{0, -math.Sqrt2 / 3, 0}, 1e-3},
+		{"hull a quarter under", hull, mgl32.Vec3{}, mgl32.QuatIdent(), below(-1), 12, mgl32.Vec3{0, -1.5, 0}, 1e-3},
+		{"compound", CompoundCollider{Children: []ChildCollider{
+			{Collider: sphere, Offset: mgl32.Vec3{0, 0, 0}},
+			{Collider: sphere, Offset: mgl32.Vec3{0, 10, 0}},
+		}}, mgl32.Vec3{}, mgl32.QuatIdent(), below(0), sphereVolume / 2, mgl32.Vec3{0, -3.0 / 8.0, 0}, 1e-3},
+
+		// Only the part inside the pool's sides and above its floor counts
+		{"sphere beside the pool", sphere, mgl32.Vec3{1.9, -1, 0}, mgl32.QuatIdent(), pool, sliver, mgl32.Vec3{1.9 - 3*1.9*1.9/(4*2.9), -1, 0}, 0.2},
+		{"sphere across the side", sphere, mgl32.Vec3{1, -1, 0}, mgl32.QuatIdent(), pool, sphereVolume / 2, mgl32.Vec3{1 - 3.0/8.0, -1, 0}, 0.01},
+		{"sphere in the corner", sphere, mgl32.Vec3{1, -1, 1}, mgl32.QuatIdent(), pool, sphereVolume / 4, mgl32.Vec3{1 - 3.0/8.0, -1, 1 - 3.0/8.0}, 0.01},
+		{"box across the side", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.Vec3{1.5, -1, 0}, mgl32.QuatIdent(), pool, 2, mgl32.Vec3{0.75, -1, 0}, 1e-3},
+		{"box through the floor", BoxCollider{Size: mgl32.Vec3{0.5, 1, 0.5}}, mgl32.Vec3{0, -2, 0}, mgl32.QuatIdent(), pool, 1, mgl32.Vec3{0, -1.5, 0}, 1e-3},
+		{"box beside the pool", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.Vec3{3, -1, 0}, mgl32.QuatIdent(), pool, 0, mgl32.Vec3{}, 1e-3},
+		{"hull across the side", hull, mgl32.Vec3{1.5, -1, 0}, mgl32.QuatIdent(), pool, 2, mgl32.Vec3{0.75, -1, 0}, 1e-3},
+		// The top of the prism, with its corners cut off by the sides
+		{"tilted cube through the floor", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.Vec3{0, -2, 0}, tilted, pool, 4*math.Sqrt2 - 2, mgl32.Vec3{0, -1.4973167, 0}, 1e-3},
+	}
+	for _, test := range tests {
+		volume, center := submergedVolume(test.collider, test.position, test.rotation, test.region)
+		if math.Abs(float64(volume-test.volume)) > float64(test.tolerance*test.volume)+1e-4 {
+			t.Errorf("%s: volume = %v, want %v", test.name, volume, test.volume)
+		}
+		if test.volume > 0.0 && center.Sub(test.center).Len() > test.tolerance {
+			t.Errorf("%s: center = %v, want %v", test.name, center, test.center)
+		}
+	}
+}
+
+func TestWaterVolumeSides(t *testing.T) {
+	water := NewWaterVolume(AABB{Min: mgl32.Vec3{-1, -2, -1}, Max: mgl32.Vec3{1, 0, 1}}, 1.0)
+	push := func(position mgl32.Vec3) float32 {
+		actor := NewActor()
+		actor.Transform.Position = position
+		actor.RigidBody.Collider = SphereCollider{Radius: 1}
+		actor.RigidBody.Mass = 100
+		water.Apply(actor.RigidBody, 1.0/60.0)
+		return actor.RigidBody.Velocity.Y()
+	}
+
+	// A sphere poking into the pool from beside it is barely held up
+	inside := push(mgl32.Vec3{0, -1, 0})
+	beside := push(mgl32.Vec3{1.9, -1, 0})
+	if beside <= 0 || beside > inside*0.02 {
+		t.Errorf("sphere beside the pool pushed up by %v, and one in it by %v", beside, inside)
+	}
+	if away := push(mgl32.Vec3{3, -1, 0}); away != 0 {
+		t.Errorf("sphere away from the pool pushed up by %v", away)
+	}
+}
+
+func TestWaterVolumeFloats(t *testing.T) {
+	w := NewWorld()
+	water := NewWaterVolume(NewAABB(mgl32.Vec3{0, -10, 0}, mgl32.Vec3{20, 10, 20}), 1.0)
+	water.LinearDrag = 5.0
+	w.AddForceField(water)
+
+	// Half as dense as the water, so it floats half under
+	box := newTestBox(w, mgl32.Vec3{0, 0.5, 0}, mgl32.Vec3{1, 1, 1})
+	box.RigidBody.Mass = 4.0
+	for i := 0; i < 600; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	y := box.Transform.Position.Y()
+	if math.Abs(float64(y)) > 0.05 {
+		t.Errorf("box floats at y = %v, want 0", y)
+	}
+
+	// Velocity holds a frame of gravity the water takes back next frame, so
+	// check the box stays put instead
+	for i := 0; i < 60; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	if moved := box.Transform.Position.Y() - y; math.Abs(float64(moved)) > 0.01 {
+		t.Errorf("box is still bobbing, it moved %v in a second", moved)
+	}
+}
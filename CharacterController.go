@@ -0,0 +1,329 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// How far to move out of a surface in one go when the character starts a
+	// frame inside it
+	characterMaxDepenetration = 0.5
+	// How far past the edge of a ledge to look for the top of it
+	characterLedgeProbe = 0.05
+)
+
+// CharacterController walks a body with a CapsuleCollider around the World by
+// sweeping it, rather than simulating it. It goes where it is told to, slides
+// along walls, climbs steps, and stays on slopes it can walk up but slides
+// down those it can't
+type CharacterController struct {
+	// Body is moved directly, it is given an infinite Mass so the contact
+	// solver never moves it
+	Body *RigidBody
+	Up   mgl32.Vec3
+
+	// DesiredVelocity is how the character is trying to move, and should be
+	// set every frame. Only the part across Up is used, as gravity and
+	// jumping decide how it moves along Up
+	DesiredVelocity mgl32.Vec3
+	// Velocity is how the character actually moved last frame
+	Velocity mgl32.Vec3
+
+	// Gravity is the acceleration pulling the character down Up while it
+	// isn't on the ground, and JumpSpeed is how fast it leaves the ground
+	Gravity   float32
+	JumpSpeed float32
+	// StepHeight is the tallest ledge the character walks up without
+	// jumping, and it keeps to the ground when walking off one this tall
+	StepHeight float32
+	// SlopeLimit is the steepest slope the character can stand on, in radians
+	SlopeLimit float32
+	// SkinWidth is the gap kept between the capsule and everything it
+	// touches, so it never starts a sweep already touching something
+	SkinWidth float32
+	// MaxSlides is how many surfaces a move can slide along in one frame
+	MaxSlides int
+	// PushBodies makes the character shove dynamic bodies that it walks into,
+	// as if they were hit by something heavy moving at its speed
+	PushBodies bool
+
+	// CollisionMask has a bit set for every layer the character can't move
+	// through
+	CollisionMask uint32
+
+	// Grounded is whether the character is standing on a slope it can walk
+	// on, and Ground is the body it is standing on
+	Grounded     bool
+	Ground       *RigidBody
+	GroundNormal mgl32.Vec3
+
+	// Speed along Up
+	fallSpeed float32
+	jump      bool
+}
+
+// NewCharacterController creates a CharacterController that moves body, which
+// must have a CapsuleCollider
+func NewCharacterController(body *RigidBody) *CharacterController {
+	body.Mass = math.MaxFloat32
+
+	return &CharacterController{
+		Body: body,
+		Up:   mgl32.Vec3{0, 1, 0},
+
+		Gravity:    9.81,
+		JumpSpeed:  1.0,
+		StepHeight: 0.5,
+		SlopeLimit: mgl32.DegToRad(45.0),
+		SkinWidth:  0.02,
+		MaxSlides:  4,
+		PushBodies: true,
+
+		CollisionMask: AllLayers,
+	}
+}
+
+// Jump makes the character jump at the next Update, if it is on the ground
+func (c *CharacterController) Jump() {
+	c.jump = true
+}
+
+// Update moves the character by DesiredVelocity across Up, then by gravity or
+// its jump along Up, and finally looks for the ground under it
+func (c *CharacterController) Update(w *World, delta, elapsed float32) {
+	capsule, ok := c.Body.Collider.(CapsuleCollider)
+	if !ok {
+		return
+	}
+	up := c.Up.Normalize()
+	transform := &c.Body.Parent.Transform
+	start := transform.Position
+	pos := c.depenetrate(w, capsule, start)
+
+	if c.jump && c.Grounded {
+		c.fallSpeed = c.JumpSpeed
+		c.Grounded = false
+	}
+	c.jump = false
+	if c.Grounded {
+		c.fallSpeed = 0.0
+	} else {
+		c.fallSpeed -= c.Gravity * elapsed
+	}
+	wasGrounded := c.Grounded
+
+	walk := c.DesiredVelocity.Sub(up.Mul(c.DesiredVelocity.Dot(up))).Mul(delta)
+	pos = c.walk(w, capsule, pos, walk)
+
+	pos, hit, blocked := c.slide(w, capsule, pos, up.Mul(c.fallSpeed*delta), false)
+	if blocked && c.fallSpeed > 0.0 && hit.Normal.Dot(up) < 0.0 {
+		// Hit the ceiling
+		c.fallSpeed = 0.0
+	}
+
+	// Only snap down to the ground when walking, not when jumping off it
+	c.Grounded = false
+	c.Ground = nil
+	c.GroundNormal = mgl32.Vec3{}
+	if c.fallSpeed <= 0.0 {
+		probe := c.SkinWidth * 2.0
+		if wasGrounded {
+			probe += c.StepHeight
+		}
+		hit, ok := w.sweep(capsule, pos, up.Mul(-1.0), probe, c.CollisionMask, c.Body)
+		if normal := c.surfaceNormal(w, pos, hit); ok && c.walkable(normal) {
+			pos = pos.Sub(up.Mul(float32(math.Max(float64(hit.Distance-c.SkinWidth), 0.0))))
+			c.Grounded = true
+			c.Ground = hit.Body
+			c.GroundNormal = normal
+			c.fallSpeed = 0.0
+		}
+	}
+
+	transform.Position = pos
+	c.Velocity = mgl32.Vec3{}
+	if delta > 0.0 {
+		c.Velocity = pos.Sub(start).Mul(1.0 / delta)
+	}
+}
+
+// walk moves across Up, stepping up onto anything in the way that is no
+// taller than StepHeight
+func (c *CharacterController) walk(w *World, capsule CapsuleCollider, pos, move mgl32.Vec3) mgl32.Vec3 {
+	if move.Len() == 0.0 {
+		return pos
+	}
+
+	flat, hit, blocked := c.slide(w, capsule, pos, move, true)
+	if !blocked || !c.Grounded || c.StepHeight <= 0.0 || c.walkable(hit.Normal) {
+		return flat
+	}
+
+	// Go up, across, then back down onto whatever is there
+	up := c.Up.Normalize()
+	raise := c.StepHeight
+	if hit, ok := w.sweep(capsule, pos, up, raise+c.SkinWidth, c.CollisionMask, c.Body); ok {
+		raise = float32(math.Max(float64(hit.Distance-c.SkinWidth), 0.0))
+	}
+	stepped, _, _ := c.slide(w, capsule, pos.Add(up.Mul(raise)), move, true)
+	down, ok := w.sweep(capsule, stepped, up.Mul(-1.0), raise+c.SkinWidth, c.CollisionMask, c.Body)
+	if !ok || !c.walkable(c.surfaceNormal(w, stepped, down)) {
+		return flat
+	}
+	landed := stepped.Sub(up.Mul(float32(math.Max(float64(down.Distance-c.SkinWidth), 0.0))))
+
+	// Only keep the step if it got further than walking into the ledge did
+	across := func(p mgl32.Vec3) float32 {
+		return p.Sub(pos).Dot(move)
+	}
+	if across(landed) > across(flat) {
+		return landed
+	}
+	return flat
+}
+
+// slide sweeps the capsule along move, and slides along each surface it hits
+// for whatever is left of the move. When walking, slopes too steep to stand
+// on are treated as walls, so they can't be walked up. Otherwise landing on
+// ground it can stand on stops the move, so it doesn't slide down slopes. It
+// returns the last surface hit
+func (c *CharacterController) slide(w *World, capsule CapsuleCollider, pos, move mgl32.Vec3, walking bool) (mgl32.Vec3, RaycastHit, bool) {
+	up := c.Up.Normalize()
+	original := move
+	last := RaycastHit{}
+	blocked := false
+
+	for i := 0; i < c.MaxSlides; i++ {
+		dist := move.Len()
+		if dist < 1e-5 {
+			break
+		}
+		direction := move.Mul(1.0 / dist)
+
+		hit, ok := w.sweep(capsule, pos, direction, dist+c.SkinWidth, c.CollisionMask, c.Body)
+		if !ok {
+			pos = pos.Add(move)
+			break
+		}
+
+		travel := float32(math.Max(float64(hit.Distance-c.SkinWidth), 0.0))
+		pos = pos.Add(direction.Mul(travel))
+		last = hit
+		blocked = true
+		if c.PushBodies {
+			c.push(hit, direction.Mul(dist))
+		}
+
+		normal := hit.Normal
+		if !walking && c.walkable(normal) && direction.Dot(up) < 0.0 {
+			break
+		}
+		if walking && !c.walkable(normal) {
+			if flat := normal.Sub(up.Mul(normal.Dot(up))); flat.Len() > 1e-5 {
+				normal = flat.Normalize()
+			}
+		}
+
+		move = direction.Mul(dist - travel)
+		move = move.Sub(normal.Mul(move.Dot(normal)))
+
+		// Stop rather than bounce back and forth in a corner
+		if move.Dot(original) <= 0.0 {
+			break
+		}
+	}
+
+	return pos, last, blocked
+}
+
+// push gives a dynamic body that was hit the impulse needed for the point
+// that was hit to keep up with the character
+func (c *CharacterController) push(hit RaycastHit, move mgl32.Vec3) {
+	rb := hit.Body
+	if rb.InverseMass() == 0.0 {
+		return
+	}
+
+	direction := hit.Normal.Mul(-1.0)
+	closing := move.Dot(direction) - pointVelocity(rb, hit.Point).Dot(direction)
+	if closing <= 0.0 {
+		return
+	}
+	rb.ApplyForceAtPosition(direction.Mul(closing/pointInverseMass(rb, hit.Point, direction)), hit.Point, Impulse)
+}
+
+// depenetrate moves the capsule out of any immovable bodies that it overlaps,
+// dynamic bodies are pushed out of it by the contact solver instead
+func (c *CharacterController) depenetrate(w *World, capsule CapsuleCollider, pos mgl32.Vec3) mgl32.Vec3 {
+	w.updateBroadphase()
+	for it := 0; it < c.MaxSlides; it++ {
+		moved := false
+		w.broadphase.QueryAABB(colliderBounds(capsule, pos, mgl32.QuatIdent()), func(rb *RigidBody, index int) bool {
+			if rb == c.Body || rb.IsTrigger || rb.InverseMass() != 0.0 || c.CollisionMask&(1<<rb.Layer) == 0 {
+				return true
+			}
+
+			transform := &rb.Parent.Transform
+			deepest := Contact{}
+			for _, contact := range collideColliders(capsule, pos, mgl32.QuatIdent(), rb.Collider, transform.Position, transform.Rotation) {
+				if contact.Depth > deepest.Depth {
+					deepest = contact
+				}
+			}
+			if deepest.Depth > 0.0 {
+				depth := float32(math.Min(float64(deepest.Depth+c.SkinWidth), characterMaxDepenetration))
+				pos = pos.Sub(deepest.Normal.Mul(depth))
+				moved = true
+			}
+			return true
+		})
+		if !moved {
+			break
+		}
+	}
+	return pos
+}
+
+// surfaceNormal returns the normal of the surface under a sweep hit. Where
+// the rounded bottom of the capsule rests on the edge of a ledge, the hit has
+// the edge's normal, which leans out over the edge, so the top of the ledge
+// is found with a ray instead
+func (c *CharacterController) surfaceNormal(w *World, pos mgl32.Vec3, hit RaycastHit) mgl32.Vec3 {
+	if hit.Body == nil || c.walkable(hit.Normal) {
+		return hit.Normal
+	}
+
+	up := c.Up.Normalize()
+	out := hit.Point.Sub(pos)
+	out = out.Sub(up.Mul(out.Dot(up)))
+	if out.Len() > 1e-5 {
+		out = out.Normalize()
+	}
+	origin := hit.Point.Add(up.Mul(characterLedgeProbe)).Add(out.Mul(characterLedgeProbe))
+	for _, ray := range w.RaycastAll(origin, up.Mul(-1.0), characterLedgeProbe*2.0, c.CollisionMask) {
+		if ray.Body == hit.Body {
+			return ray.Normal
+		}
+	}
+	return hit.Normal
+}
+
+// walkable returns whether a surface is flat enough to stand on
+func (c *CharacterController) walkable(normal mgl32.Vec3) bool {
+	return normal.Dot(c.Up.Normalize()) >= float32(math.Cos(float64(c.SlopeLimit)))-1e-4
+}
+
+func (w *World) AddCharacter(character *CharacterController) {
+	w.Characters = append(w.Characters, character)
+}
+
+func (w *World) RemoveCharacter(character *CharacterController) {
+	for i := range w.Characters {
+		if w.Characters[i] == character {
+			w.Characters = append(w.Characters[:i], w.Characters[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newTestCharacter adds a capsule character to the World, 4 tall with its
+// feet at feet
+func newTestCharacter(w *World, feet mgl32.Vec3) *CharacterController {
+	player := NewActor()
+	player.Transform.Position = feet.Add(mgl32.Vec3{0, 2, 0})
+	player.RigidBody.Collider = CapsuleCollider{Radius: 1, HalfHeight: 1}
+	w.AddActor(player)
+
+	character := NewCharacterController(player.RigidBody)
+	w.AddCharacter(character)
+	return character
+}
+
+// newTestBlock adds an immovable box to the World
+func newTestBlock(w *World, position, size mgl32.Vec3) *Actor {
+	block := NewActor()
+	block.Transform.Position = position
+	block.Transform.Scale = size
+	block.RigidBody.Collider = BoxCollider{Size: size}
+	block.RigidBody.Mass = math.MaxFloat32
+	w.AddActor(block)
+	return block
+}
+
+func TestCharacterWalkable(t *testing.T) {
+	c := NewCharacterController(NewRigidBody())
+	tests := []struct {
+		angle float32
+		want  bool
+	}{
+		{0, true},
+		{30, true},
+		{45, true},
+		{50, false},
+		{90, false},
+		{180, false},
+	}
+	for _, test := range tests {
+		normal := mgl32.QuatRotate(mgl32.DegToRad(test.angle), mgl32.Vec3{0, 0, 1}).Rotate(c.Up)
+		if got := c.walkable(normal); got != test.want {
+			t.Errorf("walkable at %v degrees = %v, want %v", test.angle, got, test.want)
+		}
+	}
+}
+
+func TestCharacterLands(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	character := newTestCharacter(w, mgl32.Vec3{0, 3, 0})
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	feet := character.Body.Parent.Transform.Position.Y() - 2
+	if !character.Grounded || math.Abs(float64(feet-character.SkinWidth)) > 1e-3 {
+		t.Errorf("grounded %v with feet at %v, want %v", character.Grounded, feet, character.SkinWidth)
+	}
+	if character.GroundNormal.Sub(mgl32.Vec3{0, 1, 0}).Len() > 1e-4 || character.Ground == nil {
+		t.Errorf("standing on %v, with normal %v", character.Ground, character.GroundNormal)
+	}
+
+	// Jumping leaves the ground, and comes back down to it
+	character.Jump()
+	w.Update(1.0, 1.0/60.0)
+	if character.Grounded || character.Velocity.Y() <= 0 {
+		t.Fatalf("grounded %v moving at %v after jumping", character.Grounded, character.Velocity)
+	}
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	if !character.Grounded {
+		t.Errorf("never landed after jumping")
+	}
+}
+
+func TestCharacterWalk(t *testing.T) {
+	tests := []struct {
+		name   string
+		height float32
+		past   bool
+	}{
+		{"step", 0.4, true},
+		{"wall", 3, false},
+	}
+	for _, test := range tests {
+		w := NewWorld()
+		newTestFloor(w)
+		newTestBlock(w, mgl32.Vec3{10, test.height / 2, 0}, mgl32.Vec3{5, test.height / 2, 5})
+		character := newTestCharacter(w, mgl32.Vec3{0, 0.1, 0})
+		for i := 0; i < 10; i++ {
+			w.Update(1.0, 1.0/60.0)
+		}
+
+		character.DesiredVelocity = mgl32.Vec3{0.1, 0, 0}
+		for i := 0; i < 120; i++ {
+			w.Update(1.0, 1.0/60.0)
+		}
+
+		position := character.Body.Parent.Transform.Position
+		if test.past {
+			// It walked up onto the step, and is standing on top of it, no
+			// more than a skin's width above it
+			feet := position.Y() - 2
+			if position.X() < 10 || !character.Grounded || feet < test.height || feet > test.height+character.SkinWidth+1e-3 {
+				t.Errorf("%s: at %v, grounded %v, want on top of the step", test.name, position, character.Grounded)
+			}
+		} else {
+			// It stopped a skin's width short of the wall
+			if math.Abs(float64(position.X()+1+character.SkinWidth-5)) > 1e-3 || !character.Grounded {
+				t.Errorf("%s: at %v, grounded %v, want against the wall", test.name, position, character.Grounded)
+			}
+		}
+	}
+}
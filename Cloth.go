@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// SpringKind is which neighbours in the grid a Spring connects
+type SpringKind int8
+
+const (
+	// StructuralSpring connects a particle to the next one along the grid,
+	// and stops the cloth stretching
+	StructuralSpring SpringKind = iota
+	// ShearSpring connects diagonal neighbours, and stops the cloth shearing
+	ShearSpring
+	// BendSpring skips over a particle, and stops the cloth folding
+	BendSpring
+)
+
+// Spring pulls two particles of a Cloth back towards RestLength apart
+type Spring struct {
+	A, B       int
+	RestLength float32
+	Kind       SpringKind
+}
+
+// Cloth is a grid of particles held together by springs. It is pushed out of
+// any RigidBody colliders it touches, but doesn't push back on them
+type Cloth struct {
+	// Width and Height are the number of particles along each side of the grid
+	Width  int
+	Height int
+
+	// Particle i is at row i / Width, column i % Width
+	Positions  []mgl32.Vec3
+	Velocities []mgl32.Vec3
+	// Pinned particles stay where they are
+	Pinned  []bool
+	Springs []Spring
+	// Triangles covering the grid, two per cell
+	Triangles [][3]int
+
+	ParticleMass float32
+
+	StructuralStiffness float32
+	ShearStiffness      float32
+	BendStiffness       float32
+	// SpringDamping resists springs changing length
+	SpringDamping float32
+	// Damping is air resistance, in the same way as RigidBody.LinearDamping
+	Damping float32
+
+	Gravity mgl32.Vec3
+	// Wind is the velocity of the air, per frame like Velocity, and
+	// DragCoefficient is how hard it pushes on the cloth
+	Wind            mgl32.Vec3
+	DragCoefficient float32
+
+	// Substeps splits every Update into smaller steps, stiffer springs need
+	// more of them to stay stable
+	Substeps int
+
+	// Thickness is how far the cloth is kept from colliders
+	Thickness float32
+	// Friction is how much of a particle's sliding velocity is lost when it
+	// is pushed out of a collider
+	Friction float32
+	// CollisionMask has a bit set for every layer the cloth collides with
+	CollisionMask uint32
+
+	accels []mgl32.Vec3
+
+	mesh dynamicMesh
+}
+
+// NewCloth creates a flat cloth of width by height particles, with its
+// corners at origin, origin+right, origin+down and origin+right+down
+func NewCloth(origin, right, down mgl32.Vec3, width, height int) (*Cloth, error) {
+	if width < 2 || height < 2 {
+		return nil, fmt.Errorf("Cloth needs at least 2x2 particles, got %vx%v", width, height)
+	}
+
+	cloth := &Cloth{
+		Width:      width,
+		Height:     height,
+		Positions:  make([]mgl32.Vec3, width*height),
+		Velocities: make([]mgl32.Vec3, width*height),
+		Pinned:     make([]bool, width*height),
+		Springs:    []Spring{},
+		Triangles:  [][3]int{},
+
+		ParticleMass:        0.1,
+		StructuralStiffness: 200.0,
+		ShearStiffness:      80.0,
+		BendStiffness:       20.0,
+		SpringDamping:       0.5,
+		Damping:             0.5,
+
+		Gravity:         mgl32.Vec3{0, -9.81, 0},
+		Wind:            mgl32.Vec3{0, 0, 0},
+		DragCoefficient: 0.5,
+
+		Substeps:      20,
+		Thickness:     0.5,
+		Friction:      0.3,
+		CollisionMask: AllLayers,
+
+		accels: make([]mgl32.Vec3, width*height),
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u := float32(x) / float32(width-1)
+			v := float32(y) / float32(height-1)
+			cloth.Positions[cloth.Index(x, y)] = origin.Add(right.Mul(u)).Add(down.Mul(v))
+		}
+	}
+
+	connect := func(x0, y0, x1, y1 int, kind SpringKind) {
+		if x0 >= width || x1 < 0 || x1 >= width || y1 >= height {
+			return
+		}
+		a := cloth.Index(x0, y0)
+		b := cloth.Index(x1, y1)
+		cloth.Springs = append(cloth.Springs, Spring{
+			A:          a,
+			B:          b,
+			RestLength: cloth.Positions[b].Sub(cloth.Positions[a]).Len(),
+			Kind:       kind,
+		})
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			connect(x, y, x+1, y, StructuralSpring)
+			connect(x, y, x, y+1, StructuralSpring)
+			connect(x, y, x+1, y+1, ShearSpring)
+			connect(x+1, y, x, y+1, ShearSpring)
+			connect(x, y, x+2, y, BendSpring)
+			connect(x, y, x, y+2, BendSpring)
+		}
+	}
+
+	for y := 0; y+1 < height; y++ {
+		for x := 0; x+1 < width; x++ {
+			a := cloth.Index(x, y)
+			b := cloth.Index(x+1, y)
+			c := cloth.Index(x, y+1)
+			d := cloth.Index(x+1, y+1)
+			cloth.Triangles = append(cloth.Triangles, [3]int{a, c, b}, [3]int{b, c, d})
+		}
+	}
+
+	return cloth, nil
+}
+
+// Cleanup frees up resources
+func (cloth *Cloth) Cleanup() {
+	cloth.mesh.Cleanup()
+}
+
+// Index returns the index of the particle at column x of row y
+func (cloth *Cloth) Index(x, y int) int {
+	return y*cloth.Width + x
+}
+
+// Pin fixes the particle at column x of row y in place
+func (cloth *Cloth) Pin(x, y int) {
+	i := cloth.Index(x, y)
+	cloth.Pinned[i] = true
+	cloth.Velocities[i] = mgl32.Vec3{0, 0, 0}
+}
+
+// Bounds returns the AABB of every particle
+func (cloth *Cloth) Bounds() AABB {
+	bounds := AABB{Min: cloth.Positions[0], Max: cloth.Positions[0]}
+	for _, p := range cloth.Positions {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	return bounds
+}
+
+func (cloth *Cloth) stiffness(kind SpringKind) float32 {
+	switch kind {
+	case ShearSpring:
+		return cloth.ShearStiffness
+	case BendSpring:
+		return cloth.BendStiffness
+	}
+	return cloth.StructuralStiffness
+}
+
+// Update moves the cloth forward, colliding with the bodies in w
+func (cloth *Cloth) Update(w *World, delta, elapsed float32) {
+	steps := cloth.Substeps
+	if steps < 1 {
+		steps = 1
+	}
+
+	// Anything the cloth could reach this frame, assuming it doesn't move
+	// further than its own size
+	bounds := cloth.Bounds()
+	reach := bounds.Size().Add(mgl32.Vec3{cloth.Thickness, cloth.Thickness, cloth.Thickness})
+	bodies := w.particleColliders(NewAABB(bounds.Center(), reach.Mul(2.0)), cloth.CollisionMask)
+
+	for s := 0; s < steps; s++ {
+		cloth.step(delta/float32(steps), elapsed/float32(steps), bodies)
+	}
+}
+
+func (cloth *Cloth) step(delta, elapsed float32, bodies []*RigidBody) {
+	invMass := 1.0 / cloth.ParticleMass
+
+	for i := range cloth.accels {
+		cloth.accels[i] = cloth.Gravity
+	}
+
+	for _, spring := range cloth.Springs {
+		d := cloth.Positions[spring.B].Sub(cloth.Positions[spring.A])
+		length := d.Len()
+		if length == 0.0 {
+			continue
+		}
+		dir := d.Mul(1.0 / length)
+
+		stretch := length - spring.RestLength
+		closing := cloth.Velocities[spring.B].Sub(cloth.Velocities[spring.A]).Dot(dir)
+		force := dir.Mul((cloth.stiffness(spring.Kind)*stretch + cloth.SpringDamping*closing) * invMass)
+
+		cloth.accels[spring.A] = cloth.accels[spring.A].Add(force)
+		cloth.accels[spring.B] = cloth.accels[spring.B].Sub(force)
+	}
+
+	// Wind pushes on each triangle in proportion to its area, and how
+	// directly it faces into the wind
+	if cloth.DragCoefficient != 0.0 {
+		for _, tri := range cloth.Triangles {
+			cloth.applyWind(tri[0], tri[1], tri[2], invMass)
+		}
+	}
+
+	for i := range cloth.Positions {
+		if cloth.Pinned[i] {
+			continue
+		}
+
+		v := cloth.Velocities[i].Add(cloth.accels[i].Mul(elapsed))
+		v = v.Mul(1.0 / (1.0 + elapsed*cloth.Damping))
+		prev := cloth.Positions[i]
+		cloth.Positions[i], cloth.Velocities[i] = collideParticle(prev, prev.Add(v.Mul(delta)), v,
+			cloth.Thickness, cloth.Friction, bodies)
+	}
+}
+
+func (cloth *Cloth) applyWind(a, b, c int, invMass float32) {
+	pa := cloth.Positions[a]
+	n := cloth.Positions[b].Sub(pa).Cross(cloth.Positions[c].Sub(pa))
+	area := n.Len() * 0.5
+	if area == 0.0 {
+		return
+	}
+	n = n.Normalize()
+
+	velocity := cloth.Velocities[a].Add(cloth.Velocities[b]).Add(cloth.Velocities[c]).Mul(1.0 / 3.0)
+	relative := cloth.Wind.Sub(velocity)
+	force := n.Mul(cloth.DragCoefficient * area * n.Dot(relative) * invMass / 3.0)
+
+	cloth.accels[a] = cloth.accels[a].Add(force)
+	cloth.accels[b] = cloth.accels[b].Add(force)
+	cloth.accels[c] = cloth.accels[c].Add(force)
+}
+
+func (cloth *Cloth) Render(shader *Shader) {
+	cloth.mesh.Render(shader, cloth.Positions, cloth.Triangles)
+}
+
+func (w *World) AddCloth(cloth *Cloth) {
+	w.Cloths = append(w.Cloths, cloth)
+}
+
+func (w *World) RemoveCloth(cloth *Cloth) {
+	for i := range w.Cloths {
+		if w.Cloths[i] == cloth {
+			w.Cloths = append(w.Cloths[:i], w.Cloths[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewCloth(t *testing.T) {
+	tests := []struct {
+		width, height           int
+		err                     bool
+		structural, shear, bend int
+	}{
+		{1, 5, true, 0, 0, 0},
+		{2, 2, false, 4, 2, 0},
+		{3, 3, false, 12, 8, 6},
+		{4, 2, false, 10, 6, 4},
+	}
+	for _, test := range tests {
+		cloth, err := NewCloth(mgl32.Vec3{}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, 1}, test.width, test.height)
+		if (err != nil) != test.err {
+			t.Errorf("%dx%d: err = %v, want an error: %v", test.width, test.height, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		counts := map[SpringKind]int{}
+		for _, spring := range cloth.Springs {
+			counts[spring.Kind]++
+		}
+		if counts[StructuralSpring] != test.structural || counts[ShearSpring] != test.shear || counts[BendSpring] != test.bend {
+			t.Errorf("%dx%d: got %d structural, %d shear and %d bend springs, want %d, %d and %d",
+				test.width, test.height, counts[StructuralSpring], counts[ShearSpring], counts[BendSpring],
+				test.structural, test.shear, test.bend)
+		}
+		if want := 2 * (test.width - 1) * (test.height - 1); len(cloth.Triangles) != want {
+			t.Errorf("%dx%d: got %d triangles, want %d", test.width, test.height, len(cloth.Triangles), want)
+		}
+		if corner := cloth.Positions[cloth.Index(test.width-1, test.height-1)]; corner != (mgl32.Vec3{1, 0, 1}) {
+			t.Errorf("%dx%d: far corner is at %v", test.width, test.height, corner)
+		}
+	}
+}
+
+func TestClothHangs(t *testing.T) {
+	w := NewWorld()
+	cloth, _ := NewCloth(mgl32.Vec3{0, 10, 0}, mgl32.Vec3{5, 0, 0}, mgl32.Vec3{0, 0, 5}, 10, 10)
+	cloth.Pin(0, 0)
+	cloth.Pin(9, 0)
+	w.AddCloth(cloth)
+	for i := 0; i < 300; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	if p := cloth.Positions[cloth.Index(0, 0)]; p != (mgl32.Vec3{0, 10, 0}) {
+		t.Errorf("pinned corner moved to %v", p)
+	}
+	if p := cloth.Positions[cloth.Index(0, 9)]; p.Y() > 6 {
+		t.Errorf("free corner is at %v, it should have swung down", p)
+	}
+	for _, spring := range cloth.Springs {
+		if spring.Kind != StructuralSpring {
+			continue
+		}
+		length := cloth.Positions[spring.B].Sub(cloth.Positions[spring.A]).Len()
+		if math.IsNaN(float64(length)) || length > spring.RestLength*1.5 {
+			t.Fatalf("spring stretched from %v to %v", spring.RestLength, length)
+		}
+	}
+}
+
+func TestClothDrapes(t *testing.T) {
+	w := NewWorld()
+	ball := NewActor()
+	ball.Transform.Position = mgl32.Vec3{0, 0, 0}
+	ball.RigidBody.Collider = SphereCollider{Radius: 2}
+	ball.RigidBody.Mass = math.MaxFloat32
+	w.AddActor(ball)
+
+	cloth, _ := NewCloth(mgl32.Vec3{-3, 3, -3}, mgl32.Vec3{6, 0, 0}, mgl32.Vec3{0, 0, 6}, 12, 12)
+	w.AddCloth(cloth)
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	// No particle sinks into the ball, and the middle stays on top of it
+	for i, p := range cloth.Positions {
+		if dist := p.Len(); dist < 2+cloth.Thickness*0.9 {
+			t.Fatalf("particle %d is %v from the center of the ball", i, dist)
+		}
+	}
+	if p := cloth.Positions[cloth.Index(5, 5)]; p.Y() < 2 {
+		t.Errorf("middle of the cloth fell to %v", p)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// CollisionEvent describes a collision from the point of view of Body
+type CollisionEvent struct {
+	Body  *RigidBody
+	Other *RigidBody
+	// Contact points in world space, empty when the bodies have separated
+	Points []mgl32.Vec3
+	// Normal points from Body towards Other
+	Normal mgl32.Vec3
+	// Total impulse applied along the normal this step
+	Impulse float32
+}
+
+// CollisionFunc is called with collision events
+type CollisionFunc func(event CollisionEvent)
+
+// Flipped returns the same event from the point of view of Other
+func (event CollisionEvent) Flipped() CollisionEvent {
+	event.Body, event.Other = event.Other, event.Body
+	event.Normal = event.Normal.Mul(-1.0)
+	return event
+}
+
+func newCollisionEvent(m *ContactManifold, separated bool) CollisionEvent {
+	event := CollisionEvent{
+		Body:   m.A,
+		Other:  m.B,
+		Points: []mgl32.Vec3{},
+		Normal: m.Normal,
+	}
+	if separated {
+		return event
+	}
+
+	for i := range m.Contacts {
+		event.Points = append(event.Points, m.Contacts[i].Point)
+		event.Impulse += m.Contacts[i].NormalImpulse
+	}
+	return event
+}
+
+func fireCollisionEvent(event CollisionEvent, worldFn, fnA, fnB CollisionFunc) {
+	if worldFn != nil {
+		worldFn(event)
+	}
+	if fnA != nil {
+		fnA(event)
+	}
+	if fnB != nil {
+		fnB(event.Flipped())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestCollisionEventFlipped(t *testing.T) {
+	a, b := NewRigidBody(), NewRigidBody()
+	tests := []struct {
+		normal mgl32.Vec3
+		want   mgl32.Vec3
+	}{
+		{mgl32.Vec3{0, 1, 0}, mgl32.Vec3{0, -1, 0}},
+		{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{-1, 0, 0}},
+		{mgl32.Vec3{0, 0.6, -0.8}, mgl32.Vec3{0, -0.6, 0.8}},
+	}
+	for _, test := range tests {
+		event := CollisionEvent{Body: a, Other: b, Normal: test.normal, Impulse: 2}
+		flipped := event.Flipped()
+		if flipped.Body != b || flipped.Other != a {
+			t.Errorf("flipped %v didn't swap the bodies", test.normal)
+		}
+		if flipped.Normal != test.want {
+			t.Errorf("flipped %v has normal %v, want %v", test.normal, flipped.Normal, test.want)
+		}
+		if flipped.Impulse != event.Impulse {
+			t.Errorf("flipped %v changed the impulse", test.normal)
+		}
+	}
+}
+
+func TestCollisionEventOrder(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	box := newTestBox(w, mgl32.Vec3{0, 3, 0}, mgl32.Vec3{1, 1, 1})
+	box.RigidBody.Restitution = 0.0
+
+	events := []string{}
+	record := func(name string) CollisionFunc {
+		return func(event CollisionEvent) {
+			if len(events) == 0 || events[len(events)-1] != name {
+				events = append(events, name)
+			}
+		}
+	}
+	w.OnCollisionEnter = record("enter")
+	w.OnCollisionStay = record("stay")
+	w.OnCollisionExit = record("exit")
+
+	// The box comes after the floor, so its own callback gets the flipped event
+	var boxEvent *CollisionEvent
+	box.RigidBody.OnCollisionEnter = func(event CollisionEvent) {
+		boxEvent = &event
+	}
+
+	for i := 0; i < 30; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	box.Transform.Position = mgl32.Vec3{0, 10, 0}
+	box.RigidBody.Velocity = mgl32.Vec3{}
+	box.RigidBody.Acceleration = mgl32.Vec3{}
+	for i := 0; i < 10; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	want := []string{"enter", "stay", "exit"}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("got events %v, want %v", events, want)
+		}
+	}
+
+	if boxEvent == nil {
+		t.Fatal("box's own OnCollisionEnter wasn't called")
+	}
+	if boxEvent.Body != box.RigidBody {
+		t.Error("box's OnCollisionEnter event has the floor as its Body")
+	}
+	if boxEvent.Normal.Y() >= 0.0 {
+		t.Errorf("box's OnCollisionEnter event has normal %v, want it pointing down at the floor", boxEvent.Normal)
+	}
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// How much of the penetration to resolve each step
+	contactBaumgarte = float32(0.2)
+	// How far bodies may overlap before position correction kicks in
+	contactSlop = float32(0.01)
+	// Approach speeds below this don't bounce, so resting contacts can settle
+	restitutionThreshold = float32(0.5)
+	// How far apart two rotations may be, as the sine of half the angle
+	// between them, for boxes to be treated as turned the same way
+	alignedTolerance = 1e-6
+	// How close a contact must be to one from the previous frame, which came
+	// from different features, to carry on its impulse
+	contactMatchDistance = 0.05
+)
+
+// ContactID identifies the features that generated a contact, so it can be
+// matched against the same contact from the previous frame
+type ContactID struct {
+	// Feature is the corner, edge or face of the shapes that touched
+	Feature uint32
+	// ChildA and ChildB are which child of a CompoundCollider on each side the
+	// contact came from, and Triangle is which triangle of a MeshCollider.
+	// They count from 1, and are 0 for other colliders
+	ChildA   uint32
+	ChildB   uint32
+	Triangle uint32
+}
+
+// Contact is a single point of contact between two RigidBodies
+type Contact struct {
+	ID    ContactID
+	Point mgl32.Vec3
+	// Normal points from A to B
+	Normal mgl32.Vec3
+	Depth  float32
+
+	// Accumulated impulse along the normal, carried between frames
+	NormalImpulse float32
+	// Accumulated impulse across the normal from friction
+	FrictionImpulse mgl32.Vec3
+
+	normalMass float32
+	// Speed the bodies must move apart at, to bounce or to stop short of a
+	// contact they aren't touching yet
+	bias float32
+	// Speed to push the bodies apart at to fix overlap, and the accumulated
+	// impulse doing it, which only moves the bodies
+	correction  float32
+	pushImpulse float32
+	// Where the contact is from the center of mass of A and B
+	rA mgl32.Vec3
+	rB mgl32.Vec3
+}
+
+// ContactManifold holds all of the contacts between two RigidBodies
+type ContactManifold struct {
+	A *RigidBody
+	B *RigidBody
+	// Normal is the normal of the deepest contact
+	Normal   mgl32.Vec3
+	Contacts []Contact
+
+	// World space inverse inertia of A and B, worked out once a step
+	invInertiaA mgl32.Mat3
+	invInertiaB mgl32.Mat3
+	friction    float32
+	// Articulations place their links from their joints, which would undo
+	// pushing them, so contacts on links fix overlap through their velocity
+	articulated bool
+}
+
+// flipContacts swaps which side is A and which is B, for narrowphase routines
+// written for the other order
+func flipContacts(contacts []Contact) []Contact {
+	for i := range contacts {
+		contacts[i].Normal = contacts[i].Normal.Mul(-1.0)
+	}
+	return contacts
+}
+
+// matchContacts copies the accumulated impulses from the previous frame's
+// manifold into any contacts with matching IDs, or failing that ones close by,
+// and returns how many matched
+func (m *ContactManifold) matchContacts(old *ContactManifold) (hits int) {
+	if old == nil {
+		return 0
+	}
+	matched := make([]bool, len(m.Contacts))
+	taken := make([]bool, len(old.Contacts))
+	for i := range m.Contacts {
+		for j := range old.Contacts {
+			if !taken[j] && m.Contacts[i].ID == old.Contacts[j].ID {
+				m.Contacts[i].NormalImpulse = old.Contacts[j].NormalImpulse
+				m.Contacts[i].FrictionImpulse = old.Contacts[j].FrictionImpulse
+				matched[i], taken[j] = true, true
+				hits++
+				break
+			}
+		}
+	}
+
+	// A corner that was only just clipped, or only just wasn't, comes back
+	// with a new ID in about the same place, so match it by where it is
+	for i := range m.Contacts {
+		if matched[i] {
+			continue
+		}
+		closest := -1
+		distance := float32(contactMatchDistance)
+		for j := range old.Contacts {
+			if d := m.Contacts[i].Point.Sub(old.Contacts[j].Point).Len(); !taken[j] && d < distance {
+				closest, distance = j, d
+			}
+		}
+		if closest >= 0 {
+			m.Contacts[i].NormalImpulse = old.Contacts[closest].NormalImpulse
+			m.Contacts[i].FrictionImpulse = old.Contacts[closest].FrictionImpulse
+			taken[closest] = true
+			hits++
+		}
+	}
+	return hits
+}
+
+func (m *ContactManifold) preStep() {
+	invMassA := m.A.InverseMass()
+	invMassB := m.B.InverseMass()
+	m.invInertiaA = m.A.InverseInertia()
+	m.invInertiaB = m.B.InverseInertia()
+	centerA := m.A.WorldCenterOfMass()
+	centerB := m.B.WorldCenterOfMass()
+
+	restitution := float32(math.Min(float64(m.A.Restitution), float64(m.B.Restitution)))
+	m.friction = float32(math.Sqrt(float64(m.A.Friction * m.B.Friction)))
+
+	for i := range m.Contacts {
+		c := &m.Contacts[i]
+		c.rA = c.Point.Sub(centerA)
+		c.rB = c.Point.Sub(centerB)
+		vn := m.relativeVelocity(c).Dot(c.Normal)
+
+		// Pushing off center turns the bodies as well, which makes them
+		// easier to push
+		rnA := c.rA.Cross(c.Normal)
+		rnB := c.rB.Cross(c.Normal)
+		k := invMassA + invMassB +
+			m.invInertiaA.Mul3x1(rnA).Dot(rnA) +
+			m.invInertiaB.Mul3x1(rnB).Dot(rnB)
+
+		c.normalMass = 0.0
+		if k > 0.0 {
+			c.normalMass = 1.0 / k
+		}
+
+		c.bias = 0.0
+		if c.Depth < 0.0 {
+			// Contacts that aren't touching yet only stop the gap closing
+			// past them
+			c.bias = c.Depth
+		}
+		if vn < -restitutionThreshold {
+			c.bias = float32(math.Max(float64(c.bias), float64(-restitution*vn)))
+		}
+		c.correction = contactBaumgarte * float32(math.Max(float64(c.Depth-contactSlop), 0.0))
+		c.pushImpulse = 0.0
+	}
+}
+
+func (m *ContactManifold) warmStart() {
+	for i := range m.Contacts {
+		c := &m.Contacts[i]
+		m.applyImpulse(c, c.Normal.Mul(c.NormalImpulse).Add(c.FrictionImpulse))
+	}
+}
+
+func (m *ContactManifold) solve() {
+	// Friction goes first, so that pushing the bodies apart has the last say
+	for i := range m.Contacts {
+		m.solveFriction(&m.Contacts[i])
+	}
+
+	for i := range m.Contacts {
+		c := &m.Contacts[i]
+
+		bias := c.bias
+		if m.articulated && c.correction > bias {
+			bias = c.correction
+		}
+		vn := m.relativeVelocity(c).Dot(c.Normal)
+		lambda := c.normalMass * (bias - vn)
+
+		// Clamp the accumulated impulse, not the incremental one, so that
+		// warm started impulses can be taken back
+		old := c.NormalImpulse
+		c.NormalImpulse = float32(math.Max(float64(old+lambda), 0.0))
+		lambda = c.NormalImpulse - old
+
+		m.applyImpulse(c, c.Normal.Mul(lambda))
+	}
+}
+
+// solvePush pushes the bodies apart to fix overlap, with velocities that only
+// move them, so it doesn't leave them with energy to bounce and rock with
+func (m *ContactManifold) solvePush() {
+	if m.articulated {
+		return
+	}
+	for i := range m.Contacts {
+		c := &m.Contacts[i]
+		if c.correction <= 0.0 {
+			continue
+		}
+
+		velocityA := m.A.pushVelocity.Add(m.A.pushAngularVelocity.Cross(c.rA))
+		velocityB := m.B.pushVelocity.Add(m.B.pushAngularVelocity.Cross(c.rB))
+		vn := velocityB.Sub(velocityA).Dot(c.Normal)
+		lambda := c.normalMass * (c.correction - vn)
+
+		old := c.pushImpulse
+		c.pushImpulse = float32(math.Max(float64(old+lambda), 0.0))
+		impulse := c.Normal.Mul(c.pushImpulse - old)
+
+		m.A.pushVelocity = m.A.pushVelocity.Sub(impulse.Mul(m.A.InverseMass()))
+		m.A.pushAngularVelocity = m.A.pushAngularVelocity.Sub(m.invInertiaA.Mul3x1(c.rA.Cross(impulse)))
+		m.B.pushVelocity = m.B.pushVelocity.Add(impulse.Mul(m.B.InverseMass()))
+		m.B.pushAngularVelocity = m.B.pushAngularVelocity.Add(m.invInertiaB.Mul3x1(c.rB.Cross(impulse)))
+	}
+}
+
+// solveFriction pushes against the contact sliding, up to friction times how
+// hard the bodies are pushed together
+func (m *ContactManifold) solveFriction(c *Contact) {
+	velocity := m.relativeVelocity(c)
+	sliding := velocity.Sub(c.Normal.Mul(velocity.Dot(c.Normal)))
+	speed := sliding.Len()
+	if speed < 1e-9 {
+		return
+	}
+
+	tangent := sliding.Mul(1.0 / speed)
+	rtA := c.rA.Cross(tangent)
+	rtB := c.rB.Cross(tangent)
+	k := m.A.InverseMass() + m.B.InverseMass() +
+		m.invInertiaA.Mul3x1(rtA).Dot(rtA) +
+		m.invInertiaB.Mul3x1(rtB).Dot(rtB)
+	if k <= 0.0 {
+		return
+	}
+
+	old := c.FrictionImpulse
+	c.FrictionImpulse = old.Sub(tangent.Mul(speed / k))
+	limit := m.friction * c.NormalImpulse
+	if c.FrictionImpulse.Len() > limit {
+		c.FrictionImpulse = c.FrictionImpulse.Normalize().Mul(limit)
+	}
+	m.applyImpulse(c, c.FrictionImpulse.Sub(old))
+}
+
+// relativeVelocity returns how fast the point of contact on B moves away from
+// the point on A
+func (m *ContactManifold) relativeVelocity(c *Contact) mgl32.Vec3 {
+	velocityA := m.A.Velocity.Add(m.A.AngularVelocity.Cross(c.rA))
+	velocityB := m.B.Velocity.Add(m.B.AngularVelocity.Cross(c.rB))
+	return velocityB.Sub(velocityA)
+}
+
+// applyImpulse pushes B away from A, and A away from B, at the contact
+func (m *ContactManifold) applyImpulse(c *Contact, impulse mgl32.Vec3) {
+	m.A.Velocity = m.A.Velocity.Sub(impulse.Mul(m.A.InverseMass()))
+	m.A.AngularVelocity = m.A.AngularVelocity.Sub(m.invInertiaA.Mul3x1(c.rA.Cross(impulse)))
+	m.B.Velocity = m.B.Velocity.Add(impulse.Mul(m.B.InverseMass()))
+	m.B.AngularVelocity = m.B.AngularVelocity.Add(m.invInertiaB.Mul3x1(c.rB.Cross(impulse)))
+}
+
+// collideColliders finds the contacts between two colliders, with normals
+// pointing from a to b
+func collideColliders(colA Collider, posA mgl32.Vec3, rotA mgl32.Quat, colB Collider, posB mgl32.Vec3, rotB mgl32.Quat) []Contact {
+	// Split compound colliders up, and tag the contacts of each child so they
+	// don't get mixed up with its siblings' between frames. Children of nested
+	// compounds are numbered within their parent
+	if compound, ok := colA.(CompoundCollider); ok {
+		contacts := []Contact{}
+		for i, child := range compound.Children {
+			pos := posA.Add(rotA.Rotate(child.Offset))
+			for _, c := range collideColliders(child.Collider, pos, rotA, colB, posB, rotB) {
+				c.ID.ChildA = c.ID.ChildA*uint32(len(compound.Children)+1) + uint32(i+1)
+				contacts = append(contacts, c)
+			}
+		}
+		return contacts
+	}
+	if compound, ok := colB.(CompoundCollider); ok {
+		contacts := []Contact{}
+		for i, child := range compound.Children {
+			pos := posB.Add(rotB.Rotate(child.Offset))
+			for _, c := range collideColliders(colA, posA, rotA, child.Collider, pos, rotB) {
+				c.ID.ChildB = c.ID.ChildB*uint32(len(compound.Children)+1) + uint32(i+1)
+				contacts = append(contacts, c)
+			}
+		}
+		return contacts
+	}
+
+	if plane, ok := colB.(PlaneCollider); ok {
+		return collidePlane(colA, posA, rotA, plane, posB, rotB)
+	}
+	if plane, ok := colA.(PlaneCollider); ok {
+		return flipContacts(collidePlane(colB, posB, rotB, plane, posA, rotA))
+	}
+
+	if mesh, ok := colB.(MeshCollider); ok {
+		return collideMesh(colA, posA, rotA, mesh, posB, rotB)
+	}
+	if mesh, ok := colA.(MeshCollider); ok {
+		return flipContacts(collideMesh(colB, posB, rotB, mesh, posA, rotA))
+	}
+
+	// Boxes are collided in the space of one of them, where it is axis
+	// aligned. Two boxes can only both be if they are turned the same way
+	switch colA := colA.(type) {
+	case SphereCollider:
+		switch colB := colB.(type) {
+		case SphereCollider:
+			return collideSpheres(colA, posA, colB, posB)
+		case BoxCollider:
+			inv := rotB.Conjugate()
+			return toWorldContacts(collideSphereBox(colA, inv.Rotate(posA.Sub(posB)), colB, mgl32.Vec3{}), posB, rotB)
+		}
+	case BoxCollider:
+		inv := rotA.Conjugate()
+		switch colB := colB.(type) {
+		case SphereCollider:
+			return toWorldContacts(flipContacts(collideSphereBox(colB, inv.Rotate(posB.Sub(posA)), colA, mgl32.Vec3{})), posA, rotA)
+		case BoxCollider:
+			if inv.Mul(rotB).V.Len() < alignedTolerance {
+				return toWorldContacts(collideBoxes(colA, mgl32.Vec3{}, colB, inv.Rotate(posB.Sub(posA))), posA, rotA)
+			}
+			return collideTurnedBoxes(colA, posA, rotA, colB, posB, rotB)
+		}
+	}
+
+	shapeA, okA := newConvexShape(colA, posA, rotA)
+	shapeB, okB := newConvexShape(colB, posB, rotB)
+	if !okA || !okB {
+		return nil
+	}
+	return collideConvex(shapeA, shapeB)
+}
+
+// toWorldContacts moves contacts found in the local space of a body at pos,
+// turned by rot, into world space
+func toWorldContacts(contacts []Contact, pos mgl32.Vec3, rot mgl32.Quat) []Contact {
+	for i := range contacts {
+		contacts[i].Point = pos.Add(rot.Rotate(contacts[i].Point))
+		contacts[i].Normal = rot.Rotate(contacts[i].Normal)
+	}
+	return contacts
+}
+
+// collideConvex finds a single contact between any two convex shapes
+func collideConvex(a, b convexShape) []Contact {
+	res := gjkDistance(a, b)
+
+	var normal, pointA, pointB mgl32.Vec3
+	var depth float32
+	if res.Overlap {
+		// The cores overlap, so expand the simplex to find how far
+		var ok bool
+		depth, pointA, pointB, normal, ok = epaPenetration(a, b, res)
+		if !ok {
+			return nil
+		}
+		depth += a.Margin + b.Margin
+		pointA = pointA.Add(normal.Mul(a.Margin))
+		pointB = pointB.Sub(normal.Mul(b.Margin))
+	} else {
+		normal = res.PointB.Sub(res.PointA).Mul(1.0 / res.Distance)
+		depth = a.Margin + b.Margin - res.Distance
+		if depth <= 0.0 {
+			return nil
+		}
+		pointA = res.PointA.Add(normal.Mul(a.Margin))
+		pointB = res.PointB.Sub(normal.Mul(b.Margin))
+	}
+
+	return []Contact{{
+		Point:  pointA.Add(pointB).Mul(0.5),
+		Normal: normal,
+		Depth:  depth,
+	}}
+}
+
+// collidePlane finds the contacts between a Collider and a plane, with a
+// contact for every corner of the Collider behind the plane. A MeshCollider
+// can't move, so it never touches a plane and isn't handled
+func collidePlane(col Collider, pos mgl32.Vec3, rot mgl32.Quat, plane PlaneCollider, planePos mgl32.Vec3, planeRot mgl32.Quat) []Contact {
+	normal := planeRot.Rotate(plane.Normal).Normalize()
+	offset := normal.Dot(planePos) + plane.Offset
+
+	points := []mgl32.Vec3{}
+	margin := float32(0.0)
+	switch col := col.(type) {
+	case SphereCollider:
+		points = append(points, pos)
+		margin = col.Radius
+	case CapsuleCollider:
+		axis := rot.Rotate(mgl32.Vec3{0, col.HalfHeight, 0})
+		points = append(points, pos.Add(axis), pos.Sub(axis))
+		margin = col.Radius
+	case BoxCollider:
+		for _, p := range boxCorners(col.Size) {
+			points = append(points, pos.Add(rot.Rotate(p)))
+		}
+	case ConvexHullCollider:
+		for _, p := range col.Points {
+			points = append(points, pos.Add(rot.Rotate(p)))
+		}
+	}
+
+	contacts := []Contact{}
+	for i, p := range points {
+		depth := offset - normal.Dot(p) + margin
+		if depth <= 0.0 {
+			continue
+		}
+		contacts = append(contacts, Contact{
+			ID:     ContactID{Feature: uint32(i)},
+			Point:  p.Sub(normal.Mul(margin - depth*0.5)),
+			Normal: normal.Mul(-1.0),
+			Depth:  depth,
+		})
+	}
+	return contacts
+}
+
+func collideSpheres(colA SphereCollider, posA mgl32.Vec3, colB SphereCollider, posB mgl32.Vec3) []Contact {
+	radius := colA.Radius + colB.Radius
+	dist := DistanceSquared(posA, posB)
+	if dist >= radius*radius {
+		return nil
+	}
+
+	normal := mgl32.Vec3{0, 1, 0}
+	if dist > 0.0 {
+		normal = posB.Sub(posA).Normalize()
+	}
+	depth := radius - float32(math.Sqrt(float64(dist)))
+
+	return []Contact{{
+		Point:  posA.Add(normal.Mul(colA.Radius - depth*0.5)),
+		Normal: normal,
+		Depth:  depth,
+	}}
+}
+
+func collideSphereBox(colA SphereCollider, center mgl32.Vec3, colB BoxCollider, boxPos mgl32.Vec3) []Contact {
+	local := center.Sub(boxPos)
+
+	// Find the closest point on the box, and remember which side of each slab
+	// it was clamped to, to build a feature ID
+	closest := local
+	id := uint32(0)
+	inside := true
+	for i := 0; i < 3; i++ {
+		id *= 3
+		if local[i] < -colB.Size[i] {
+			closest[i] = -colB.Size[i]
+			id += 1
+			inside = false
+		} else if local[i] > colB.Size[i] {
+			closest[i] = colB.Size[i]
+			id += 2
+			inside = false
+		}
+	}
+
+	var normal mgl32.Vec3
+	var depth float32
+
+	if inside {
+		// Push out along the axis of least penetration
+		axis := 0
+		best := float32(math.MaxFloat32)
+		for i := 0; i < 3; i++ {
+			d := colB.Size[i] - float32(math.Abs(float64(local[i])))
+			if d < best {
+				best = d
+				axis = i
+			}
+		}
+		sign := float32(1.0)
+		if local[axis] < 0.0 {
+			sign = -1.0
+		}
+		normal = mgl32.Vec3{}
+		normal[axis] = -sign
+		closest[axis] = sign * colB.Size[axis]
+		depth = best + colA.Radius
+		id = 27 + uint32(axis)*2
+		if sign > 0.0 {
+			id++
+		}
+	} else {
+		diff := closest.Sub(local)
+		dist := diff.Len()
+		if dist >= colA.Radius {
+			return nil
+		}
+		normal = diff.Mul(1.0 / dist)
+		depth = colA.Radius - dist
+	}
+
+	return []Contact{{
+		ID:     ContactID{Feature: id},
+		Point:  boxPos.Add(closest),
+		Normal: normal,
+		Depth:  depth,
+	}}
+}
+
+func collideBoxes(colA BoxCollider, posA mgl32.Vec3, colB BoxCollider, posB mgl32.Vec3) []Contact {
+	diff := posB.Sub(posA)
+
+	// Boxes are turned the same way, so the separating axis is always one of
+	// their X, Y or Z
+	axis := 0
+	depth := float32(math.MaxFloat32)
+	for i := 0; i < 3; i++ {
+		overlap := colA.Size[i] + colB.Size[i] - float32(math.Abs(float64(diff[i])))
+		if overlap < 0.0 {
+			return nil
+		}
+		if overlap < depth {
+			depth = overlap
+			axis = i
+		}
+	}
+
+	normal := mgl32.Vec3{}
+	normal[axis] = 1.0
+	if diff[axis] < 0.0 {
+		normal[axis] = -1.0
+	}
+
+	// The contact region is the overlap of the two faces, put a contact at
+	// each of its corners, halfway between the faces
+	minA, maxA := posA.Sub(colA.Size), posA.Add(colA.Size)
+	minB, maxB := posB.Sub(colB.Size), posB.Add(colB.Size)
+	lo := mgl32.Vec3{}
+	hi := mgl32.Vec3{}
+	for i := 0; i < 3; i++ {
+		lo[i] = float32(math.Max(float64(minA[i]), float64(minB[i])))
+		hi[i] = float32(math.Min(float64(maxA[i]), float64(maxB[i])))
+	}
+	mid := (lo[axis] + hi[axis]) * 0.5
+
+	u := (axis + 1) % 3
+	v := (axis + 2) % 3
+
+	contacts := make([]Contact, 0, 4)
+	for corner := uint32(0); corner < 4; corner++ {
+		point := mgl32.Vec3{}
+		point[axis] = mid
+		point[u] = lo[u]
+		if corner&1 != 0 {
+			point[u] = hi[u]
+		}
+		point[v] = lo[v]
+		if corner&2 != 0 {
+			point[v] = hi[v]
+		}
+		contacts = append(contacts, Contact{
+			ID:     ContactID{Feature: uint32(axis)*4 + corner},
+			Point:  point,
+			Normal: normal,
+			Depth:  depth,
+		})
+	}
+	return contacts
+}
+
+// collideTurnedBoxes finds the contacts between two boxes turned different
+// ways. The axis they overlap least along is found from the axes of their
+// faces, and the ones across a pair of their edges. On a face, the face of the
+// other box facing it the most is clipped to it, with a contact at every
+// corner left behind it. Boxes touching edge to edge get the single contact
+// from collideConvex
+func collideTurnedBoxes(colA BoxCollider, posA mgl32.Vec3, rotA mgl32.Quat, colB BoxCollider, posB mgl32.Vec3, rotB mgl32.Quat) []Contact {
+	var axesA, axesB [3]mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		axis := mgl32.Vec3{}
+		axis[i] = 1.0
+		axesA[i] = rotA.Rotate(axis)
+		axesB[i] = rotB.Rotate(axis)
+	}
+	diff := posB.Sub(posA)
+
+	// overlap returns how far the boxes overlap along axis, which must be
+	// normalized
+	overlap := func(axis mgl32.Vec3) float32 {
+		radius := float32(0.0)
+		for i := 0; i < 3; i++ {
+			radius += colA.Size[i] * float32(math.Abs(float64(axis.Dot(axesA[i]))))
+			radius += colB.Size[i] * float32(math.Abs(float64(axis.Dot(axesB[i]))))
+		}
+		return radius - float32(math.Abs(float64(axis.Dot(diff))))
+	}
+
+	// Faces are preferred over edges that overlap about as little, since
+	// their contacts are steadier. For the same reason the faces of B only
+	// take over from A's when they overlap clearly less, so that boxes
+	// stacked square don't swap which face the contacts are on every frame
+	face := -1
+	depth := float32(math.MaxFloat32)
+	for i := 0; i < 3; i++ {
+		d := overlap(axesA[i])
+		if d <= 0.0 {
+			return nil
+		}
+		if d < depth {
+			depth = d
+			face = i
+		}
+	}
+	faceDepth := depth
+	for i := 0; i < 3; i++ {
+		d := overlap(axesB[i])
+		if d <= 0.0 {
+			return nil
+		}
+		if d < faceDepth*0.95-contactSlop && d < depth {
+			depth = d
+			face = i + 3
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			axis := axesA[i].Cross(axesB[j])
+			if axis.Len() < 1e-3 {
+				// Parallel edges, which the faces already cover
+				continue
+			}
+			d := overlap(axis.Normalize())
+			if d <= 0.0 {
+				return nil
+			}
+			if d < depth*0.95-contactSlop {
+				face = -1
+			}
+		}
+	}
+
+	if face < 0 {
+		shapeA, _ := newConvexShape(colA, posA, rotA)
+		shapeB, _ := newConvexShape(colB, posB, rotB)
+		return collideConvex(shapeA, shapeB)
+	}
+	if face < 3 {
+		// The face of A pointing towards B
+		reference := face * 2
+		if axesA[face].Dot(diff) < 0.0 {
+			reference++
+		}
+		contacts := clipBoxFaces(colA, posA, rotA, reference, colB, posB, rotB)
+		for i := range contacts {
+			contacts[i].ID.Feature = 12 + contacts[i].ID.Feature*2
+		}
+		return contacts
+	}
+	reference := (face - 3) * 2
+	if axesB[face-3].Dot(diff) > 0.0 {
+		reference++
+	}
+	contacts := flipContacts(clipBoxFaces(colB, posB, rotB, reference, colA, posA, rotA))
+	for i := range contacts {
+		// Keep them apart from the contacts on the faces of A
+		contacts[i].ID.Feature = 12 + contacts[i].ID.Feature*2 + 1
+	}
+	return contacts
+}
+
+// boxFace returns which face of a box turned by rot faces along direction the
+// most, as axis*2, plus 1 for the negative side, and how much it faces along it
+func boxFace(rot mgl32.Quat, direction mgl32.Vec3) (int, float32) {
+	local := rot.Conjugate().Rotate(direction)
+	face := 0
+	for i := 1; i < 3; i++ {
+		if math.Abs(float64(local[i])) > math.Abs(float64(local[face/2])) {
+			face = i * 2
+		}
+	}
+	align := local[face/2]
+	if align < 0.0 {
+		face++
+		align = -align
+	}
+	return face, align
+}
+
+// boxFaceCorners returns the corners of a face of a box, in order around it
+func boxFaceCorners(size mgl32.Vec3, face int) []mgl32.Vec3 {
+	axis := face / 2
+	u := (axis + 1) % 3
+	v := (axis + 2) % 3
+	corners := make([]mgl32.Vec3, 4)
+	for i, sign := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		corners[i][axis] = size[axis]
+		if face%2 != 0 {
+			corners[i][axis] = -size[axis]
+		}
+		corners[i][u] = sign[0] * size[u]
+		corners[i][v] = sign[1] * size[v]
+	}
+	return corners
+}
+
+// clipBoxFaces clips the face of box B that faces a face of box A the most to
+// the sides of A's face, and returns contacts in world space for the corners
+// left behind it
+func clipBoxFaces(colA BoxCollider, posA mgl32.Vec3, rotA mgl32.Quat, face int, colB BoxCollider, posB mgl32.Vec3, rotB mgl32.Quat) []Contact {
+	axis := face / 2
+	normal := mgl32.Vec3{}
+	normal[axis] = 1.0
+	if face%2 != 0 {
+		normal[axis] = -1.0
+	}
+
+	// Work in the space of A, where its face is axis aligned
+	inv := rotA.Conjugate()
+	incident, _ := boxFace(inv.Mul(rotB), normal.Mul(-1.0))
+	polygon := []clipPoint{}
+	for i, corner := range boxFaceCorners(colB.Size, incident) {
+		polygon = append(polygon, clipPoint{
+			point:   inv.Rotate(posB.Add(rotB.Rotate(corner)).Sub(posA)),
+			feature: uint32(i),
+		})
+	}
+	for i, side := range []int{(axis + 1) % 3, (axis + 2) % 3} {
+		polygon = clipPolygon(polygon, side, 1.0, colA.Size[side], uint32(i*2))
+		polygon = clipPolygon(polygon, side, -1.0, colA.Size[side], uint32(i*2+1))
+	}
+
+	contacts := []Contact{}
+	for _, p := range polygon {
+		depth := colA.Size[axis] - normal.Dot(p.point)
+		// Corners just short of touching are kept, so a box resting on a
+		// face doesn't lose a corner to rounding and tip over
+		if depth <= -contactSlop {
+			continue
+		}
+		contacts = append(contacts, Contact{
+			ID:     ContactID{Feature: (p.feature*6+uint32(incident))*6 + uint32(face)},
+			Point:  p.point.Add(normal.Mul(depth * 0.5)),
+			Normal: normal,
+			Depth:  depth,
+		})
+	}
+	return toWorldContacts(contacts, posA, rotA)
+}
+
+// clipPoint is a corner of a polygon being clipped, with which corner of the
+// face it was, or which edge and side cut it, to tell it apart between frames
+type clipPoint struct {
+	point   mgl32.Vec3
+	feature uint32
+}
+
+// clipPolygon cuts off the part of a polygon where sign*point[axis] is over
+// limit. The side is one of the 4 sides of the face being clipped to
+func clipPolygon(polygon []clipPoint, axis int, sign, limit float32, side uint32) []clipPoint {
+	clipped := make([]clipPoint, 0, len(polygon)+1)
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		distA := sign*a.point[axis] - limit
+		distB := sign*b.point[axis] - limit
+		if distA <= 0.0 {
+			clipped = append(clipped, a)
+		}
+		if (distA < 0.0 && distB > 0.0) || (distA > 0.0 && distB < 0.0) {
+			clipped = append(clipped, clipPoint{
+				point:   a.point.Add(b.point.Sub(a.point).Mul(distA / (distA - distB))),
+				feature: 4 + a.feature*4 + side,
+			})
+		}
+	}
+	return clipped
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newTestFloor adds a static box to the World, with its top at y = 0
+func newTestFloor(w *World) *Actor {
+	floor := NewActor()
+	floor.Transform.Scale = mgl32.Vec3{100, 0, 100}
+	floor.RigidBody.Collider = BoxCollider{Size: floor.Transform.Scale}
+	floor.RigidBody.Mass = math.MaxFloat32
+	w.AddActor(floor)
+	return floor
+}
+
+// newTestBox adds a box falling under gravity to the World
+func newTestBox(w *World, position, size mgl32.Vec3) *Actor {
+	box := NewActor()
+	box.Transform.Position = position
+	box.Transform.Scale = size
+	box.RigidBody.Collider = BoxCollider{Size: size}
+	box.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	w.AddActor(box)
+	return box
+}
+
+func TestMatchContacts(t *testing.T) {
+	contact := func(feature uint32, impulse float32) Contact {
+		return Contact{ID: ContactID{Feature: feature}, NormalImpulse: impulse}
+	}
+
+	tests := []struct {
+		name     string
+		old      []Contact
+		contacts []Contact
+		hits     int
+		impulses []float32
+	}{
+		{"no previous frame", nil, []Contact{contact(1, 0)}, 0, []float32{0}},
+		{"same features", []Contact{contact(1, 2), contact(2, 3)}, []Contact{contact(2, 0), contact(1, 0)}, 2, []float32{3, 2}},
+		{"new feature", []Contact{contact(1, 2)}, []Contact{contact(1, 0), contact(5, 0)}, 1, []float32{2, 0}},
+		{"lost feature", []Contact{contact(1, 2), contact(2, 3)}, []Contact{contact(2, 0)}, 1, []float32{3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &ContactManifold{Contacts: test.contacts}
+			var old *ContactManifold
+			if test.old != nil {
+				old = &ContactManifold{Contacts: test.old}
+			}
+			if hits := m.matchContacts(old); hits != test.hits {
+				t.Errorf("got %d hits, want %d", hits, test.hits)
+			}
+			for i, impulse := range test.impulses {
+				if m.Contacts[i].NormalImpulse != impulse {
+					t.Errorf("contact %d has impulse %v, want %v", i, m.Contacts[i].NormalImpulse, impulse)
+				}
+			}
+		})
+	}
+}
+
+func TestWarmStartingHitsResting(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	newTestBox(w, mgl32.Vec3{0, 1, 0}, mgl32.Vec3{1, 1, 1})
+	for i := 0; i < 30; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	if w.Stats.Contacts == 0 {
+		t.Fatal("box resting on the floor has no contacts")
+	}
+	if w.Stats.CacheHits != w.Stats.Contacts {
+		t.Errorf("%d of %d resting contacts were warm started", w.Stats.CacheHits, w.Stats.Contacts)
+	}
+}
+
+func TestCollideColliders(t *testing.T) {
+	box := BoxCollider{Size: mgl32.Vec3{1, 1, 1}}
+	hull, _ := NewConvexHullCollider(boxCorners(mgl32.Vec3{1, 1, 1}))
+	turned := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 1, 0})
+	tilted := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 0, 1})
+
+	tests := []struct {
+		name       string
+		colA, colB Collider
+		posB       mgl32.Vec3
+		rotB       mgl32.Quat
+		contacts   int
+		depth      float32
+		normal     mgl32.Vec3
+	}{
+		{"spheres", SphereCollider{Radius: 1}, SphereCollider{Radius: 1}, mgl32.Vec3{1.5, 0, 0}, mgl32.QuatIdent(), 1, 0.5, mgl32.Vec3{1, 0, 0}},
+		{"boxes apart", box, box, mgl32.Vec3{0, 2.5, 0}, mgl32.QuatIdent(), 0, 0, mgl32.Vec3{}},
+		{"box on box", box, box, mgl32.Vec3{0.5, 1.9, 0}, mgl32.QuatIdent(), 4, 0.1, mgl32.Vec3{0, 1, 0}},
+		{"turned box on box", box, box, mgl32.Vec3{0, 1.9, 0}, turned, 8, 0.1, mgl32.Vec3{0, 1, 0}},
+		{"box on its edge", box, box, mgl32.Vec3{0, 0.9 + math.Sqrt2, 0}, tilted, 2, 0.1, mgl32.Vec3{0, 1, 0}},
+		{"hulls", hull, hull, mgl32.Vec3{0, 1.8, 0}, mgl32.QuatIdent(), 1, 0.2, mgl32.Vec3{0, 1, 0}},
+		{"sphere in hull", hull, SphereCollider{Radius: 1}, mgl32.Vec3{0.3, 1.5, 0}, mgl32.QuatIdent(), 1, 0.5, mgl32.Vec3{0, 1, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contacts := collideColliders(test.colA, mgl32.Vec3{}, mgl32.QuatIdent(), test.colB, test.posB, test.rotB)
+			if len(contacts) != test.contacts {
+				t.Fatalf("got %d contacts, want %d", len(contacts), test.contacts)
+			}
+			for _, c := range contacts {
+				if math.Abs(float64(c.Depth-test.depth)) > 1e-3 {
+					t.Errorf("contact at %v has depth %v, want %v", c.Point, c.Depth, test.depth)
+				}
+				if c.Normal.Sub(test.normal).Len() > 1e-3 {
+					t.Errorf("contact at %v has normal %v, want %v", c.Point, c.Normal, test.normal)
+				}
+			}
+		})
+	}
+}
+
+func TestTurnedBoxRests(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	box := newTestBox(w, mgl32.Vec3{0, 1, 0}, mgl32.Vec3{1, 0.5, 2})
+	box.RigidBody.Restitution = 0.0
+	rotation := mgl32.QuatRotate(0.5, mgl32.Vec3{0, 1, 0})
+	box.Transform.Rotation = rotation
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	position := box.Transform.Position
+	if math.Abs(float64(position.X())) > 1e-3 || math.Abs(float64(position.Z())) > 1e-3 {
+		t.Errorf("box slid to %v", position)
+	}
+	if y := position.Y(); y < 0.45 || y > 0.5 {
+		t.Errorf("box came to rest at y = %v, want about 0.5", y)
+	}
+	if angle := box.Transform.Rotation.Dot(rotation); angle < 0.9999 {
+		t.Errorf("box turned from %v to %v", rotation, box.Transform.Rotation)
+	}
+	if v := box.RigidBody.AngularVelocity.Len(); v > 1e-3 {
+		t.Errorf("box is still spinning at %v", v)
+	}
+}
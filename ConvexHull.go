@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ConvexHullCollider is the smallest convex shape containing a set of points,
+// in local space. It rotates with the body
+type ConvexHullCollider struct {
+	Points []mgl32.Vec3
+	// Triangles, wound counter-clockwise when seen from outside
+	Faces [][3]int
+	// The plane of each face, where Normals[i].Dot(p) == Offsets[i]
+	Normals []mgl32.Vec3
+	Offsets []float32
+}
+
+// NewConvexHullCollider builds the convex hull of points, points inside of the
+// hull are dropped
+func NewConvexHullCollider(points []mgl32.Vec3) (ConvexHullCollider, error) {
+	hull := ConvexHullCollider{}
+	if len(points) < 4 {
+		return hull, fmt.Errorf("Convex hull needs at least 4 points, got %v", len(points))
+	}
+
+	bounds := AABB{Min: points[0], Max: points[0]}
+	for _, p := range points {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	eps := bounds.Size().Len() * 1e-5
+
+	// Start with the largest tetrahedron we can easily find
+	farthest := func(score func(p mgl32.Vec3) float32) int {
+		best := 0
+		for i := range points {
+			if score(points[i]) > score(points[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	i0 := 0
+	i1 := farthest(func(p mgl32.Vec3) float32 {
+		return p.Sub(points[i0]).LenSqr()
+	})
+	line := points[i1].Sub(points[i0])
+	i2 := farthest(func(p mgl32.Vec3) float32 {
+		return p.Sub(points[i0]).Cross(line).LenSqr()
+	})
+	normal := line.Cross(points[i2].Sub(points[i0]))
+	i3 := farthest(func(p mgl32.Vec3) float32 {
+		return float32(math.Abs(float64(p.Sub(points[i0]).Dot(normal))))
+	})
+
+	if line.Len() <= eps || normal.Len() <= eps*line.Len() ||
+		math.Abs(float64(points[i3].Sub(points[i0]).Dot(normal.Normalize()))) <= float64(eps) {
+		return hull, fmt.Errorf("Convex hull points are all on the same plane")
+	}
+
+	center := points[i0].Add(points[i1]).Add(points[i2]).Add(points[i3]).Mul(0.25)
+
+	// Orient a face so it winds counter-clockwise when seen from outside
+	makeFace := func(a, b, c int) [3]int {
+		n := points[b].Sub(points[a]).Cross(points[c].Sub(points[a]))
+		if n.Dot(points[a].Sub(center)) < 0.0 {
+			return [3]int{a, c, b}
+		}
+		return [3]int{a, b, c}
+	}
+	visible := func(face [3]int, p mgl32.Vec3) bool {
+		a := points[face[0]]
+		n := points[face[1]].Sub(a).Cross(points[face[2]].Sub(a))
+		if n.Len() == 0.0 {
+			return false
+		}
+		return n.Normalize().Dot(p.Sub(a)) > eps
+	}
+
+	faces := [][3]int{
+		makeFace(i0, i1, i2),
+		makeFace(i0, i1, i3),
+		makeFace(i0, i2, i3),
+		makeFace(i1, i2, i3),
+	}
+
+	// Add each point in turn, replacing every face it can see with a fan of
+	// faces from the edge of that region to the point
+	for i, p := range points {
+		if i == i0 || i == i1 || i == i2 || i == i3 {
+			continue
+		}
+
+		kept := [][3]int{}
+		horizon := [][2]int{}
+		for _, face := range faces {
+			if !visible(face, p) {
+				kept = append(kept, face)
+				continue
+			}
+
+			// Edges shared by two visible faces cancel out, leaving the horizon
+			for e := 0; e < 3; e++ {
+				edge := [2]int{face[e], face[(e+1)%3]}
+				shared := false
+				for j := range horizon {
+					if horizon[j] == [2]int{edge[1], edge[0]} {
+						horizon = append(horizon[:j], horizon[j+1:]...)
+						shared = true
+						break
+					}
+				}
+				if !shared {
+					horizon = append(horizon, edge)
+				}
+			}
+		}
+		if len(kept) == len(faces) {
+			continue
+		}
+
+		for _, edge := range horizon {
+			kept = append(kept, [3]int{edge[0], edge[1], i})
+		}
+		faces = kept
+	}
+
+	// Only keep the points that ended up on the hull
+	remap := map[int]int{}
+	for _, face := range faces {
+		for e := 0; e < 3; e++ {
+			if _, ok := remap[face[e]]; !ok {
+				remap[face[e]] = len(hull.Points)
+				hull.Points = append(hull.Points, points[face[e]])
+			}
+		}
+	}
+	for _, face := range faces {
+		face = [3]int{remap[face[0]], remap[face[1]], remap[face[2]]}
+		a := hull.Points[face[0]]
+		n := hull.Points[face[1]].Sub(a).Cross(hull.Points[face[2]].Sub(a))
+		if n.Len() == 0.0 {
+			continue
+		}
+		n = n.Normalize()
+
+		hull.Faces = append(hull.Faces, face)
+		hull.Normals = append(hull.Normals, n)
+		hull.Offsets = append(hull.Offsets, n.Dot(a))
+	}
+
+	return hull, nil
+}
+
+// support returns the point on the hull furthest along direction, in local
+// space
+func (hull ConvexHullCollider) support(direction mgl32.Vec3) mgl32.Vec3 {
+	best := hull.Points[0]
+	bestDot := best.Dot(direction)
+	for _, p := range hull.Points[1:] {
+		if d := p.Dot(direction); d > bestDot {
+			best = p
+			bestDot = d
+		}
+	}
+	return best
+}
+
+func raycastHull(hull ConvexHullCollider, pos mgl32.Vec3, rot mgl32.Quat, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	inv := rot.Conjugate()
+	o := inv.Rotate(origin.Sub(pos))
+	d := inv.Rotate(direction)
+
+	// Clip the ray against every face plane
+	enter := float32(0.0)
+	exit := maxDist
+	normal := mgl32.Vec3{}
+	inside := true
+
+	for i, n := range hull.Normals {
+		dist := n.Dot(o) - hull.Offsets[i]
+		denom := n.Dot(d)
+		if dist > 0.0 {
+			inside = false
+		}
+
+		if denom == 0.0 {
+			if dist > 0.0 {
+				return 0.0, mgl32.Vec3{}, false
+			}
+			continue
+		}
+
+		t := -dist / denom
+		if denom < 0.0 {
+			if t > enter {
+				enter = t
+				normal = n
+			}
+		} else if t < exit {
+			exit = t
+		}
+		if enter > exit {
+			return 0.0, mgl32.Vec3{}, false
+		}
+	}
+
+	if inside {
+		return 0.0, mgl32.Vec3{}, false
+	}
+	return enter, rot.Rotate(normal), true
+}
+
+// boxCorners returns the corners of a box with a half-extent of size, in its
+// local space
+func boxCorners(size mgl32.Vec3) []mgl32.Vec3 {
+	corners := make([]mgl32.Vec3, 0, 8)
+	for corner := 0; corner < 8; corner++ {
+		point := size.Mul(-1.0)
+		for i := 0; i < 3; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				point[i] = size[i]
+			}
+		}
+		corners = append(corners, point)
+	}
+	return corners
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewConvexHullCollider(t *testing.T) {
+	cube := boxCorners(mgl32.Vec3{1, 1, 1})
+	tests := []struct {
+		name   string
+		points []mgl32.Vec3
+		err    bool
+		kept   int
+		faces  int
+	}{
+		{"tetrahedron", []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, false, 4, 4},
+		{"cube", cube, false, 8, 12},
+		{"cube with points inside", append(append([]mgl32.Vec3{}, cube...), mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0.5, -0.5, 0.2}), false, 8, 12},
+		{"too few points", cube[:3], true, 0, 0},
+		{"flat", []mgl32.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 0, 1}, {1, 0, 1}, {0.5, 0, 0.5}}, true, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hull, err := NewConvexHullCollider(test.points)
+			if (err != nil) != test.err {
+				t.Fatalf("err = %v, want an error: %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			if len(hull.Points) != test.kept || len(hull.Faces) != test.faces {
+				t.Errorf("hull has %d points and %d faces, want %d and %d", len(hull.Points), len(hull.Faces), test.kept, test.faces)
+			}
+
+			// Every point is on or behind every face, so the faces face out
+			for i, normal := range hull.Normals {
+				for _, p := range test.points {
+					if normal.Dot(p) > hull.Offsets[i]+1e-4 {
+						t.Fatalf("point %v is in front of face %d", p, i)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// dynamicMesh draws a triangle mesh in world space whose vertices move every
+// frame, with smooth normals
+type dynamicMesh struct {
+	model *Model
+	verts []float32
+	norms []float32
+}
+
+// Cleanup frees up resources
+func (mesh *dynamicMesh) Cleanup() {
+	if mesh.model != nil {
+		mesh.model.Cleanup()
+		mesh.model = nil
+	}
+}
+
+func (mesh *dynamicMesh) Render(shader *Shader, positions []mgl32.Vec3, triangles [][3]int) {
+	if mesh.model == nil {
+		mesh.model, _ = NewDynamicModel(len(triangles) * 3)
+	}
+
+	normals := smoothNormals(positions, triangles)
+	mesh.verts = mesh.verts[:0]
+	mesh.norms = mesh.norms[:0]
+	for _, tri := range triangles {
+		for _, j := range tri {
+			p := positions[j]
+			n := normals[j]
+			mesh.verts = append(mesh.verts, p[0], p[1], p[2])
+			mesh.norms = append(mesh.norms, n[0], n[1], n[2])
+		}
+	}
+	mesh.model.UpdateVertices(mesh.verts, mesh.norms)
+
+	shader.Use()
+
+	model := mgl32.Ident4()
+	gl.UniformMatrix4fv(shader.GetUniformLocation("u_Model"), 1, false, &model[0])
+	mesh.model.Render(shader)
+}
+
+// smoothNormals works out a normal for every vertex, from the area weighted
+// normals of the triangles around it
+func smoothNormals(positions []mgl32.Vec3, triangles [][3]int) []mgl32.Vec3 {
+	normals := make([]mgl32.Vec3, len(positions))
+	for _, tri := range triangles {
+		pa := positions[tri[0]]
+		n := positions[tri[1]].Sub(pa).Cross(positions[tri[2]].Sub(pa))
+		for _, j := range tri {
+			normals[j] = normals[j].Add(n)
+		}
+	}
+	for i := range normals {
+		if normals[i].Len() > 0.0 {
+			normals[i] = normals[i].Normalize()
+		}
+	}
+	return normals
+}
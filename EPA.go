@@ -0,0 +1,211 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	epaMaxIterations = 64
+	// How close the polytope has to get to the surface of the Minkowski
+	// difference before we stop expanding it
+	epaTolerance = float32(1e-4)
+)
+
+type epaFace struct {
+	Verts [3]int
+	// Normal points away from the polytope, and Dist is how far the plane of
+	// the face is from the origin
+	Normal mgl32.Vec3
+	Dist   float32
+}
+
+// epaPenetration finds how far the cores of two overlapping shapes are inside
+// each other, by growing the simplex that GJK finished with out to the surface
+// of their Minkowski difference with the Expanding Polytope Algorithm. The
+// normal points from a to b, and ok is false if the simplex can't be expanded
+func epaPenetration(a, b convexShape, res gjkResult) (depth float32, pointA, pointB, normal mgl32.Vec3, ok bool) {
+	support := func(direction mgl32.Vec3) simplexVertex {
+		pa := a.Support(direction)
+		pb := b.Support(direction.Mul(-1.0))
+		return simplexVertex{W: pa.Sub(pb), A: pa, B: pb}
+	}
+
+	verts := make([]simplexVertex, 0, 16)
+	for i := 0; i < res.simplex.Count; i++ {
+		verts = append(verts, res.simplex.Verts[i])
+	}
+	if len(verts) == 0 {
+		verts = append(verts, support(mgl32.Vec3{1, 0, 0}))
+	}
+
+	// GJK can stop with fewer than 4 points when the origin is on the boundary
+	// of the simplex, so grow it into a tetrahedron first
+	axes := []mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for len(verts) < 4 {
+		directions := []mgl32.Vec3{}
+		switch len(verts) {
+		case 1:
+			for _, axis := range axes {
+				directions = append(directions, axis, axis.Mul(-1.0))
+			}
+		case 2:
+			line := verts[1].W.Sub(verts[0].W)
+			for _, axis := range axes {
+				if perp := line.Cross(axis); perp.LenSqr() > 0.0 {
+					directions = append(directions, perp, perp.Mul(-1.0))
+				}
+			}
+		case 3:
+			n := verts[1].W.Sub(verts[0].W).Cross(verts[2].W.Sub(verts[0].W))
+			directions = append(directions, n, n.Mul(-1.0))
+		}
+
+		grown := false
+		for _, direction := range directions {
+			v := support(direction)
+			if epaAddsVolume(verts, v.W) {
+				verts = append(verts, v)
+				grown = true
+				break
+			}
+		}
+		if !grown {
+			return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, false
+		}
+	}
+
+	center := verts[0].W.Add(verts[1].W).Add(verts[2].W).Add(verts[3].W).Mul(0.25)
+	makeFace := func(i, j, k int) (epaFace, bool) {
+		wa := verts[i].W
+		n := verts[j].W.Sub(wa).Cross(verts[k].W.Sub(wa))
+		if n.Len() == 0.0 {
+			return epaFace{}, false
+		}
+		n = n.Normalize()
+		if n.Dot(wa.Sub(center)) < 0.0 {
+			n = n.Mul(-1.0)
+			j, k = k, j
+		}
+		return epaFace{Verts: [3]int{i, j, k}, Normal: n, Dist: n.Dot(wa)}, true
+	}
+
+	faces := []epaFace{}
+	for _, f := range [4][3]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 3}, {1, 2, 3}} {
+		face, ok := makeFace(f[0], f[1], f[2])
+		if !ok {
+			return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, false
+		}
+		faces = append(faces, face)
+	}
+
+	closest := 0
+	for it := 0; it < epaMaxIterations; it++ {
+		closest = 0
+		for i := range faces {
+			if faces[i].Dist < faces[closest].Dist {
+				closest = i
+			}
+		}
+		face := faces[closest]
+
+		v := support(face.Normal)
+		if v.W.Dot(face.Normal)-face.Dist <= epaTolerance {
+			break
+		}
+
+		// Replace every face the new point can see with a fan of faces from
+		// the edge of that region to the point
+		verts = append(verts, v)
+		index := len(verts) - 1
+
+		kept := []epaFace{}
+		horizon := [][2]int{}
+		for _, f := range faces {
+			if f.Normal.Dot(v.W.Sub(verts[f.Verts[0]].W)) <= 0.0 {
+				kept = append(kept, f)
+				continue
+			}
+			for e := 0; e < 3; e++ {
+				edge := [2]int{f.Verts[e], f.Verts[(e+1)%3]}
+				shared := false
+				for j := range horizon {
+					if horizon[j] == [2]int{edge[1], edge[0]} {
+						horizon = append(horizon[:j], horizon[j+1:]...)
+						shared = true
+						break
+					}
+				}
+				if !shared {
+					horizon = append(horizon, edge)
+				}
+			}
+		}
+		if len(kept) == len(faces) {
+			break
+		}
+
+		for _, edge := range horizon {
+			if f, ok := makeFace(edge[0], edge[1], index); ok {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == 0 {
+			return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, false
+		}
+		faces = kept
+	}
+
+	closest = 0
+	for i := range faces {
+		if faces[i].Dist < faces[closest].Dist {
+			closest = i
+		}
+	}
+	face := faces[closest]
+
+	// Find the witness points from where the origin projects onto the face
+	u, v, w := barycentric(face.Normal.Mul(face.Dist),
+		verts[face.Verts[0]].W, verts[face.Verts[1]].W, verts[face.Verts[2]].W)
+	pointA = verts[face.Verts[0]].A.Mul(u).Add(verts[face.Verts[1]].A.Mul(v)).Add(verts[face.Verts[2]].A.Mul(w))
+	pointB = verts[face.Verts[0]].B.Mul(u).Add(verts[face.Verts[1]].B.Mul(v)).Add(verts[face.Verts[2]].B.Mul(w))
+
+	depth = float32(math.Max(float64(face.Dist), 0.0))
+	return depth, pointA, pointB, face.Normal, true
+}
+
+// epaAddsVolume is true if w isn't on the line or plane through points
+func epaAddsVolume(points []simplexVertex, w mgl32.Vec3) bool {
+	switch len(points) {
+	case 1:
+		return w.Sub(points[0].W).LenSqr() > epaTolerance*epaTolerance
+	case 2:
+		line := points[1].W.Sub(points[0].W)
+		return line.Cross(w.Sub(points[0].W)).Len() > epaTolerance*line.Len()
+	case 3:
+		n := points[1].W.Sub(points[0].W).Cross(points[2].W.Sub(points[0].W))
+		return float32(math.Abs(float64(n.Dot(w.Sub(points[0].W))))) > epaTolerance*n.Len()
+	}
+	return false
+}
+
+// barycentric returns the weights of a, b and c that make up p
+func barycentric(p, a, b, c mgl32.Vec3) (u, v, w float32) {
+	v0 := b.Sub(a)
+	v1 := c.Sub(a)
+	v2 := p.Sub(a)
+	d00 := v0.Dot(v0)
+	d01 := v0.Dot(v1)
+	d11 := v1.Dot(v1)
+	d20 := v2.Dot(v0)
+	d21 := v2.Dot(v1)
+
+	denom := d00*d11 - d01*d01
+	if denom == 0.0 {
+		return 1.0, 0.0, 0.0
+	}
+	v = (d11*d20 - d01*d21) / denom
+	w = (d00*d21 - d01*d20) / denom
+	return 1.0 - v - w, v, w
+}
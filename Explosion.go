@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Falloff is how a force weakens with distance from its source
+type Falloff int8
+
+const (
+	// NoFalloff applies the full force everywhere within range
+	NoFalloff Falloff = iota
+	// LinearFalloff fades the force out to nothing at the edge of the range
+	LinearFalloff
+	// QuadraticFalloff fades the force out faster, as the square of the
+	// linear falloff
+	QuadraticFalloff
+)
+
+// Attenuate scales a force by the falloff at dist, for a range of radius
+func (falloff Falloff) Attenuate(force mgl32.Vec3, dist, radius float32) mgl32.Vec3 {
+	if dist >= radius {
+		return mgl32.Vec3{0, 0, 0}
+	}
+
+	scale := 1.0 - dist/radius
+	switch falloff {
+	case NoFalloff:
+		scale = 1.0
+	case QuadraticFalloff:
+		scale *= scale
+	}
+	return force.Mul(scale)
+}
+
+// ApplyExplosionForce pushes every body within radius of center away from
+// it, with an impulse of strength that weakens with distance from the
+// surface of each body. The push comes from upwardsModifier below center,
+// which throws bodies upwards more than a real explosion would. The impulse
+// is applied at the closest point on each body, so it can set bodies spinning
+func (w *World) ApplyExplosionForce(center mgl32.Vec3, radius, strength float32, falloff Falloff, upwardsModifier float32) {
+	origin := center.Sub(mgl32.Vec3{0, upwardsModifier, 0})
+	point, _ := newConvexShape(SphereCollider{Radius: 0.0}, center, mgl32.QuatIdent())
+	bounds := NewAABB(center, mgl32.Vec3{radius, radius, radius})
+
+	for _, rb := range w.OverlapSphere(center, radius, AllLayers) {
+		transform := &rb.Parent.Transform
+
+		dist := radius
+		closest := rb.WorldCenterOfMass()
+		if plane, ok := rb.Collider.(PlaneCollider); ok {
+			dist, closest, _ = closestOnPlane(center, plane, transform.Position, transform.Rotation)
+		}
+		for _, target := range convexPieces(rb.Collider, transform.Position, transform.Rotation, bounds) {
+			d, _, pointB, _, overlap := shapeDistance(point, target)
+			if overlap {
+				d = 0.0
+				pointB = rb.WorldCenterOfMass()
+			}
+			if d < dist {
+				dist = d
+				closest = pointB
+			}
+		}
+
+		direction := rb.Parent.Transform.Position.Sub(origin)
+		if direction.Len() == 0.0 {
+			direction = mgl32.Vec3{0, 1, 0}
+		}
+		force := direction.Normalize().Mul(strength)
+
+		rb.ApplyForceAtPosition(falloff.Attenuate(force, dist, radius), closest, Impulse)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestFalloffAttenuate(t *testing.T) {
+	force := mgl32.Vec3{0, 10, 0}
+	tests := []struct {
+		falloff Falloff
+		dist    float32
+		want    float32
+	}{
+		{NoFalloff, 0, 10},
+		{NoFalloff, 9, 10},
+		{NoFalloff, 10, 0},
+		{LinearFalloff, 0, 10},
+		{LinearFalloff, 5, 5},
+		{LinearFalloff, 7.5, 2.5},
+		{LinearFalloff, 12, 0},
+		{QuadraticFalloff, 5, 2.5},
+		{QuadraticFalloff, 8, 0.4},
+	}
+	for _, test := range tests {
+		got := test.falloff.Attenuate(force, test.dist, 10).Y()
+		if math.Abs(float64(got-test.want)) > 1e-4 {
+			t.Errorf("falloff %d at %v = %v, want %v", test.falloff, test.dist, got, test.want)
+		}
+	}
+}
+
+func TestApplyExplosionForce(t *testing.T) {
+	w := NewWorld()
+	addSphere := func(position mgl32.Vec3) *RigidBody {
+		actor := NewActor()
+		actor.Transform.Position = position
+		actor.RigidBody.Collider = SphereCollider{Radius: 1}
+		actor.RigidBody.Mass = 2
+		w.AddActor(actor)
+		return actor.RigidBody
+	}
+	near := addSphere(mgl32.Vec3{3, 0, 0})
+	far := addSphere(mgl32.Vec3{0, 0, -7})
+	outside := addSphere(mgl32.Vec3{0, 12, 0})
+
+	w.ApplyExplosionForce(mgl32.Vec3{}, 10, 20, LinearFalloff, 0)
+
+	tests := []struct {
+		name string
+		body *RigidBody
+		want mgl32.Vec3
+	}{
+		// The surface of each sphere is 1 closer than its center
+		{"near", near, mgl32.Vec3{20 * 0.8 / 2, 0, 0}},
+		{"far", far, mgl32.Vec3{0, 0, -20 * 0.4 / 2}},
+		{"outside", outside, mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		if test.body.Velocity.Sub(test.want).Len() > 1e-3 {
+			t.Errorf("%s sphere has velocity %v, want %v", test.name, test.body.Velocity, test.want)
+		}
+		// Spheres are pushed through their center, so they shouldn't spin
+		if test.body.AngularVelocity.Len() > 1e-3 {
+			t.Errorf("%s sphere has angular velocity %v", test.name, test.body.AngularVelocity)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// FluidSource pours particles into a Fluid
+type FluidSource struct {
+	Position mgl32.Vec3
+	// Velocity particles start with, per frame like Velocity
+	Velocity mgl32.Vec3
+	// Rate is the number of particles added every second
+	Rate float32
+	// Radius particles are scattered within around Position
+	Radius float32
+
+	pending float32
+}
+
+// Fluid is a smoothed particle hydrodynamics liquid, solved with
+// position-based dynamics so it stays incompressible at large steps. Every
+// particle is pushed away from its neighbours until the density around it is
+// back to RestDensity. It is pushed out of static RigidBody colliders, but
+// passes through moving ones
+type Fluid struct {
+	Positions  []mgl32.Vec3
+	Velocities []mgl32.Vec3
+	// MaxParticles stops Sources and AddParticle adding more
+	MaxParticles int
+	Sources      []*FluidSource
+
+	// ParticleRadius is half the distance particles settle apart
+	ParticleRadius float32
+	// SmoothingRadius is how far away particles affect each other
+	SmoothingRadius float32
+	ParticleMass    float32
+	RestDensity     float32
+
+	// Iterations is how many times the density is solved every step, and
+	// Substeps splits every Update into smaller steps
+	Iterations int
+	Substeps   int
+	// Relaxation softens the density constraint, so particles with few
+	// neighbours don't get pushed apart too hard
+	Relaxation float32
+
+	// Viscosity, from 0 to 1, is how much particles take on the velocity of
+	// their neighbours
+	Viscosity float32
+	// SurfaceTension pulls particles towards their neighbours, so the
+	// surface holds together in drops
+	SurfaceTension float32
+
+	Gravity mgl32.Vec3
+	// Friction is how much of a particle's sliding velocity is lost when it
+	// is pushed out of a collider
+	Friction float32
+	// CollisionMask has a bit set for every layer the fluid collides with
+	CollisionMask uint32
+
+	// Model is drawn for every particle, scaled to ParticleRadius, in Color
+	Model *Model
+	Color mgl32.Vec4
+
+	// Constant parts of the kernels, which depend on SmoothingRadius
+	kernelScale   float32
+	gradientScale float32
+
+	hash      *SpatialHash
+	neighbors [][]int
+	predicted []mgl32.Vec3
+	lambdas   []float32
+	deltas    []mgl32.Vec3
+
+	mesh    instancedMesh
+	offsets []mgl32.Vec4
+	colors  []mgl32.Vec4
+}
+
+// NewFluid creates an empty Fluid whose particles settle radius*2 apart
+func NewFluid(radius float32) *Fluid {
+	fluid := &Fluid{
+		Positions:    []mgl32.Vec3{},
+		Velocities:   []mgl32.Vec3{},
+		MaxParticles: 2000,
+		Sources:      []*FluidSource{},
+
+		ParticleRadius:  radius,
+		SmoothingRadius: radius * 4.0,
+		ParticleMass:    1.0,
+
+		Iterations: 4,
+		Substeps:   2,
+
+		Viscosity:      0.05,
+		SurfaceTension: 0.2,
+
+		Gravity:       mgl32.Vec3{0, -9.81, 0},
+		Friction:      0.1,
+		CollisionMask: AllLayers,
+
+		Color: mgl32.Vec4{0.2, 0.4, 0.9, 1.0},
+
+		hash: NewSpatialHash(radius * 4.0),
+	}
+
+	fluid.updateKernels()
+
+	// The rest density and relaxation are whatever they are for a particle
+	// in the middle of a grid of them, spaced as they should settle
+	fluid.RestDensity = 0.0
+	spacing := radius * 2.0
+	steps := int(math.Ceil(float64(fluid.SmoothingRadius / spacing)))
+	gradients := float32(0.0)
+	sum := mgl32.Vec3{}
+	for x := -steps; x <= steps; x++ {
+		for y := -steps; y <= steps; y++ {
+			for z := -steps; z <= steps; z++ {
+				d := mgl32.Vec3{float32(x), float32(y), float32(z)}.Mul(spacing)
+				fluid.RestDensity += fluid.ParticleMass * fluid.kernel(d.LenSqr())
+				g := fluid.kernelGradient(d)
+				sum = sum.Add(g)
+				gradients += g.LenSqr()
+			}
+		}
+	}
+	scale := fluid.ParticleMass / fluid.RestDensity
+	fluid.Relaxation = 0.1 * (gradients + sum.LenSqr()) * scale * scale
+
+	return fluid
+}
+
+// Cleanup frees up resources
+func (fluid *Fluid) Cleanup() {
+	fluid.mesh.Cleanup()
+}
+
+// AddParticle adds a particle at p moving at v, unless there are already
+// MaxParticles
+func (fluid *Fluid) AddParticle(p, v mgl32.Vec3) bool {
+	if len(fluid.Positions) >= fluid.MaxParticles {
+		return false
+	}
+	fluid.Positions = append(fluid.Positions, p)
+	fluid.Velocities = append(fluid.Velocities, v)
+	return true
+}
+
+// AddBlock fills region with particles, spaced as they settle
+func (fluid *Fluid) AddBlock(region AABB) {
+	spacing := fluid.ParticleRadius * 2.0
+	for x := region.Min.X() + fluid.ParticleRadius; x <= region.Max.X(); x += spacing {
+		for y := region.Min.Y() + fluid.ParticleRadius; y <= region.Max.Y(); y += spacing {
+			for z := region.Min.Z() + fluid.ParticleRadius; z <= region.Max.Z(); z += spacing {
+				if !fluid.AddParticle(mgl32.Vec3{x, y, z}, mgl32.Vec3{}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Bounds returns the AABB of every particle
+func (fluid *Fluid) Bounds() AABB {
+	if len(fluid.Positions) == 0 {
+		return AABB{}
+	}
+	bounds := AABB{Min: fluid.Positions[0], Max: fluid.Positions[0]}
+	for _, p := range fluid.Positions {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	return bounds
+}
+
+func (fluid *Fluid) updateKernels() {
+	h := float64(fluid.SmoothingRadius)
+	fluid.kernelScale = float32(315.0 / (64.0 * math.Pi * math.Pow(h, 9)))
+	fluid.gradientScale = float32(-45.0 / (math.Pi * math.Pow(h, 6)))
+}
+
+// kernel is the poly6 smoothing kernel, for a neighbour distSqr away
+func (fluid *Fluid) kernel(distSqr float32) float32 {
+	h := fluid.SmoothingRadius
+	if distSqr >= h*h {
+		return 0.0
+	}
+	x := h*h - distSqr
+	return fluid.kernelScale * x * x * x
+}
+
+// kernelGradient is the gradient of the spiky kernel, for a neighbour at
+// offset d, which points back towards the neighbour
+func (fluid *Fluid) kernelGradient(d mgl32.Vec3) mgl32.Vec3 {
+	h := fluid.SmoothingRadius
+	r := d.Len()
+	if r >= h || r == 0.0 {
+		return mgl32.Vec3{}
+	}
+	x := h - r
+	return d.Mul(fluid.gradientScale * x * x / r)
+}
+
+// Update pours in particles from Sources, then moves the fluid forward,
+// colliding with the static bodies in w
+func (fluid *Fluid) Update(w *World, delta, elapsed float32) {
+	for _, source := range fluid.Sources {
+		source.pending += source.Rate * elapsed
+		for ; source.pending >= 1.0; source.pending-- {
+			offset := mgl32.Vec3{rand.Float32() - 0.5, rand.Float32() - 0.5, rand.Float32() - 0.5}
+			fluid.AddParticle(source.Position.Add(offset.Mul(source.Radius*2.0)), source.Velocity)
+		}
+	}
+
+	count := len(fluid.Positions)
+	if count == 0 || delta <= 0.0 {
+		return
+	}
+	if len(fluid.predicted) != count {
+		fluid.predicted = make([]mgl32.Vec3, count)
+		fluid.lambdas = make([]float32, count)
+		fluid.deltas = make([]mgl32.Vec3, count)
+	}
+	for len(fluid.neighbors) < count {
+		fluid.neighbors = append(fluid.neighbors, []int{})
+	}
+
+	// Anything the fluid could reach this frame, assuming it doesn't move
+	// further than its own size
+	bounds := fluid.Bounds()
+	reach := bounds.Size().Add(mgl32.Vec3{fluid.SmoothingRadius, fluid.SmoothingRadius, fluid.SmoothingRadius})
+	bodies := []*RigidBody{}
+	for _, rb := range w.particleColliders(NewAABB(bounds.Center(), reach.Mul(2.0)), fluid.CollisionMask) {
+		if rb.InverseMass() == 0.0 {
+			bodies = append(bodies, rb)
+		}
+	}
+
+	steps := fluid.Substeps
+	if steps < 1 {
+		steps = 1
+	}
+	for s := 0; s < steps; s++ {
+		fluid.step(delta/float32(steps), elapsed/float32(steps), bodies)
+	}
+}
+
+func (fluid *Fluid) step(delta, elapsed float32, bodies []*RigidBody) {
+	for i := range fluid.Positions {
+		fluid.Velocities[i] = fluid.Velocities[i].Add(fluid.Gravity.Mul(elapsed))
+		fluid.predicted[i] = fluid.Positions[i].Add(fluid.Velocities[i].Mul(delta))
+	}
+
+	// Particles don't move far while solving, so the same neighbours are
+	// used throughout
+	fluid.updateKernels()
+	fluid.hash.CellSize = fluid.SmoothingRadius
+	fluid.hash.Build(fluid.predicted)
+	for i, p := range fluid.predicted {
+		fluid.neighbors[i] = fluid.hash.Query(p, fluid.SmoothingRadius, fluid.neighbors[i][:0])
+	}
+
+	for it := 0; it < fluid.Iterations; it++ {
+		fluid.solveDensity()
+
+		for i := range fluid.predicted {
+			fluid.predicted[i], _ = collideParticle(fluid.Positions[i], fluid.predicted[i], mgl32.Vec3{},
+				fluid.ParticleRadius, 0.0, bodies)
+		}
+	}
+
+	for i := range fluid.Positions {
+		fluid.Velocities[i] = fluid.predicted[i].Sub(fluid.Positions[i]).Mul(1.0 / delta)
+	}
+	fluid.applyViscosity(elapsed)
+
+	for i := range fluid.Positions {
+		fluid.Positions[i], fluid.Velocities[i] = collideParticle(fluid.Positions[i], fluid.predicted[i], fluid.Velocities[i],
+			fluid.ParticleRadius, fluid.Friction, bodies)
+	}
+}
+
+// solveDensity moves every particle so the density around it gets back to
+// RestDensity. Only compression is corrected, so particles at the surface
+// aren't pulled into the fluid
+func (fluid *Fluid) solveDensity() {
+	scale := fluid.ParticleMass / fluid.RestDensity
+
+	for i, p := range fluid.predicted {
+		density := float32(0.0)
+		gradient := mgl32.Vec3{}
+		gradients := float32(0.0)
+		for _, j := range fluid.neighbors[i] {
+			d := p.Sub(fluid.predicted[j])
+			density += fluid.ParticleMass * fluid.kernel(d.LenSqr())
+			if j != i {
+				g := fluid.kernelGradient(d).Mul(scale)
+				gradient = gradient.Add(g)
+				gradients += g.LenSqr()
+			}
+		}
+
+		constraint := density/fluid.RestDensity - 1.0
+		if constraint < 0.0 {
+			constraint = 0.0
+		}
+		fluid.lambdas[i] = -constraint / (gradients + gradient.LenSqr() + fluid.Relaxation)
+	}
+
+	for i, p := range fluid.predicted {
+		delta := mgl32.Vec3{}
+		for _, j := range fluid.neighbors[i] {
+			if j != i {
+				g := fluid.kernelGradient(p.Sub(fluid.predicted[j]))
+				delta = delta.Add(g.Mul(fluid.lambdas[i] + fluid.lambdas[j]))
+			}
+		}
+		fluid.deltas[i] = delta.Mul(scale)
+	}
+	for i := range fluid.predicted {
+		fluid.predicted[i] = fluid.predicted[i].Add(fluid.deltas[i])
+	}
+}
+
+// applyViscosity blends each particle's velocity with its neighbours', and
+// pulls them together for surface tension
+func (fluid *Fluid) applyViscosity(elapsed float32) {
+	self := fluid.kernel(0.0)
+
+	for i, p := range fluid.predicted {
+		v := fluid.Velocities[i]
+		blend := mgl32.Vec3{}
+		pull := mgl32.Vec3{}
+		for _, j := range fluid.neighbors[i] {
+			if j == i {
+				continue
+			}
+			d := fluid.predicted[j].Sub(p)
+			weight := fluid.kernel(d.LenSqr()) / self
+			blend = blend.Add(fluid.Velocities[j].Sub(v).Mul(weight))
+			pull = pull.Add(d.Mul(weight))
+		}
+		fluid.deltas[i] = blend.Mul(fluid.Viscosity).Add(pull.Mul(fluid.SurfaceTension * elapsed))
+	}
+	for i := range fluid.Velocities {
+		fluid.Velocities[i] = fluid.Velocities[i].Add(fluid.deltas[i])
+	}
+}
+
+// Render draws Model for every particle, with the instanced shader
+func (fluid *Fluid) Render(shader *Shader) {
+	if fluid.Model == nil {
+		return
+	}
+
+	fluid.offsets = fluid.offsets[:0]
+	fluid.colors = fluid.colors[:0]
+	for _, p := range fluid.Positions {
+		fluid.offsets = append(fluid.offsets, p.Vec4(fluid.ParticleRadius))
+		fluid.colors = append(fluid.colors, fluid.Color)
+	}
+	fluid.mesh.Render(shader, fluid.Model, fluid.offsets, fluid.colors)
+}
+
+func (w *World) AddFluid(fluid *Fluid) {
+	w.Fluids = append(w.Fluids, fluid)
+}
+
+func (w *World) RemoveFluid(fluid *Fluid) {
+	for i := range w.Fluids {
+		if w.Fluids[i] == fluid {
+			w.Fluids = append(w.Fluids[:i], w.Fluids[i+1:]...)
+			return
+		}
+	}
+}
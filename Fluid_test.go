@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestFluidKernel(t *testing.T) {
+	fluid := NewFluid(0.25)
+	h := fluid.SmoothingRadius
+
+	// The smoothing kernel adds up to 1 over its volume
+	steps := 40
+	step := 2 * h / float32(steps)
+	total := float32(0.0)
+	for x := 0; x < steps; x++ {
+		for y := 0; y < steps; y++ {
+			for z := 0; z < steps; z++ {
+				d := mgl32.Vec3{float32(x), float32(y), float32(z)}.Add(mgl32.Vec3{0.5, 0.5, 0.5}).Mul(step).Sub(mgl32.Vec3{h, h, h})
+				total += fluid.kernel(d.LenSqr()) * step * step * step
+			}
+		}
+	}
+	if math.Abs(float64(total-1)) > 0.01 {
+		t.Errorf("kernel integrates to %v, want 1", total)
+	}
+
+	tests := []struct {
+		name string
+		d    mgl32.Vec3
+	}{
+		{"outside", mgl32.Vec3{h, 0, 0}},
+		{"on top", mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		if g := fluid.kernelGradient(test.d); g != (mgl32.Vec3{}) {
+			t.Errorf("%s: gradient = %v, want 0", test.name, g)
+		}
+	}
+	// The gradient points back towards the neighbour
+	if g := fluid.kernelGradient(mgl32.Vec3{h / 2, 0, 0}); g.X() >= 0 || g.Y() != 0 || g.Z() != 0 {
+		t.Errorf("gradient = %v, want it along -x", g)
+	}
+}
+
+func TestFluidAddBlock(t *testing.T) {
+	fluid := NewFluid(0.5)
+	fluid.AddBlock(NewAABB(mgl32.Vec3{}, mgl32.Vec3{2, 1, 1}))
+	// Four particles along x, and two along y and z
+	if len(fluid.Positions) != 16 {
+		t.Errorf("block has %d particles, want 16", len(fluid.Positions))
+	}
+
+	fluid.MaxParticles = 20
+	fluid.AddBlock(NewAABB(mgl32.Vec3{}, mgl32.Vec3{2, 1, 1}))
+	if len(fluid.Positions) != 20 || len(fluid.Velocities) != 20 {
+		t.Errorf("fluid has %d particles, want MaxParticles", len(fluid.Positions))
+	}
+}
+
+func TestFluidSettles(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	fluid := NewFluid(0.25)
+	fluid.AddBlock(AABB{Min: mgl32.Vec3{-1, 1, -1}, Max: mgl32.Vec3{1, 3, 1}})
+	w.AddFluid(fluid)
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	for i, p := range fluid.Positions {
+		if math.IsNaN(float64(p.Len())) || p.Y() < 0 {
+			t.Fatalf("particle %d ended up at %v", i, p)
+		}
+	}
+	if top := fluid.Bounds().Max.Y(); top > 2 {
+		t.Errorf("fluid reaches up to %v, it should have fallen and spread out", top)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ForceField applies a force to every body inside of it, every step
+type ForceField interface {
+	// Bounds returns the region the field can affect
+	Bounds() AABB
+	// Apply adds the field's force to a body over elapsed seconds
+	Apply(rb *RigidBody, elapsed float32)
+}
+
+// PointField pulls bodies towards Center, or pushes them away when Strength
+// is negative
+type PointField struct {
+	Center   mgl32.Vec3
+	Radius   float32
+	Strength float32
+	Falloff  Falloff
+	// Mode is ConstantForce to use each body's mass, or Acceleration to ignore it
+	Mode ForceMode
+}
+
+func (field *PointField) Bounds() AABB {
+	return NewAABB(field.Center, mgl32.Vec3{field.Radius, field.Radius, field.Radius})
+}
+
+func (field *PointField) Apply(rb *RigidBody, elapsed float32) {
+	toCenter := field.Center.Sub(rb.Parent.Transform.Position)
+	dist := toCenter.Len()
+	if dist == 0.0 {
+		return
+	}
+
+	force := toCenter.Mul(field.Strength / dist)
+	rb.applyForceOverTime(field.Falloff.Attenuate(force, dist, field.Radius), field.Mode, elapsed)
+}
+
+// UniformField applies the same Force everywhere inside of Region, use
+// InfiniteAABB for world-wide fields like gravity
+type UniformField struct {
+	Region AABB
+	Force  mgl32.Vec3
+	Mode   ForceMode
+}
+
+func (field *UniformField) Bounds() AABB {
+	return field.Region
+}
+
+func (field *UniformField) Apply(rb *RigidBody, elapsed float32) {
+	rb.applyForceOverTime(field.Force, field.Mode, elapsed)
+}
+
+// VortexField spins bodies around an Axis through Center with a tangential
+// force of Strength, and pulls them in towards the axis with a force of Pull
+type VortexField struct {
+	Center   mgl32.Vec3
+	Axis     mgl32.Vec3
+	Radius   float32
+	Strength float32
+	Pull     float32
+	Falloff  Falloff
+	Mode     ForceMode
+}
+
+func (field *VortexField) Bounds() AABB {
+	return NewAABB(field.Center, mgl32.Vec3{field.Radius, field.Radius, field.Radius})
+}
+
+func (field *VortexField) Apply(rb *RigidBody, elapsed float32) {
+	if field.Axis.Len() == 0.0 {
+		return
+	}
+	axis := field.Axis.Normalize()
+	offset := rb.Parent.Transform.Position.Sub(field.Center)
+	radial := offset.Sub(axis.Mul(offset.Dot(axis)))
+	if radial.Len() == 0.0 {
+		return
+	}
+	radial = radial.Normalize()
+
+	force := axis.Cross(radial).Mul(field.Strength).Sub(radial.Mul(field.Pull))
+	rb.applyForceOverTime(field.Falloff.Attenuate(force, offset.Len(), field.Radius), field.Mode, elapsed)
+}
+
+func (w *World) AddForceField(field ForceField) {
+	w.ForceFields = append(w.ForceFields, field)
+}
+
+func (w *World) RemoveForceField(field ForceField) {
+	for i := range w.ForceFields {
+		if w.ForceFields[i] == field {
+			w.ForceFields = append(w.ForceFields[:i], w.ForceFields[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *World) applyForceFields(elapsed float32) {
+	for _, field := range w.ForceFields {
+		bounds := field.Bounds()
+
+		for i := range w.Actors {
+			rb := w.Actors[i].RigidBody
+			if rb.InverseMass() == 0.0 {
+				continue
+			}
+
+			// Bodies are in a field when their position is, except for water,
+			// which holds up bodies that are only partly in it
+			inside := false
+			switch field.(type) {
+			case *WaterVolume:
+				inside = bounds.Overlaps(rb.Bounds())
+			default:
+				inside = bounds.Contains(rb.Parent.Transform.Position)
+			}
+			if !inside {
+				continue
+			}
+			field.Apply(rb, elapsed)
+		}
+	}
+}
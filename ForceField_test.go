@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestForceFieldApply(t *testing.T) {
+	up := mgl32.Vec3{0, 1, 0}
+	tests := []struct {
+		name     string
+		field    ForceField
+		position mgl32.Vec3
+		want     mgl32.Vec3
+	}{
+		{"point", &PointField{Radius: 10, Strength: 5, Mode: Acceleration}, mgl32.Vec3{3, 0, 0}, mgl32.Vec3{-5, 0, 0}},
+		{"point repels", &PointField{Radius: 10, Strength: -5, Mode: Acceleration}, mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 0, 5}},
+		{"point linear falloff", &PointField{Radius: 10, Strength: 5, Falloff: LinearFalloff, Mode: Acceleration}, mgl32.Vec3{3, 0, 0}, mgl32.Vec3{-3.5, 0, 0}},
+		{"point at center", &PointField{Radius: 10, Strength: 5, Mode: Acceleration}, mgl32.Vec3{}, mgl32.Vec3{}},
+		{"uniform", &UniformField{Region: InfiniteAABB, Force: mgl32.Vec3{1, 2, 3}, Mode: Acceleration}, mgl32.Vec3{7, 7, 7}, mgl32.Vec3{1, 2, 3}},
+		{"vortex", &VortexField{Axis: up, Radius: 10, Strength: 4, Pull: 1, Mode: Acceleration}, mgl32.Vec3{2, 5, 0}, mgl32.Vec3{-1, 0, -4}},
+		{"vortex on its axis", &VortexField{Axis: up, Radius: 10, Strength: 4, Pull: 1, Mode: Acceleration}, mgl32.Vec3{0, 5, 0}, mgl32.Vec3{}},
+		{"vortex without an axis", &VortexField{Radius: 10, Strength: 4, Pull: 1, Mode: Acceleration}, mgl32.Vec3{2, 0, 0}, mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		actor := NewActor()
+		actor.Transform.Position = test.position
+		test.field.Apply(actor.RigidBody, 1.0)
+
+		if actor.RigidBody.Velocity.Sub(test.want).Len() > 1e-4 {
+			t.Errorf("%s: velocity = %v, want %v", test.name, actor.RigidBody.Velocity, test.want)
+		}
+	}
+}
+
+func TestWorldForceFields(t *testing.T) {
+	w := NewWorld()
+	field := &UniformField{
+		Region: NewAABB(mgl32.Vec3{}, mgl32.Vec3{5, 5, 5}),
+		Force:  mgl32.Vec3{0, 10, 0},
+		Mode:   Acceleration,
+	}
+	w.AddForceField(field)
+
+	inside := NewActor()
+	w.AddActor(inside)
+	outside := NewActor()
+	outside.Transform.Position = mgl32.Vec3{10, 0, 0}
+	w.AddActor(outside)
+
+	w.applyForceFields(0.5)
+	if v := inside.RigidBody.Velocity; v != (mgl32.Vec3{0, 5, 0}) {
+		t.Errorf("body inside the field has velocity %v, want [0 5 0]", v)
+	}
+	if v := outside.RigidBody.Velocity; v != (mgl32.Vec3{}) {
+		t.Errorf("body outside the field has velocity %v", v)
+	}
+
+	w.RemoveForceField(field)
+	w.applyForceFields(0.5)
+	if len(w.ForceFields) != 0 || inside.RigidBody.Velocity != (mgl32.Vec3{0, 5, 0}) {
+		t.Error("removed field is still applied")
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	gjkMaxIterations = 32
+	// Relative tolerance for deciding GJK has stopped making progress
+	gjkTolerance = float32(1e-4)
+)
+
+// convexShape is a convex Collider placed in the world. Shapes are a core,
+// described by its support function, grown by Margin in every direction, so a
+// sphere is a point with a margin of its radius
+type convexShape struct {
+	// Support returns the point on the core furthest along direction
+	Support func(direction mgl32.Vec3) mgl32.Vec3
+	Margin  float32
+	// Center is any point inside the core
+	Center mgl32.Vec3
+}
+
+// newConvexShape creates a convexShape for a Collider at pos, turned by rot.
+// It returns false for colliders that aren't convex
+func newConvexShape(col Collider, pos mgl32.Vec3, rot mgl32.Quat) (convexShape, bool) {
+	switch col := col.(type) {
+	case SphereCollider:
+		return convexShape{
+			Support: func(direction mgl32.Vec3) mgl32.Vec3 {
+				return pos
+			},
+			Margin: col.Radius,
+			Center: pos,
+		}, true
+	case CapsuleCollider:
+		axis := rot.Rotate(mgl32.Vec3{0, col.HalfHeight, 0})
+		return convexShape{
+			Support: func(direction mgl32.Vec3) mgl32.Vec3 {
+				if direction.Dot(axis) < 0.0 {
+					return pos.Sub(axis)
+				}
+				return pos.Add(axis)
+			},
+			Margin: col.Radius,
+			Center: pos,
+		}, true
+	case BoxCollider:
+		inv := rot.Conjugate()
+		return convexShape{
+			Support: func(direction mgl32.Vec3) mgl32.Vec3 {
+				direction = inv.Rotate(direction)
+				point := col.Size
+				for i := 0; i < 3; i++ {
+					if direction[i] < 0.0 {
+						point[i] = -point[i]
+					}
+				}
+				return pos.Add(rot.Rotate(point))
+			},
+			Center: pos,
+		}, true
+	case ConvexHullCollider:
+		if len(col.Points) == 0 {
+			break
+		}
+		inv := rot.Conjugate()
+		center := mgl32.Vec3{}
+		for _, p := range col.Points {
+			center = center.Add(p)
+		}
+		center = center.Mul(1.0 / float32(len(col.Points)))
+		return convexShape{
+			Support: func(direction mgl32.Vec3) mgl32.Vec3 {
+				return pos.Add(rot.Rotate(col.support(inv.Rotate(direction))))
+			},
+			Center: pos.Add(rot.Rotate(center)),
+		}, true
+	}
+	return convexShape{}, false
+}
+
+// convexPieces splits a Collider into convex shapes, one for each child of a
+// CompoundCollider, and one for each triangle of a MeshCollider that touches
+// bounds
+func convexPieces(col Collider, pos mgl32.Vec3, rot mgl32.Quat, bounds AABB) []convexShape {
+	if compound, ok := col.(CompoundCollider); ok {
+		pieces := []convexShape{}
+		for _, child := range compound.Children {
+			pieces = append(pieces, convexPieces(child.Collider, pos.Add(rot.Rotate(child.Offset)), rot, bounds)...)
+		}
+		return pieces
+	}
+	if mesh, ok := col.(MeshCollider); ok {
+		return mesh.pieces(pos, rot, bounds)
+	}
+
+	if shape, ok := newConvexShape(col, pos, rot); ok {
+		return []convexShape{shape}
+	}
+	return nil
+}
+
+type simplexVertex struct {
+	// W = A - B, a point on the Minkowski difference
+	W mgl32.Vec3
+	A mgl32.Vec3
+	B mgl32.Vec3
+}
+
+type simplex struct {
+	Verts [4]simplexVertex
+	// Barycentric weights of the closest point to the origin
+	Bary  [4]float32
+	Count int
+}
+
+func (s *simplex) closest() mgl32.Vec3 {
+	v := mgl32.Vec3{}
+	for i := 0; i < s.Count; i++ {
+		v = v.Add(s.Verts[i].W.Mul(s.Bary[i]))
+	}
+	return v
+}
+
+func (s *simplex) witnessPoints() (mgl32.Vec3, mgl32.Vec3) {
+	a := mgl32.Vec3{}
+	b := mgl32.Vec3{}
+	for i := 0; i < s.Count; i++ {
+		a = a.Add(s.Verts[i].A.Mul(s.Bary[i]))
+		b = b.Add(s.Verts[i].B.Mul(s.Bary[i]))
+	}
+	return a, b
+}
+
+func (s *simplex) keep(indices ...int) {
+	verts := s.Verts
+	bary := s.Bary
+	for i, index := range indices {
+		s.Verts[i] = verts[index]
+		s.Bary[i] = bary[index]
+	}
+	s.Count = len(indices)
+}
+
+// solve reduces the simplex to the smallest feature containing the closest
+// point to the origin, and returns false if the origin is inside of it
+func (s *simplex) solve() bool {
+	switch s.Count {
+	case 1:
+		s.Bary[0] = 1.0
+	case 2:
+		s.solveSegment(0, 1)
+	case 3:
+		s.solveTriangle(0, 1, 2)
+	case 4:
+		return s.solveTetrahedron()
+	}
+	return true
+}
+
+func (s *simplex) solveSegment(i, j int) {
+	a := s.Verts[i].W
+	ab := s.Verts[j].W.Sub(a)
+
+	denom := ab.Dot(ab)
+	t := float32(0.0)
+	if denom > 0.0 {
+		t = -a.Dot(ab) / denom
+	}
+
+	if t <= 0.0 {
+		s.Bary[i] = 1.0
+		s.keep(i)
+	} else if t >= 1.0 {
+		s.Bary[j] = 1.0
+		s.keep(j)
+	} else {
+		s.Bary[i] = 1.0 - t
+		s.Bary[j] = t
+		s.keep(i, j)
+	}
+}
+
+// solveTriangle is the closest point on a triangle from Ericson's Real-Time
+// Collision Detection, with the query point at the origin
+func (s *simplex) solveTriangle(i, j, k int) {
+	a := s.Verts[i].W
+	b := s.Verts[j].W
+	c := s.Verts[k].W
+	ab := b.Sub(a)
+	ac := c.Sub(a)
+
+	d1 := -ab.Dot(a)
+	d2 := -ac.Dot(a)
+	if d1 <= 0.0 && d2 <= 0.0 {
+		s.Bary[i] = 1.0
+		s.keep(i)
+		return
+	}
+
+	d3 := -ab.Dot(b)
+	d4 := -ac.Dot(b)
+	if d3 >= 0.0 && d4 <= d3 {
+		s.Bary[j] = 1.0
+		s.keep(j)
+		return
+	}
+
+	vc := d1*d4 - d3*d2
+	if vc <= 0.0 && d1 >= 0.0 && d3 <= 0.0 {
+		v := d1 / (d1 - d3)
+		s.Bary[i] = 1.0 - v
+		s.Bary[j] = v
+		s.keep(i, j)
+		return
+	}
+
+	d5 := -ab.Dot(c)
+	d6 := -ac.Dot(c)
+	if d6 >= 0.0 && d5 <= d6 {
+		s.Bary[k] = 1.0
+		s.keep(k)
+		return
+	}
+
+	vb := d5*d2 - d1*d6
+	if vb <= 0.0 && d2 >= 0.0 && d6 <= 0.0 {
+		w := d2 / (d2 - d6)
+		s.Bary[i] = 1.0 - w
+		s.Bary[k] = w
+		s.keep(i, k)
+		return
+	}
+
+	va := d3*d6 - d5*d4
+	if va <= 0.0 && (d4-d3) >= 0.0 && (d5-d6) >= 0.0 {
+		w := (d4 - d3) / ((d4 - d3) + (d5 - d6))
+		s.Bary[j] = 1.0 - w
+		s.Bary[k] = w
+		s.keep(j, k)
+		return
+	}
+
+	// Degenerate triangles have no interior to be closest to
+	if va+vb+vc <= 0.0 {
+		s.solveSegment(i, j)
+		return
+	}
+
+	denom := 1.0 / (va + vb + vc)
+	v := vb * denom
+	w := vc * denom
+	s.Bary[i] = 1.0 - v - w
+	s.Bary[j] = v
+	s.Bary[k] = w
+	s.keep(i, j, k)
+}
+
+func (s *simplex) solveTetrahedron() bool {
+	faces := [4][4]int{
+		{0, 1, 2, 3},
+		{0, 2, 3, 1},
+		{0, 3, 1, 2},
+		{1, 3, 2, 0},
+	}
+
+	best := float32(math.MaxFloat32)
+	bestSimplex := simplex{}
+	outside := false
+
+	for _, face := range faces {
+		a := s.Verts[face[0]].W
+		b := s.Verts[face[1]].W
+		c := s.Verts[face[2]].W
+		d := s.Verts[face[3]].W
+
+		// The origin can only be closest to a face it's in front of
+		n := b.Sub(a).Cross(c.Sub(a))
+		signOrigin := -a.Dot(n)
+		signOther := d.Sub(a).Dot(n)
+		if signOrigin*signOther > 0.0 && float32(math.Abs(float64(signOther))) > gjkTolerance {
+			continue
+		}
+		outside = true
+
+		tri := *s
+		tri.keep(face[0], face[1], face[2])
+		tri.solveTriangle(0, 1, 2)
+		if dist := tri.closest().LenSqr(); dist < best {
+			best = dist
+			bestSimplex = tri
+		}
+	}
+
+	if !outside {
+		return false
+	}
+	*s = bestSimplex
+	return true
+}
+
+type gjkResult struct {
+	// Distance between the cores, ignoring margins
+	Distance float32
+	// Closest points on each core
+	PointA  mgl32.Vec3
+	PointB  mgl32.Vec3
+	Overlap bool
+
+	// The simplex GJK finished with, which contains the origin on overlap
+	simplex simplex
+}
+
+// gjkDistance finds the distance between the cores of two convex shapes using
+// the Gilbert-Johnson-Keerthi algorithm
+func gjkDistance(a, b convexShape) gjkResult {
+	s := simplex{}
+
+	v := a.Center.Sub(b.Center)
+	if v.LenSqr() == 0.0 {
+		v = mgl32.Vec3{1, 0, 0}
+	}
+
+	for it := 0; it < gjkMaxIterations; it++ {
+		pa := a.Support(v.Mul(-1.0))
+		pb := b.Support(v)
+		w := pa.Sub(pb)
+
+		// No closer point exists in the direction of the origin
+		if s.Count > 0 && v.LenSqr()-v.Dot(w) <= gjkTolerance*v.LenSqr() {
+			break
+		}
+
+		duplicate := false
+		for i := 0; i < s.Count; i++ {
+			if s.Verts[i].W == w {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			break
+		}
+
+		s.Verts[s.Count] = simplexVertex{W: w, A: pa, B: pb}
+		s.Count++
+
+		if !s.solve() {
+			return gjkResult{Overlap: true, simplex: s}
+		}
+
+		v = s.closest()
+		if v.LenSqr() <= gjkTolerance*gjkTolerance {
+			return gjkResult{Overlap: true, simplex: s}
+		}
+	}
+
+	pointA, pointB := s.witnessPoints()
+	return gjkResult{
+		Distance: v.Len(),
+		PointA:   pointA,
+		PointB:   pointB,
+	}
+}
+
+// shapeDistance is gjkDistance including margins. The normal points from a to
+// b, and overlap is true if the shapes are touching
+func shapeDistance(a, b convexShape) (dist float32, pointA, pointB, normal mgl32.Vec3, overlap bool) {
+	res := gjkDistance(a, b)
+	if res.Overlap {
+		return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, true
+	}
+
+	normal = res.PointB.Sub(res.PointA).Mul(1.0 / res.Distance)
+	dist = res.Distance - a.Margin - b.Margin
+	pointA = res.PointA.Add(normal.Mul(a.Margin))
+	pointB = res.PointB.Sub(normal.Mul(b.Margin))
+	return dist, pointA, pointB, normal, dist <= 0.0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestShapeDistance(t *testing.T) {
+	sphere := SphereCollider{Radius: 1}
+	box := BoxCollider{Size: mgl32.Vec3{1, 1, 1}}
+	capsule := CapsuleCollider{Radius: 0.5, HalfHeight: 1}
+	turned := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 0, 1})
+
+	tests := []struct {
+		name       string
+		colA, colB Collider
+		posB       mgl32.Vec3
+		rotB       mgl32.Quat
+		overlap    bool
+		dist       float32
+		normal     mgl32.Vec3
+	}{
+		{"separated spheres", sphere, sphere, mgl32.Vec3{5, 0, 0}, mgl32.QuatIdent(), false, 3, mgl32.Vec3{1, 0, 0}},
+		{"diagonal spheres", sphere, sphere, mgl32.Vec3{3, 4, 0}, mgl32.QuatIdent(), false, 3, mgl32.Vec3{0.6, 0.8, 0}},
+		{"overlapping spheres", sphere, sphere, mgl32.Vec3{1.5, 0, 0}, mgl32.QuatIdent(), true, 0, mgl32.Vec3{}},
+		{"sphere above box", box, sphere, mgl32.Vec3{0, 4, 0}, mgl32.QuatIdent(), false, 2, mgl32.Vec3{0, 1, 0}},
+		{"sphere off box corner", box, sphere, mgl32.Vec3{4, 5, 1}, mgl32.QuatIdent(), false, 4, mgl32.Vec3{0.6, 0.8, 0}},
+		{"box turned onto its edge", box, box, mgl32.Vec3{0, 4, 0}, turned, false, 3 - math.Sqrt2, mgl32.Vec3{0, 1, 0}},
+		{"overlapping boxes", box, box, mgl32.Vec3{1.5, 0.5, 0}, turned, true, 0, mgl32.Vec3{}},
+		{"capsule beside sphere", capsule, sphere, mgl32.Vec3{3, 0.5, 0}, mgl32.QuatIdent(), false, 1.5, mgl32.Vec3{1, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, _ := newConvexShape(test.colA, mgl32.Vec3{}, mgl32.QuatIdent())
+			b, _ := newConvexShape(test.colB, test.posB, test.rotB)
+			dist, pointA, pointB, normal, overlap := shapeDistance(a, b)
+			if overlap != test.overlap {
+				t.Fatalf("overlap = %v, want %v", overlap, test.overlap)
+			}
+			if overlap {
+				return
+			}
+			if math.Abs(float64(dist-test.dist)) > 1e-3 {
+				t.Errorf("distance = %v, want %v", dist, test.dist)
+			}
+			if normal.Sub(test.normal).Len() > 1e-3 {
+				t.Errorf("normal = %v, want %v", normal, test.normal)
+			}
+			if gap := pointB.Sub(pointA).Len(); math.Abs(float64(gap-dist)) > 1e-3 {
+				t.Errorf("closest points are %v apart, want %v", gap, dist)
+			}
+		})
+	}
+}
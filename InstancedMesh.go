@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// instancedMesh draws many copies of a Model in one call, each moved,
+// scaled and coloured differently. It is meant for the instanced shader
+type instancedMesh struct {
+	glVbos   [2]uint32
+	capacity int
+}
+
+// Cleanup frees up resources
+func (mesh *instancedMesh) Cleanup() {
+	if mesh.glVbos[0] != 0 {
+		gl.DeleteBuffers(2, &mesh.glVbos[0])
+		mesh.glVbos = [2]uint32{0, 0}
+		mesh.capacity = 0
+	}
+}
+
+// Render draws a copy of model for each offset, where W is its scale, in the
+// matching colour
+func (mesh *instancedMesh) Render(shader *Shader, model *Model, offsets, colors []mgl32.Vec4) {
+	if len(offsets) == 0 {
+		return
+	}
+	if mesh.glVbos[0] == 0 {
+		gl.GenBuffers(2, &mesh.glVbos[0])
+	}
+
+	// The attributes are added to the model's vertex array, then taken off
+	// again afterwards so it can still be drawn normally
+	gl.BindVertexArray(model.glVao)
+
+	grow := len(offsets) > mesh.capacity
+	if grow {
+		mesh.capacity = len(offsets) * 2
+	}
+	for i, data := range [][]mgl32.Vec4{offsets, colors} {
+		attrib := uint32(OFST_ATTRIB + i)
+		gl.BindBuffer(gl.ARRAY_BUFFER, mesh.glVbos[i])
+		if grow {
+			gl.BufferData(gl.ARRAY_BUFFER, mesh.capacity*4*4, nil, gl.DYNAMIC_DRAW)
+		}
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(data)*4*4, gl.Ptr(data))
+		gl.VertexAttribPointer(attrib, 4, gl.FLOAT, false, 0, gl.PtrOffset(0))
+		gl.EnableVertexAttribArray(attrib)
+		gl.VertexAttribDivisor(attrib, 1)
+	}
+
+	shader.Use()
+	model.RenderInstanced(shader, len(offsets))
+
+	gl.DisableVertexAttribArray(OFST_ATTRIB)
+	gl.DisableVertexAttribArray(COLR_ATTRIB)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// sensorPose returns where a sensor offset from actor is in world space. With
+// no actor, offset and rotation are already in world space
+func sensorPose(actor *Actor, offset mgl32.Vec3, rotation mgl32.Quat) (mgl32.Vec3, mgl32.Quat) {
+	if actor == nil {
+		return offset, rotation
+	}
+	transform := &actor.Transform
+	return transform.Position.Add(transform.Rotation.Rotate(offset)), transform.Rotation.Mul(rotation)
+}
+
+// castRange casts a ray from a sensor, and returns how far along direction it
+// hit something between minRange and maxRange, give or take noise. Nothing
+// closer than minRange is seen at all, as it is behind where the ray starts
+func castRange(w *World, origin, direction mgl32.Vec3, minRange, maxRange, noise float32, layerMask uint32, rng *rand.Rand) (float32, *RigidBody, bool) {
+	if maxRange <= minRange {
+		return 0.0, nil, false
+	}
+	start := origin.Add(direction.Mul(minRange))
+	hit, ok := w.Raycast(start, direction, maxRange-minRange, layerMask)
+	if !ok {
+		return 0.0, nil, false
+	}
+	dist := hit.Distance + minRange + randomNormal(rng, noise)
+	return float32(math.Max(float64(dist), 0.0)), hit.Body, true
+}
+
+// LidarPoint is where one ray of a LidarScan hit something
+type LidarPoint struct {
+	// Position is in the lidar's frame
+	Position mgl32.Vec3
+	Distance float32
+	// Ring is which of the vertical samples the ray was, from the bottom up
+	Ring int
+	Body *RigidBody
+}
+
+// LidarScan is the point cloud from one sweep of a LidarSensor
+type LidarScan struct {
+	Time float32
+	// Origin and Rotation are where the lidar was in world space
+	Origin   mgl32.Vec3
+	Rotation mgl32.Quat
+	// Points has a point for every ray that hit something
+	Points []LidarPoint
+}
+
+// LidarSensor sweeps fans of rays around itself, and finds where they hit the
+// colliders in the World. It looks down its -Z axis, with Y up. Bodies the
+// lidar starts inside of, like the Actor it is on, aren't seen
+type LidarSensor struct {
+	// Actor carries the lidar, at Offset and Rotation in its local space.
+	// Without an Actor, Offset and Rotation are in world space
+	Actor    *Actor
+	Offset   mgl32.Vec3
+	Rotation mgl32.Quat
+	// Rate is how many scans are taken a second
+	Rate float32
+
+	// HorizontalSamples rays are spread across HorizontalFOV radians, around
+	// the Y axis. A full circle doesn't sample the back twice
+	HorizontalFOV     float32
+	HorizontalSamples int
+	// VerticalSamples rings of rays are spread from MinElevation to
+	// MaxElevation radians, above the horizontal
+	MinElevation    float32
+	MaxElevation    float32
+	VerticalSamples int
+
+	MinRange float32
+	MaxRange float32
+	// RangeNoise is the standard deviation of the random error added to each
+	// distance
+	RangeNoise float32
+	LayerMask  uint32
+	// Rand makes the noise, the global source is used when it is nil
+	Rand *rand.Rand
+
+	// LastScan is the latest scan, which is also passed to OnScan
+	LastScan LidarScan
+	OnScan   func(scan LidarScan)
+
+	clock sensorClock
+}
+
+// NewLidarSensor creates a LidarSensor like a 16 ring spinning lidar, taking
+// 10 scans a second
+func NewLidarSensor(actor *Actor) *LidarSensor {
+	return &LidarSensor{
+		Actor:             actor,
+		Rotation:          mgl32.QuatIdent(),
+		Rate:              10.0,
+		HorizontalFOV:     2.0 * math.Pi,
+		HorizontalSamples: 360,
+		MinElevation:      mgl32.DegToRad(-15.0),
+		MaxElevation:      mgl32.DegToRad(15.0),
+		VerticalSamples:   16,
+		MinRange:          0.5,
+		MaxRange:          100.0,
+		LayerMask:         AllLayers,
+	}
+}
+
+// Scan sweeps the lidar once, where it is now
+func (lidar *LidarSensor) Scan(w *World) LidarScan {
+	origin, rotation := sensorPose(lidar.Actor, lidar.Offset, lidar.Rotation)
+	scan := LidarScan{
+		Time:     lidar.clock.time,
+		Origin:   origin,
+		Rotation: rotation,
+		Points:   []LidarPoint{},
+	}
+
+	// A full circle leaves a gap at the back the same as between the rest
+	hStep := float32(0.0)
+	if lidar.HorizontalFOV >= 2.0*math.Pi {
+		hStep = lidar.HorizontalFOV / float32(lidar.HorizontalSamples)
+	} else if lidar.HorizontalSamples > 1 {
+		hStep = lidar.HorizontalFOV / float32(lidar.HorizontalSamples-1)
+	}
+	vStep := float32(0.0)
+	if lidar.VerticalSamples > 1 {
+		vStep = (lidar.MaxElevation - lidar.MinElevation) / float32(lidar.VerticalSamples-1)
+	}
+
+	for ring := 0; ring < lidar.VerticalSamples; ring++ {
+		elevation := float64(lidar.MinElevation + vStep*float32(ring))
+		if lidar.VerticalSamples == 1 {
+			elevation = float64(lidar.MinElevation+lidar.MaxElevation) * 0.5
+		}
+		for i := 0; i < lidar.HorizontalSamples; i++ {
+			azimuth := float64(hStep*float32(i) - hStep*float32(lidar.HorizontalSamples-1)*0.5)
+			local := mgl32.Vec3{
+				float32(math.Sin(azimuth) * math.Cos(elevation)),
+				float32(math.Sin(elevation)),
+				float32(-math.Cos(azimuth) * math.Cos(elevation)),
+			}
+
+			dist, body, hit := castRange(w, origin, rotation.Rotate(local),
+				lidar.MinRange, lidar.MaxRange, lidar.RangeNoise, lidar.LayerMask, lidar.Rand)
+			if !hit {
+				continue
+			}
+			scan.Points = append(scan.Points, LidarPoint{
+				Position: local.Mul(dist),
+				Distance: dist,
+				Ring:     ring,
+				Body:     body,
+			})
+		}
+	}
+	return scan
+}
+
+func (lidar *LidarSensor) Update(w *World, delta, elapsed float32) {
+	if elapsed <= 0.0 || !lidar.clock.tick(lidar.Rate, elapsed) {
+		return
+	}
+
+	lidar.LastScan = lidar.Scan(w)
+	if lidar.OnScan != nil {
+		lidar.OnScan(lidar.LastScan)
+	}
+}
+
+// WritePCD writes the points, in the lidar's frame, as an ASCII PCD file
+func (scan LidarScan) WritePCD(writer io.Writer) error {
+	buf := bufio.NewWriter(writer)
+	fmt.Fprintf(buf, "# .PCD v0.7 - Point Cloud Data file format\n")
+	fmt.Fprintf(buf, "VERSION 0.7\n")
+	fmt.Fprintf(buf, "FIELDS x y z\n")
+	fmt.Fprintf(buf, "SIZE 4 4 4\n")
+	fmt.Fprintf(buf, "TYPE F F F\n")
+	fmt.Fprintf(buf, "COUNT 1 1 1\n")
+	fmt.Fprintf(buf, "WIDTH %v\n", len(scan.Points))
+	fmt.Fprintf(buf, "HEIGHT 1\n")
+	fmt.Fprintf(buf, "VIEWPOINT 0 0 0 1 0 0 0\n")
+	fmt.Fprintf(buf, "POINTS %v\n", len(scan.Points))
+	fmt.Fprintf(buf, "DATA ascii\n")
+	for _, point := range scan.Points {
+		fmt.Fprintf(buf, "%v %v %v\n", point.Position[0], point.Position[1], point.Position[2])
+	}
+	return buf.Flush()
+}
+
+// WritePLY writes the points, in the lidar's frame, as an ASCII PLY file
+func (scan LidarScan) WritePLY(writer io.Writer) error {
+	buf := bufio.NewWriter(writer)
+	fmt.Fprintf(buf, "ply\n")
+	fmt.Fprintf(buf, "format ascii 1.0\n")
+	fmt.Fprintf(buf, "element vertex %v\n", len(scan.Points))
+	fmt.Fprintf(buf, "property float x\n")
+	fmt.Fprintf(buf, "property float y\n")
+	fmt.Fprintf(buf, "property float z\n")
+	fmt.Fprintf(buf, "end_header\n")
+	for _, point := range scan.Points {
+		fmt.Fprintf(buf, "%v %v %v\n", point.Position[0], point.Position[1], point.Position[2])
+	}
+	return buf.Flush()
+}
+
+// SavePCD saves the points to a PCD file
+func (scan LidarScan) SavePCD(filename string) error {
+	return saveFile(filename, scan.WritePCD)
+}
+
+// SavePLY saves the points to a PLY file
+func (scan LidarScan) SavePLY(filename string) error {
+	return saveFile(filename, scan.WritePLY)
+}
+
+// saveFile creates filename and fills it with write
+func saveFile(filename string, write func(writer io.Writer) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// DepthImage is how far away things are from a DepthCamera, along the way it
+// looks, for every pixel. Pixels go row by row from the top left, and are 0
+// where there is nothing in range
+type DepthImage struct {
+	Time     float32
+	Width    int
+	Height   int
+	MaxRange float32
+	Depth    []float32
+}
+
+// At returns the depth at pixel x, y
+func (img DepthImage) At(x, y int) float32 {
+	return img.Depth[y*img.Width+x]
+}
+
+// Image converts the depths to 16 bit grayscale, with MaxRange as white. It is
+// black when MaxRange isn't positive, as nothing can be in range
+func (img DepthImage) Image() *image.Gray16 {
+	gray := image.NewGray16(image.Rect(0, 0, img.Width, img.Height))
+	if img.MaxRange <= 0.0 {
+		return gray
+	}
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			value := math.Min(float64(img.At(x, y)/img.MaxRange), 1.0) * math.MaxUint16
+			gray.SetGray16(x, y, color.Gray16{Y: uint16(value)})
+		}
+	}
+	return gray
+}
+
+// SavePNG saves the depths as a 16 bit grayscale PNG
+func (img DepthImage) SavePNG(filename string) error {
+	return saveFile(filename, func(writer io.Writer) error {
+		return png.Encode(writer, img.Image())
+	})
+}
+
+// DepthCamera casts a ray through every pixel of an image, to find how far
+// away things are like a depth sensor. It looks down its -Z axis, with Y up.
+// Bodies the camera starts inside of, like the Actor it is on, aren't seen
+type DepthCamera struct {
+	// Actor carries the camera, at Offset and Rotation in its local space.
+	// Without an Actor, Offset and Rotation are in world space
+	Actor    *Actor
+	Offset   mgl32.Vec3
+	Rotation mgl32.Quat
+	// Rate is how many images are taken a second
+	Rate float32
+
+	Width  int
+	Height int
+	// FieldOfView is the vertical field of view in radians
+	FieldOfView float32
+
+	MinRange float32
+	MaxRange float32
+	// RangeNoise is the standard deviation of the random error added to each
+	// distance
+	RangeNoise float32
+	LayerMask  uint32
+	// Rand makes the noise, the global source is used when it is nil
+	Rand *rand.Rand
+
+	// LastImage is the latest image, which is also passed to OnImage
+	LastImage DepthImage
+	OnImage   func(img DepthImage)
+
+	clock sensorClock
+}
+
+// NewDepthCamera creates a 160x120 DepthCamera, taking 10 images a second
+func NewDepthCamera(actor *Actor) *DepthCamera {
+	return &DepthCamera{
+		Actor:       actor,
+		Rotation:    mgl32.QuatIdent(),
+		Rate:        10.0,
+		Width:       160,
+		Height:      120,
+		FieldOfView: mgl32.DegToRad(60.0),
+		MinRange:    0.1,
+		MaxRange:    100.0,
+		LayerMask:   AllLayers,
+	}
+}
+
+// Capture takes an image, from where the camera is now. A camera without a
+// positive Width and Height takes an empty image
+func (camera *DepthCamera) Capture(w *World) DepthImage {
+	img := DepthImage{
+		Time:     camera.clock.time,
+		MaxRange: camera.MaxRange,
+		Depth:    []float32{},
+	}
+	if camera.Width <= 0 || camera.Height <= 0 {
+		return img
+	}
+	img.Width = camera.Width
+	img.Height = camera.Height
+	img.Depth = make([]float32, camera.Width*camera.Height)
+
+	origin, rotation := sensorPose(camera.Actor, camera.Offset, camera.Rotation)
+
+	// Pixels are spread out evenly on a plane 1 in front of the camera
+	tanHalf := float32(math.Tan(float64(camera.FieldOfView) * 0.5))
+	aspect := float32(camera.Width) / float32(camera.Height)
+	for y := 0; y < camera.Height; y++ {
+		for x := 0; x < camera.Width; x++ {
+			u := ((float32(x)+0.5)/float32(camera.Width)*2.0 - 1.0) * tanHalf * aspect
+			v := (1.0 - (float32(y)+0.5)/float32(camera.Height)*2.0) * tanHalf
+			local := mgl32.Vec3{u, v, -1}
+			length := local.Len()
+
+			// Ranges are along the ray, but depth is along the view
+			dist, _, hit := castRange(w, origin, rotation.Rotate(local.Mul(1.0/length)),
+				camera.MinRange*length, camera.MaxRange*length, camera.RangeNoise*length, camera.LayerMask, camera.Rand)
+			if hit && dist/length <= camera.MaxRange {
+				img.Depth[y*camera.Width+x] = dist / length
+			}
+		}
+	}
+	return img
+}
+
+func (camera *DepthCamera) Update(w *World, delta, elapsed float32) {
+	if elapsed <= 0.0 || !camera.clock.tick(camera.Rate, elapsed) {
+		return
+	}
+
+	camera.LastImage = camera.Capture(w)
+	if camera.OnImage != nil {
+		camera.OnImage(camera.LastImage)
+	}
+}
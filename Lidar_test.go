@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newSensorWorld builds a World with a floor, and a box 10 in front of where
+// the sensors are at (0, 2, 0) looking down -Z
+func newSensorWorld() (*World, *Actor) {
+	w := NewWorld()
+	newTestFloor(w)
+	box := NewActor()
+	box.Transform.Position = mgl32.Vec3{0, 2, -11}
+	box.RigidBody.Collider = BoxCollider{Size: mgl32.Vec3{1, 1, 1}}
+	box.RigidBody.Mass = math.MaxFloat32
+	w.AddActor(box)
+	return w, box
+}
+
+func TestLidarScan(t *testing.T) {
+	w, box := newSensorWorld()
+	lidar := NewLidarSensor(nil)
+	lidar.Offset = mgl32.Vec3{0, 2, 0}
+	scans := 0
+	lidar.OnScan = func(scan LidarScan) {
+		scans++
+	}
+	w.AddSensor(lidar)
+	for i := 0; i < 60; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	if scans != 10 {
+		t.Errorf("took %d scans in a second, want 10", scans)
+	}
+
+	scan := lidar.LastScan
+	floor, front, hidden := 0, 0, 0
+	for _, point := range scan.Points {
+		// Rings are 2 degrees apart from -15, and the ones below the
+		// horizontal hit the floor, except at -1 degree where it is out of
+		// range
+		elevation := mgl32.DegToRad(-15 + 2*float32(point.Ring))
+		if point.Body == box.RigidBody {
+			front++
+			if point.Ring < 7 {
+				hidden++
+			}
+			if point.Position.Z() > -9.99 || point.Position.Z() < -10.5 {
+				t.Errorf("box seen at %v, want its front at z = -10", point.Position)
+			}
+			continue
+		}
+		floor++
+		if want := 2 / float32(math.Sin(float64(-elevation))); math.Abs(float64(point.Distance-want)) > 1e-3 {
+			t.Errorf("ring %d hit the floor %v away, want %v", point.Ring, point.Distance, want)
+		}
+		if math.Abs(float64(point.Position.Y()+2)) > 1e-3 || math.Abs(float64(point.Position.Len()-point.Distance)) > 1e-3 {
+			t.Errorf("floor point at %v, %v away", point.Position, point.Distance)
+		}
+	}
+	// Every ray of the 7 rings that reach the floor hits it, unless the box
+	// is in the way
+	if front == 0 || floor+hidden != 7*360 {
+		t.Errorf("saw %d points on the floor and %d on the box, %d of them in front of the floor", floor, front, hidden)
+	}
+}
+
+// readHeader reads lines from scanner up to and including end, and returns
+// them
+func readHeader(scanner *bufio.Scanner, end string) []string {
+	lines := []string{}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if strings.HasPrefix(scanner.Text(), end) {
+			break
+		}
+	}
+	return lines
+}
+
+func TestLidarSave(t *testing.T) {
+	w, _ := newSensorWorld()
+	lidar := NewLidarSensor(nil)
+	lidar.Offset = mgl32.Vec3{0, 2, 0}
+	scan := lidar.Scan(w)
+
+	dir, err := ioutil.TempDir("", "lidar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		save func(filename string) error
+		end  string
+		// Header lines that have to be there
+		want []string
+	}{
+		{"scan.pcd", scan.SavePCD, "DATA", []string{
+			"VERSION 0.7",
+			"FIELDS x y z",
+			"WIDTH " + strconv.Itoa(len(scan.Points)),
+			"HEIGHT 1",
+			"POINTS " + strconv.Itoa(len(scan.Points)),
+			"DATA ascii",
+		}},
+		{"scan.ply", scan.SavePLY, "end_header", []string{
+			"ply",
+			"format ascii 1.0",
+			"element vertex " + strconv.Itoa(len(scan.Points)),
+			"property float x",
+			"end_header",
+		}},
+	}
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.name)
+		if err := test.save(filename); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		header := strings.Join(readHeader(scanner, test.end), "\n")
+		for _, line := range test.want {
+			if !strings.Contains(header, line) {
+				t.Errorf("%s: header is missing %q", test.name, line)
+			}
+		}
+
+		// A point a line, matching the scan
+		points := 0
+		for ; scanner.Scan(); points++ {
+			var p mgl32.Vec3
+			if n, err := fmt.Sscan(scanner.Text(), &p[0], &p[1], &p[2]); n != 3 || err != nil {
+				t.Fatalf("%s: malformed point %q", test.name, scanner.Text())
+			}
+			if points < len(scan.Points) && p.Sub(scan.Points[points].Position).Len() > 1e-4 {
+				t.Errorf("%s: point %d is %v, want %v", test.name, points, p, scan.Points[points].Position)
+			}
+		}
+		if points != len(scan.Points) || points == 0 {
+			t.Errorf("%s: has %d points, want %d", test.name, points, len(scan.Points))
+		}
+	}
+
+	// Saving somewhere that can't be written to fails
+	if err := scan.SavePCD(filepath.Join(dir, "missing", "scan.pcd")); err == nil {
+		t.Errorf("saved into a missing folder")
+	}
+}
+
+func TestDepthCamera(t *testing.T) {
+	w, _ := newSensorWorld()
+	camera := NewDepthCamera(nil)
+	camera.Offset = mgl32.Vec3{0, 2, 0}
+	camera.Width = 40
+	camera.Height = 30
+	camera.MaxRange = 40
+	img := camera.Capture(w)
+
+	tanHalf := math.Tan(float64(camera.FieldOfView) * 0.5)
+	for y := 0; y < img.Height; y++ {
+		v := (1 - (float64(y)+0.5)/float64(img.Height)*2) * tanHalf
+		want := float32(0)
+		if v < 0 && -2/v <= 40 {
+			// The floor is 2 below, so a ray dropping by v along the view
+			// reaches it 2/v in front
+			want = float32(-2 / v)
+		}
+		// Depth is along the view, so it is the same across a row
+		if got := img.At(0, y); math.Abs(float64(got-want)) > 1e-3*float64(want)+1e-5 {
+			t.Errorf("pixel (0, %d) is %v deep, want %v", y, got, want)
+		}
+	}
+	if got := img.At(img.Width/2, img.Height/2); math.Abs(float64(got-10)) > 1e-3 {
+		t.Errorf("center pixel is %v deep, want the box 10 in front", got)
+	}
+
+	dir, err := ioutil.TempDir("", "depth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "depth.png")
+	if err := img.SavePNG(filename); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := decoded.Bounds().Size(); size.X != img.Width || size.Y != img.Height {
+		t.Fatalf("image is %v, want %dx%d", size, img.Width, img.Height)
+	}
+	for _, p := range [][2]int{{0, 0}, {img.Width / 2, img.Height / 2}, {0, img.Height - 1}} {
+		gray, _, _, _ := decoded.At(p[0], p[1]).RGBA()
+		want := img.At(p[0], p[1]) / img.MaxRange * math.MaxUint16
+		if math.Abs(float64(gray)-float64(want)) > 1 {
+			t.Errorf("pixel %v is %v, want %v", p, gray, want)
+		}
+	}
+}
+
+func TestDepthCameraEmpty(t *testing.T) {
+	w, _ := newSensorWorld()
+	tests := []struct {
+		width, height int
+	}{
+		{0, 0},
+		{40, 0},
+		{0, 30},
+		{-40, 30},
+		{40, -30},
+	}
+	for _, test := range tests {
+		camera := NewDepthCamera(nil)
+		camera.Width = test.width
+		camera.Height = test.height
+		img := camera.Capture(w)
+		if img.Width != 0 || img.Height != 0 || len(img.Depth) != 0 {
+			t.Errorf("%dx%d: took a %dx%d image with %d depths, want an empty one", test.width, test.height, img.Width, img.Height, len(img.Depth))
+		}
+		if size := img.Image().Bounds().Size(); size.X != 0 || size.Y != 0 {
+			t.Errorf("%dx%d: image is %v, want empty", test.width, test.height, size)
+		}
+	}
+}
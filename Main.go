@@ -25,12 +25,34 @@ var inputMap = map[glfw.Key]bool{
 	glfw.Key3:      false,
 	glfw.Key4:      false,
 	glfw.Key5:      false,
+	glfw.Key6:      false,
+	glfw.Key7:      false,
+	glfw.Key8:      false,
+	glfw.Key9:      false,
+	glfw.Key0:      false,
+	glfw.KeySpace:  false,
+	glfw.KeyF1:     false,
+	glfw.KeyF3:     false,
+	glfw.KeyF4:     false,
+	glfw.KeyF5:     false,
+	glfw.KeyF6:     false,
+	glfw.KeyF7:     false,
+
+	// Held down to drive the vehicle
+	glfw.KeyW:         false,
+	glfw.KeyA:         false,
+	glfw.KeyS:         false,
+	glfw.KeyD:         false,
+	glfw.KeyLeftShift: false,
 }
 
 var windowSize = mgl32.Vec2{1024, 768}
 
 var mainShader *Shader
-var actors []*Actor
+var instancedShader *Shader
+var world *World
+var vehicle *Vehicle
+var character *CharacterController
 
 func init() {
 	runtime.LockOSThread()
@@ -76,21 +98,33 @@ func main() {
 	}
 	mainShader.Use()
 
-	actors = make([]*Actor, 0)
+	instancedShader, err = NewShader("assets/instanced.vs.glsl", "assets/instanced.fs.glsl")
+	if err != nil {
+		log.Fatalln("Failed to compile shader:", err)
+	}
+
+	world = NewWorld()
+	world.InstancedShader = instancedShader
+	defer world.Cleanup()
 
 	view := mgl32.LookAtV(mgl32.Vec3{150, 150, 150}, mgl32.Vec3{0, -50, 0}, mgl32.Vec3{0, 1, 0})
 	projection := mgl32.Perspective(mgl32.DegToRad(45.0), windowSize.X()/windowSize.Y(), 1.0, 10000.0)
 
-	gl.UniformMatrix4fv(mainShader.GetUniformLocation("u_View"), 1, false, &view[0])
-	gl.UniformMatrix4fv(mainShader.GetUniformLocation("u_Projection"), 1, false, &projection[0])
+	for _, shader := range []*Shader{mainShader, instancedShader} {
+		shader.Use()
+		gl.UniformMatrix4fv(shader.GetUniformLocation("u_View"), 1, false, &view[0])
+		gl.UniformMatrix4fv(shader.GetUniformLocation("u_Projection"), 1, false, &projection[0])
+	}
 
 	floor := NewActor()
 	floorMdl, _ := NewModelFromFile("assets/cube.obj")
 	floor.AddModel(floorMdl)
 	floor.Transform.Position = mgl32.Vec3{0, 0, 0}
 	floor.Transform.Scale = mgl32.Vec3{100, 0, 100}
+	floor.RigidBody.Collider = BoxCollider{Size: floor.Transform.Scale}
 	floor.RigidBody.Mass = math.MaxFloat32
-	actors = append(actors, floor)
+	floor.RigidBody.Restitution = 0.5
+	world.AddActor(floor)
 
 	inputState := map[glfw.Key]glfw.Action{}
 
@@ -146,48 +180,101 @@ func main() {
 		if inputMap[glfw.Key4] {
 			Test4()
 		}
+		if inputMap[glfw.Key5] {
+			Test5()
+		}
+		if inputMap[glfw.Key6] {
+			Test6()
+		}
+		if inputMap[glfw.Key7] {
+			Test7()
+		}
+		if inputMap[glfw.Key8] {
+			Test8()
+		}
+		if inputMap[glfw.Key9] {
+			Test9()
+		}
+		if inputMap[glfw.Key0] {
+			Test10()
+		}
+		if inputMap[glfw.KeyF1] {
+			Test11()
+		}
+		if inputMap[glfw.KeyF3] {
+			Test12()
+		}
+		if inputMap[glfw.KeyF4] {
+			Test13()
+		}
+		if inputMap[glfw.KeyF5] {
+			Test14()
+		}
+		if inputMap[glfw.KeyF6] {
+			Test15()
+		}
+		if inputMap[glfw.KeyF7] {
+			Test16()
+		}
 
-		if inputMap[glfw.KeyLeft] {
-			for i := 0; i < len(actors); i++ {
-				actors[i].RigidBody.ApplyForce(mgl32.Vec3{-5.0, -5.0, 0.0}, Impulse)
+		held := func(k glfw.Key) float32 {
+			if inputState[k] == glfw.Press {
+				return 1.0
 			}
+			return 0.0
 		}
-		if inputMap[glfw.KeyRight] {
-			for i := 0; i < len(actors); i++ {
-				actors[i].RigidBody.ApplyForce(mgl32.Vec3{5.0, -5.0, 0.0}, Impulse)
-			}
+		if vehicle != nil {
+			vehicle.Throttle = held(glfw.KeyW) - held(glfw.KeyS)
+			vehicle.Steering = held(glfw.KeyA) - held(glfw.KeyD)
+			vehicle.Brake = held(glfw.KeyLeftShift)
 		}
-		if inputMap[glfw.KeyUp] {
-			for i := 0; i < len(actors); i++ {
-				actors[i].RigidBody.ApplyForce(mgl32.Vec3{0.0, -5.0, -5.0}, Impulse)
+
+		if character != nil {
+			// The arrows walk the character, and space jumps
+			walk := mgl32.Vec3{held(glfw.KeyRight) - held(glfw.KeyLeft), 0, held(glfw.KeyDown) - held(glfw.KeyUp)}
+			if walk.Len() > 0.0 {
+				walk = walk.Normalize().Mul(0.5)
 			}
-		}
-		if inputMap[glfw.KeyDown] {
-			for i := 0; i < len(actors); i++ {
-				actors[i].RigidBody.ApplyForce(mgl32.Vec3{0.0, -5.0, 5.0}, Impulse)
+			character.DesiredVelocity = walk
+			if inputMap[glfw.KeySpace] {
+				character.Jump()
+			}
+		} else {
+			if inputMap[glfw.KeySpace] {
+				world.ApplyExplosionForce(mgl32.Vec3{50, 0, 50}, 75, 20, LinearFalloff, 10)
+			}
+
+			if inputMap[glfw.KeyLeft] {
+				for i := 0; i < len(world.Actors); i++ {
+					world.Actors[i].RigidBody.ApplyForce(mgl32.Vec3{-5.0, -5.0, 0.0}, Impulse)
+				}
+			}
+			if inputMap[glfw.KeyRight] {
+				for i := 0; i < len(world.Actors); i++ {
+					world.Actors[i].RigidBody.ApplyForce(mgl32.Vec3{5.0, -5.0, 0.0}, Impulse)
+				}
+			}
+			if inputMap[glfw.KeyUp] {
+				for i := 0; i < len(world.Actors); i++ {
+					world.Actors[i].RigidBody.ApplyForce(mgl32.Vec3{0.0, -5.0, -5.0}, Impulse)
+				}
+			}
+			if inputMap[glfw.KeyDown] {
+				for i := 0; i < len(world.Actors); i++ {
+					world.Actors[i].RigidBody.ApplyForce(mgl32.Vec3{0.0, -5.0, 5.0}, Impulse)
+				}
 			}
 		}
 
 		delta := float32(elapsedTime / frameDelay)
 
-		for i := 0; i < len(actors); i++ {
-			actors[i].Update(delta, float32(elapsedTime/1000.0))
-			//log.Printf("%v\n", actors[i].Transform.Position)
-
-			for j := i + 1; j < len(actors); j++ {
-				actors[i].RigidBody.CheckCollide(actors[j].RigidBody)
-			}
-
-			actors[i].Render(mainShader)
-		}
+		world.Update(delta, float32(elapsedTime/1000.0))
 
 		frameElap += elapsedTime
 		if frameDelay <= frameElap {
 			gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 
-			for i := 0; i < len(actors); i++ {
-				actors[i].Render(mainShader)
-			}
+			world.Render(mainShader)
 
 			window.SwapBuffers()
 
@@ -199,7 +286,8 @@ func main() {
 		if fpsUpdateDelay <= fpsUpdateElap {
 			currentFps = float32(float64(fpsUpdateFrames)/fpsUpdateElap) * 1000.0
 
-			title := fmt.Sprintf("Physick - %0.2f", currentFps)
+			title := fmt.Sprintf("Physick - %0.2f - %d contacts, %0.0f%% warm started",
+				currentFps, world.Stats.Contacts, world.Stats.CacheHitRate()*100.0)
 			window.SetTitle(title)
 
 			fpsUpdateElap = 0.0
@@ -231,14 +319,14 @@ func Test1() {
 		}
 		actor.Transform.Scale = mgl32.Vec3{size, size, size}
 		actor.RigidBody.Collider = SphereCollider{Radius: size}
-		actor.RigidBody.Mass = size + 2
+		actor.RigidBody.SetDensity(0.5)
 		actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
 		actor.RigidBody.ApplyForce(mgl32.Vec3{
 			(rand.Float32() - 0.5) * 10,
 			(rand.Float32() - 0.5) * 10,
 			(rand.Float32() - 0.5) * 10,
 		}, Impulse)
-		actors = append(actors, actor)
+		world.AddActor(actor)
 	}
 }
 
@@ -253,7 +341,7 @@ func Test2() {
 	actor.RigidBody.Collider = SphereCollider{Radius: size}
 	actor.RigidBody.Mass = size
 	actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
-	actors = append(actors, actor)
+	world.AddActor(actor)
 
 	actor = NewActor()
 	size = float32(3)
@@ -263,7 +351,7 @@ func Test2() {
 	actor.RigidBody.Collider = SphereCollider{Radius: size}
 	actor.RigidBody.Mass = size
 	actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
-	actors = append(actors, actor)
+	world.AddActor(actor)
 }
 
 func Test3() {
@@ -278,7 +366,7 @@ func Test3() {
 	actor.RigidBody.Mass = size
 	actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
 	actor.RigidBody.ApplyForce(mgl32.Vec3{5, 0, 0}, Impulse)
-	actors = append(actors, actor)
+	world.AddActor(actor)
 
 	actor = NewActor()
 	size = float32(3)
@@ -289,32 +377,648 @@ func Test3() {
 	actor.RigidBody.Mass = size
 	actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
 	actor.RigidBody.ApplyForce(mgl32.Vec3{-5, 0, 0}, Impulse)
-	actors = append(actors, actor)
+	world.AddActor(actor)
 }
 
 func Test4() {
 	model, _ := NewModelFromFile("assets/sphere.obj")
 
-	for i := 0; i < 100; i++ {
+	// A burst of sparks, bouncing off the floor and a ball
+	sparks := NewParticleSystem(1000)
+	sparks.Model = model
+	sparks.Planes = append(sparks.Planes, PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}, Offset: 0})
+	sparks.Spheres = append(sparks.Spheres, ParticleSphere{Center: mgl32.Vec3{50, 0, 50}, Radius: 10})
+
+	burst := NewParticleEmitter(mgl32.Vec3{
+		rand.Float32() * 100,
+		(rand.Float32() * 50) + 50,
+		rand.Float32() * 100,
+	}, mgl32.Vec3{0, 1, 0})
+	burst.ConeAngle = math.Pi
+	burst.MinLifetime = 3.0
+	burst.MaxLifetime = 5.0
+	sparks.Burst(burst, 100)
+
+	// And a fountain that keeps going
+	fountain := NewParticleEmitter(mgl32.Vec3{50, 10, 50}, mgl32.Vec3{0, 1, 0})
+	fountain.StartColor = mgl32.Vec4{0.6, 0.8, 1.0, 1.0}
+	fountain.EndColor = mgl32.Vec4{0.1, 0.2, 0.8, 1.0}
+	fountain.Rate = 200
+	sparks.AddEmitter(fountain)
+
+	world.AddParticleSystem(sparks)
+}
+
+func Test5() {
+	model, _ := NewModelFromFile("assets/sphere.obj")
+
+	world.NBodyGravity = true
+
+	center := mgl32.Vec3{50, 50, 50}
+	coreMass := float32(500)
+
+	core := NewActor()
+	core.AddModel(model)
+	core.Transform.Position = center
+	core.Transform.Scale = mgl32.Vec3{3, 3, 3}
+	core.RigidBody.Mass = coreMass
+	world.AddActor(core)
+
+	// A disk of stars on circular orbits around the core, without colliders so
+	// they can pass through each other
+	for i := 0; i < 2000; i++ {
 		actor := NewActor()
-		size := float32(1.0)
+		size := float32(0.3)
 		actor.AddModel(model)
+
+		angle := rand.Float64() * 2.0 * math.Pi
+		dist := (rand.Float32() * 40) + 8.0
+		dir := mgl32.Vec3{float32(math.Cos(angle)), 0, float32(math.Sin(angle))}
+		actor.Transform.Position = center.Add(dir.Mul(dist)).Add(mgl32.Vec3{0, (rand.Float32() - 0.5) * 2, 0})
+		actor.Transform.Scale = mgl32.Vec3{size, size, size}
+		actor.RigidBody.Mass = 0.1
+
+		// Velocities are per frame, and accelerations per second
+		speed := float32(math.Sqrt(float64(world.GravitationalConstant * coreMass / (dist * 60.0))))
+		actor.RigidBody.ApplyForce(mgl32.Vec3{0, 1, 0}.Cross(dir).Mul(speed), VelocityChange)
+		world.AddActor(actor)
+	}
+}
+
+func Test6() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	pool := NewWaterVolume(AABB{Min: mgl32.Vec3{0, 0, 0}, Max: mgl32.Vec3{100, 40, 100}}, 1.0)
+	world.AddForceField(pool)
+
+	// Debris of different densities, the lighter pieces float higher and the
+	// heaviest sink
+	for i := 0; i < 30; i++ {
+		actor := NewActor()
+		size := (rand.Float32() * 3) + 1.0
 		actor.Transform.Position = mgl32.Vec3{
 			rand.Float32() * 100,
-			rand.Float32() * 100,
+			(rand.Float32() * 40) + 50,
 			rand.Float32() * 100,
 		}
 		actor.Transform.Scale = mgl32.Vec3{size, size, size}
-		actor.RigidBody.Collider = SphereCollider{Radius: size}
-		actor.RigidBody.Mass = size + 1
+		if i%2 == 0 {
+			actor.AddModel(sphere)
+			actor.RigidBody.Collider = SphereCollider{Radius: size}
+		} else {
+			actor.AddModel(cube)
+			actor.RigidBody.Collider = BoxCollider{Size: actor.Transform.Scale}
+		}
+		actor.RigidBody.SetDensity((rand.Float32() * 1.2) + 0.2)
+		actor.RigidBody.Restitution = 0.2
+		actor.RigidBody.ApplyForce(pool.Gravity, Acceleration)
+		world.AddActor(actor)
+	}
+}
+
+func Test7() {
+	model, _ := NewModelFromFile("assets/sphere.obj")
+
+	// A flag pinned along one edge, blowing in the wind
+	flag, _ := NewCloth(mgl32.Vec3{20, 80, 50}, mgl32.Vec3{30, 0, 0}, mgl32.Vec3{0, -20, 0}, 30, 20)
+	for y := 0; y < flag.Height; y++ {
+		flag.Pin(0, y)
+	}
+	flag.Wind = mgl32.Vec3{3, 0, 1}
+	world.AddCloth(flag)
+
+	// A sheet dropped over a ball
+	ball := NewActor()
+	ball.AddModel(model)
+	ball.Transform.Position = mgl32.Vec3{70, 10, 70}
+	ball.Transform.Scale = mgl32.Vec3{10, 10, 10}
+	ball.RigidBody.Collider = SphereCollider{Radius: 10}
+	ball.RigidBody.Mass = math.MaxFloat32
+	world.AddActor(ball)
+
+	sheet, _ := NewCloth(mgl32.Vec3{55, 40, 55}, mgl32.Vec3{30, 0, 0}, mgl32.Vec3{0, 0, 30}, 30, 30)
+	world.AddCloth(sheet)
+}
+
+func Test8() {
+	model, _ := NewModelFromFile("assets/sphere.obj")
+
+	// Jelly balls of increasing stiffness, dropped onto the floor
+	for i := 0; i < 5; i++ {
+		transform := NewTransform()
+		transform.Position = mgl32.Vec3{float32(i)*20 + 10, 40, 50}
+		transform.Scale = mgl32.Vec3{6, 6, 6}
+
+		body, err := NewSoftBody(model, transform, 10)
+		if err != nil {
+			log.Println("Failed to create soft body:", err)
+			return
+		}
+		body.Stiffness = float32(i+1) * 0.2
+		world.AddSoftBody(body)
+	}
+}
+
+func Test9() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// A pendulum, a ball swinging on a chain from a fixed point
+	bob := NewActor()
+	bob.AddModel(sphere)
+	bob.Transform.Position = mgl32.Vec3{40, 60, 20}
+	bob.Transform.Scale = mgl32.Vec3{3, 3, 3}
+	bob.RigidBody.Collider = SphereCollider{Radius: 3}
+	bob.RigidBody.SetDensity(0.5)
+	bob.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	world.AddActor(bob)
+
+	chain, _ := NewRope(mgl32.Vec3{20, 60, 20}, mgl32.Vec3{37, 60, 20}, 15)
+	chain.Start = NewRopeAnchor(nil, mgl32.Vec3{20, 60, 20})
+	chain.End = NewRopeAnchor(bob.RigidBody, mgl32.Vec3{37, 60, 20})
+	world.AddRope(chain)
+
+	// A crane, a crate hanging from the end of a beam
+	beam := NewActor()
+	beam.AddModel(cube)
+	beam.Transform.Position = mgl32.Vec3{70, 60, 20}
+	beam.Transform.Scale = mgl32.Vec3{15, 1, 1}
+	beam.RigidBody.Collider = BoxCollider{Size: beam.Transform.Scale}
+	beam.RigidBody.Mass = math.MaxFloat32
+	world.AddActor(beam)
+
+	crate := NewActor()
+	crate.AddModel(cube)
+	crate.Transform.Position = mgl32.Vec3{82, 35, 20}
+	crate.Transform.Scale = mgl32.Vec3{3, 3, 3}
+	crate.RigidBody.Collider = BoxCollider{Size: crate.Transform.Scale}
+	crate.RigidBody.SetDensity(0.2)
+	crate.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	crate.RigidBody.ApplyForce(mgl32.Vec3{5, 0, 0}, VelocityChange)
+	world.AddActor(crate)
+
+	cable, _ := NewRope(mgl32.Vec3{82, 59, 20}, mgl32.Vec3{82, 38, 20}, 20)
+	cable.Start = NewRopeAnchor(beam.RigidBody, mgl32.Vec3{82, 59, 20})
+	cable.End = NewRopeAnchor(crate.RigidBody, mgl32.Vec3{82, 38, 20})
+	world.AddRope(cable)
+
+	// A bridge of planks, tied to each other and to two posts at either end
+	const planks = 8
+	var previous *RigidBody
+	for i := 0; i <= planks; i++ {
+		x := 20 + float32(i)*8
+		var body *RigidBody
+		if i < planks {
+			plank := NewActor()
+			plank.AddModel(cube)
+			plank.Transform.Position = mgl32.Vec3{x + 4, 30, 70}
+			plank.Transform.Scale = mgl32.Vec3{3, 0.5, 5}
+			plank.RigidBody.Collider = BoxCollider{Size: plank.Transform.Scale}
+			plank.RigidBody.SetDensity(0.5)
+			plank.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+			world.AddActor(plank)
+			body = plank.RigidBody
+		}
+
+		for _, z := range []float32{66, 74} {
+			start := mgl32.Vec3{x - 1, 30, z}
+			end := mgl32.Vec3{x + 1, 30, z}
+			rope, _ := NewRope(start, end, 2)
+			rope.Thickness = 0.2
+			rope.Start = NewRopeAnchor(previous, start)
+			rope.End = NewRopeAnchor(body, end)
+			world.AddRope(rope)
+		}
+		previous = body
+	}
+}
+
+func Test10() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// A tank with water pouring into it
+	walls := []struct{ position, size mgl32.Vec3 }{
+		{mgl32.Vec3{29, 8, 40}, mgl32.Vec3{1, 8, 11}},
+		{mgl32.Vec3{51, 8, 40}, mgl32.Vec3{1, 8, 11}},
+		{mgl32.Vec3{40, 8, 29}, mgl32.Vec3{11, 8, 1}},
+		{mgl32.Vec3{40, 8, 51}, mgl32.Vec3{11, 8, 1}},
+	}
+	for _, wall := range walls {
+		actor := NewActor()
+		actor.AddModel(cube)
+		actor.Transform.Position = wall.position
+		actor.Transform.Scale = wall.size
+		actor.RigidBody.Collider = BoxCollider{Size: wall.size}
+		actor.RigidBody.Mass = math.MaxFloat32
+		world.AddActor(actor)
+	}
+
+	fluid := NewFluid(0.5)
+	fluid.MaxParticles = 1500
+	fluid.Model = sphere
+	fluid.AddBlock(AABB{Min: mgl32.Vec3{30, 0, 30}, Max: mgl32.Vec3{50, 2, 50}})
+	fluid.Sources = append(fluid.Sources, &FluidSource{
+		Position: mgl32.Vec3{40, 30, 40},
+		Velocity: mgl32.Vec3{0.3, 0, 0},
+		Rate:     120,
+		Radius:   0.5,
+	})
+	world.AddFluid(fluid)
+
+	// A stack of boxes next to it
+	for i := 0; i < 4; i++ {
+		actor := NewActor()
+		actor.AddModel(cube)
+		actor.Transform.Position = mgl32.Vec3{65, float32(i)*4 + 2, 40}
+		actor.Transform.Scale = mgl32.Vec3{2, 2, 2}
+		actor.RigidBody.Collider = BoxCollider{Size: actor.Transform.Scale}
+		actor.RigidBody.SetDensity(0.5)
 		actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
-		actor.RigidBody.ApplyForce(mgl32.Vec3{
-			(rand.Float32() - 0.5) * 10,
-			(rand.Float32() - 0.5) * 10,
-			(rand.Float32() - 0.5) * 10,
-		}, Impulse)
-		actors = append(actors, actor)
+		world.AddActor(actor)
+	}
+}
+
+func Test11() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// A hill with bumps on it, that only a mesh can describe
+	const cells = 16
+	vertices := []mgl32.Vec3{}
+	triangles := [][3]int{}
+	for i := 0; i <= cells; i++ {
+		for j := 0; j <= cells; j++ {
+			u := float32(i) / cells
+			v := float32(j) / cells
+			height := 5.0*float32(math.Sin(math.Pi*float64(u))*math.Sin(math.Pi*float64(v))) +
+				0.5*float32(math.Sin(float64(u)*40.0)*math.Cos(float64(v)*30.0))
+			vertices = append(vertices, mgl32.Vec3{u*60 - 80, height, v*80 - 40})
+			if i < cells && j < cells {
+				a := i*(cells+1) + j
+				b := a + cells + 1
+				triangles = append(triangles, [3]int{a, a + 1, b}, [3]int{b, a + 1, b + 1})
+			}
+		}
+	}
+	ground, err := newMeshActor(vertices, triangles)
+	if err != nil {
+		log.Println("Failed to create hill:", err)
+		return
+	}
+	world.AddActor(ground)
+
+	// A car driven with WASD, and braked with shift
+	chassis := NewActor()
+	chassis.AddModel(cube)
+	chassis.Transform.Position = mgl32.Vec3{0, 5, 0}
+	chassis.Transform.Scale = mgl32.Vec3{2, 1, 4}
+	chassis.RigidBody.Collider = BoxCollider{Size: chassis.Transform.Scale}
+	chassis.RigidBody.SetDensity(0.2)
+	chassis.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	world.AddActor(chassis)
+
+	vehicle = NewVehicle(chassis.RigidBody)
+	vehicle.EngineTorque = chassis.RigidBody.Mass * 4.0
+	vehicle.BrakeTorque = chassis.RigidBody.Mass * 4.0
+	vehicle.WheelModel = sphere
+	for _, x := range []float32{-2.2, 2.2} {
+		vehicle.AddWheel(mgl32.Vec3{x, -1, 3}, 1.2, true, true)
+		vehicle.AddWheel(mgl32.Vec3{x, -1, -3}, 1.2, false, true)
+	}
+	world.AddVehicle(vehicle)
+}
+
+func Test12() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// Stairs, each step short enough to walk up
+	for i := 0; i < 5; i++ {
+		step := NewActor()
+		step.AddModel(cube)
+		step.Transform.Scale = mgl32.Vec3{20 - float32(i)*2, float32(i+1) * 0.25, 6}
+		step.Transform.Position = mgl32.Vec3{40 + float32(i)*2, step.Transform.Scale.Y(), -10}
+		step.RigidBody.Collider = BoxCollider{Size: step.Transform.Scale}
+		step.RigidBody.Mass = math.MaxFloat32
+		world.AddActor(step)
+	}
+
+	// A ramp that can be walked up, and one that is too steep
+	for i, angle := range []float64{25, 60} {
+		x0, x1 := float32(20), float32(40)
+		z0, z1 := float32(-35-i*15), float32(-25-i*15)
+		h := (x1 - x0) * float32(math.Tan(float64(mgl32.DegToRad(float32(angle)))))
+		// Every face has its own corners, so they don't share normals
+		vertices := []mgl32.Vec3{
+			{x0, 0, z0}, {x0, 0, z1}, {x1, h, z0}, {x1, h, z1},
+			{x1, 0, z0}, {x1, h, z0}, {x1, h, z1}, {x1, 0, z1},
+			{x0, 0, z0}, {x1, h, z0}, {x1, 0, z0},
+			{x0, 0, z1}, {x1, 0, z1}, {x1, h, z1},
+		}
+		triangles := [][3]int{{0, 1, 2}, {2, 1, 3}, {4, 5, 6}, {4, 6, 7}, {8, 9, 10}, {11, 12, 13}}
+		ramp, err := newMeshActor(vertices, triangles)
+		if err != nil {
+			log.Println("Failed to create ramp:", err)
+			return
+		}
+		world.AddActor(ramp)
+	}
+
+	// Some boxes to push around
+	for i := 0; i < 3; i++ {
+		actor := NewActor()
+		actor.AddModel(cube)
+		actor.Transform.Position = mgl32.Vec3{10, 1.5, float32(i)*5 - 5}
+		actor.Transform.Scale = mgl32.Vec3{1.5, 1.5, 1.5}
+		actor.RigidBody.Collider = BoxCollider{Size: actor.Transform.Scale}
+		actor.RigidBody.SetDensity(0.5)
+		actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+		world.AddActor(actor)
+	}
+
+	// A player walked with the arrows, and jumping with space
+	player := NewActor()
+	player.AddModel(sphere)
+	player.Transform.Position = mgl32.Vec3{0, 5, -10}
+	player.Transform.Scale = mgl32.Vec3{1, 2, 1}
+	player.RigidBody.Collider = CapsuleCollider{Radius: 1, HalfHeight: 1}
+	world.AddActor(player)
+
+	character = NewCharacterController(player.RigidBody)
+	character.StepHeight = 0.75
+	character.JumpSpeed = 1.5
+	world.AddCharacter(character)
+}
+
+func Test13() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	bead := func(radius float32) *Actor {
+		actor := NewActor()
+		actor.AddModel(sphere)
+		actor.Transform.Scale = mgl32.Vec3{radius, radius, radius}
+		actor.RigidBody.Collider = SphereCollider{Radius: radius}
+		actor.RigidBody.SetDensity(0.5)
+		world.AddActor(actor)
+		return actor
+	}
+
+	// A chain hanging from a post, swinging both ways
+	post := NewActor()
+	post.AddModel(cube)
+	post.Transform.Position = mgl32.Vec3{-30, 40, 0}
+	post.RigidBody.Collider = BoxCollider{Size: post.Transform.Scale}
+	world.AddActor(post)
+
+	chain := NewArticulation(post, true)
+	link := chain.Root()
+	for i := 0; i < 6; i++ {
+		axis := mgl32.Vec3{0, 0, 1}
+		if i%2 == 1 {
+			axis = mgl32.Vec3{1, 0, 0}
+		}
+		link = chain.AddLink(link, bead(1.5), RevoluteJoint, mgl32.Vec3{0, -4, 0}, axis)
+		link.Damping = 0.5
+	}
+	chain.Links[1].Position = 1.2
+	chain.Links[2].Position = 0.5
+	world.AddArticulation(chain)
+
+	// An arm on a lift, driven to hold a pose
+	base := NewActor()
+	base.AddModel(cube)
+	base.Transform.Position = mgl32.Vec3{20, 2, 0}
+	base.Transform.Scale = mgl32.Vec3{3, 2, 3}
+	base.RigidBody.Collider = BoxCollider{Size: base.Transform.Scale}
+	world.AddActor(base)
+
+	arm := NewArticulation(base, true)
+	lift := arm.AddLink(arm.Root(), bead(1.5), PrismaticJoint, mgl32.Vec3{0, 4, 0}, mgl32.Vec3{0, 1, 0})
+	lift.Limited = true
+	lift.Lower = 0
+	lift.Upper = 15
+	lift.Target = 10
+	lift.DriveStiffness = 1500
+	lift.DriveDamping = 600
+	link = lift
+	for i, target := range []float32{0.8, -1.2, 0.6} {
+		link = arm.AddLink(link, bead(1.5-float32(i)*0.25), RevoluteJoint, mgl32.Vec3{0, 4, 0}, mgl32.Vec3{0, 0, 1})
+		link.Target = target
+		link.DriveStiffness = 1000
+		link.DriveDamping = 400
+	}
+	arm.AddLink(link, bead(0.75), FixedJoint, mgl32.Vec3{0, 3, 0}, mgl32.Vec3{})
+	arm.Substeps = 4
+	world.AddArticulation(arm)
+
+	// A limp body dropped on the floor, with joints that only bend so far
+	body := NewActor()
+	body.AddModel(cube)
+	body.Transform.Position = mgl32.Vec3{0, 5, 30}
+	body.Transform.Scale = mgl32.Vec3{2, 2, 2}
+	body.RigidBody.Collider = BoxCollider{Size: body.Transform.Scale}
+	body.RigidBody.SetDensity(0.5)
+	world.AddActor(body)
+
+	ragdoll := NewArticulation(body, false)
+	for _, side := range []float32{-1, 1} {
+		link := ragdoll.Root()
+		for i := 0; i < 3; i++ {
+			offset := mgl32.Vec3{side * 3.5, 0, 0}
+			if i == 0 {
+				offset = mgl32.Vec3{side * 4, 1, 0}
+			}
+			link = ragdoll.AddLink(link, bead(1.25), RevoluteJoint, offset, mgl32.Vec3{0, 0, 1})
+			link.Limited = true
+			link.Lower = -0.75
+			link.Upper = 0.75
+			link.Damping = 2
+		}
+	}
+	world.AddArticulation(ragdoll)
+}
+
+func Test14() {
+	// A robot arm bolted down, driven to hold a pose
+	arm, err := LoadURDF("assets/arm.urdf", mgl32.Vec3{0, 0, 0}, true)
+	if err != nil {
+		log.Println("Failed to load robot:", err)
+		return
+	}
+	for name, target := range map[string]float32{"pan": 1, "shoulder": 0.6, "elbow": 1.2} {
+		joint := arm.Joints[name]
+		joint.Target = target
+		joint.DriveStiffness = 4000
+		joint.DriveDamping = 1500
+	}
+	world.AddRobot(arm)
+
+	// The same arm with nothing driving it, flopping down onto the floor
+	limp, err := LoadURDF("assets/arm.urdf", mgl32.Vec3{15, 0, 0}, true)
+	if err != nil {
+		log.Println("Failed to load robot:", err)
+		return
+	}
+	limp.Joints["shoulder"].Position = 0.3
+	world.AddRobot(limp)
+}
+
+func Test15() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// A spinning box with an IMU and a contact sensor, printing once a second
+	box := NewActor()
+	box.AddModel(cube)
+	box.Transform.Position = mgl32.Vec3{0, 10, 0}
+	box.Transform.Scale = mgl32.Vec3{1.5, 1.5, 1.5}
+	box.RigidBody.Collider = BoxCollider{Size: box.Transform.Scale}
+	box.RigidBody.SetDensity(0.5)
+	box.RigidBody.AngularVelocity = mgl32.Vec3{0, 0.05, 0}
+	box.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	world.AddActor(box)
+
+	imu := NewIMUSensor(box)
+	imu.Rate = 1
+	imu.AccelerationNoise = 0.05
+	imu.AccelerationBias = mgl32.Vec3{0.1, 0, 0}
+	imu.AngularVelocityNoise = 0.01
+	imu.OnReading = func(reading IMUReading) {
+		log.Printf("IMU %.0fs: acceleration %.2f, angular velocity %.2f", reading.Time, reading.Acceleration, reading.AngularVelocity)
+	}
+	world.AddSensor(imu)
+
+	contact := NewContactSensor(box)
+	contact.Rate = 1
+	contact.OnReading = func(reading ContactReading) {
+		log.Printf("Contact %.0fs: touching %v bodies, force %.2f", reading.Time, len(reading.Touching), reading.Force)
+	}
+	world.AddSensor(contact)
+
+	// A weight held out from a driven joint, with a force/torque sensor
+	post := NewActor()
+	post.AddModel(cube)
+	post.Transform.Position = mgl32.Vec3{-20, 20, 0}
+	post.RigidBody.Collider = BoxCollider{Size: post.Transform.Scale}
+	world.AddActor(post)
+
+	weight := NewActor()
+	weight.AddModelAt(sphere, mgl32.Translate3D(5, 0, 0))
+	weight.RigidBody.Collider = CompoundCollider{Children: []ChildCollider{
+		{Offset: mgl32.Vec3{5, 0, 0}, Collider: SphereCollider{Radius: 1}},
+	}}
+	weight.RigidBody.SetDensity(0.5)
+	world.AddActor(weight)
+
+	arm := NewArticulation(post, true)
+	joint := arm.AddLink(arm.Root(), weight, RevoluteJoint, mgl32.Vec3{}, mgl32.Vec3{0, 0, 1})
+	joint.DriveStiffness = 2000
+	joint.DriveDamping = 800
+	world.AddArticulation(arm)
+
+	forceTorque := NewForceTorqueSensor(joint)
+	forceTorque.Rate = 1
+	forceTorque.OnReading = func(reading ForceTorqueReading) {
+		log.Printf("Joint %.0fs: force %.2f, torque %.2f", reading.Time, reading.Force, reading.Torque)
+	}
+	world.AddSensor(forceTorque)
+}
+
+func Test16() {
+	sphere, _ := NewModelFromFile("assets/sphere.obj")
+	cube, _ := NewModelFromFile("assets/cube.obj")
+
+	// Boxes and balls scattered around a post, falling into place
+	for i := 0; i < 12; i++ {
+		angle := float64(i) * math.Pi / 6.0
+		actor := NewActor()
+		actor.Transform.Position = mgl32.Vec3{
+			float32(math.Cos(angle)) * (10 + float32(i%3)*5),
+			3 + float32(i%4),
+			float32(math.Sin(angle)) * (10 + float32(i%3)*5),
+		}
+		if i%2 == 0 {
+			actor.AddModel(cube)
+			actor.Transform.Scale = mgl32.Vec3{1.5, 1.5, 1.5}
+			actor.RigidBody.Collider = BoxCollider{Size: actor.Transform.Scale}
+		} else {
+			actor.AddModel(sphere)
+			actor.Transform.Scale = mgl32.Vec3{1.5, 1.5, 1.5}
+			actor.RigidBody.Collider = SphereCollider{Radius: 1.5}
+		}
+		actor.RigidBody.SetDensity(0.5)
+		actor.RigidBody.Restitution = 0.2
+		actor.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+		world.AddActor(actor)
+	}
+
+	// A lidar and a depth camera on top of the post, the first scan and image
+	// are saved once everything has settled
+	post := NewActor()
+	post.AddModel(cube)
+	post.Transform.Position = mgl32.Vec3{0, 3, 0}
+	post.Transform.Scale = mgl32.Vec3{0.5, 3, 0.5}
+	post.RigidBody.Collider = BoxCollider{Size: post.Transform.Scale}
+	post.RigidBody.Mass = math.MaxFloat32
+	world.AddActor(post)
+
+	lidar := NewLidarSensor(post)
+	lidar.Offset = mgl32.Vec3{0, 4, 0}
+	lidar.Rate = 0.5
+	lidar.RangeNoise = 0.02
+	lidar.OnScan = func(scan LidarScan) {
+		log.Printf("Lidar %.0fs: %v points", scan.Time, len(scan.Points))
+		if err := scan.SavePCD("scan.pcd"); err != nil {
+			log.Println("Failed to save scan:", err)
+		}
+		if err := scan.SavePLY("scan.ply"); err != nil {
+			log.Println("Failed to save scan:", err)
+		}
+		lidar.OnScan = nil
+	}
+	world.AddSensor(lidar)
+
+	camera := NewDepthCamera(post)
+	camera.Offset = mgl32.Vec3{0, 4, 0}
+	camera.Rotation = mgl32.QuatRotate(mgl32.DegToRad(-20), mgl32.Vec3{1, 0, 0})
+	camera.Rate = 0.5
+	camera.MaxRange = 40
+	camera.OnImage = func(img DepthImage) {
+		if err := img.SavePNG("depth.png"); err != nil {
+			log.Println("Failed to save depth image:", err)
+		}
+		camera.OnImage = nil
+	}
+	world.AddSensor(camera)
+}
+
+// newMeshActor creates an immovable Actor with a MeshCollider, drawn with the
+// same triangles
+func newMeshActor(vertices []mgl32.Vec3, triangles [][3]int) (*Actor, error) {
+	mesh, err := NewMeshCollider(vertices, triangles)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := NewActor()
+	if model, err := NewDynamicModel(len(triangles) * 3); err == nil {
+		normals := smoothNormals(vertices, triangles)
+		verts, norms := []float32{}, []float32{}
+		for _, tri := range triangles {
+			for _, i := range tri {
+				verts = append(verts, vertices[i][0], vertices[i][1], vertices[i][2])
+				norms = append(norms, normals[i][0], normals[i][1], normals[i][2])
+			}
+		}
+		model.UpdateVertices(verts, norms)
+		actor.AddModel(model)
 	}
+	actor.RigidBody.Collider = mesh
+	actor.RigidBody.Mass = math.MaxFloat32
+	return actor, nil
 }
 
 func DistanceSquared(p1, p2 mgl32.Vec3) float32 {
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MassProperties describes how the mass of a Collider is spread through it
+type MassProperties struct {
+	Mass   float32
+	Volume float32
+	// CenterOfMass is in the Collider's local space
+	CenterOfMass mgl32.Vec3
+	// Inertia is the inertia tensor about the center of mass
+	Inertia mgl32.Mat3
+}
+
+// ComputeMassProperties finds the mass, center of mass and inertia tensor of a
+// Collider made of a material with the given density
+func ComputeMassProperties(col Collider, density float32) MassProperties {
+	switch col := col.(type) {
+	case SphereCollider:
+		r := col.Radius
+		volume := (4.0 / 3.0) * math.Pi * r * r * r
+		mass := volume * density
+		i := 0.4 * mass * r * r
+		return MassProperties{
+			Mass:    mass,
+			Volume:  volume,
+			Inertia: mgl32.Diag3(mgl32.Vec3{i, i, i}),
+		}
+
+	case CapsuleCollider:
+		// A cylinder with half a sphere on each end
+		r, h := col.Radius, col.HalfHeight
+		cylinder := math.Pi * r * r * 2.0 * h
+		sphere := (4.0 / 3.0) * math.Pi * r * r * r
+		mc := cylinder * density
+		ms := sphere * density
+		axial := mc*r*r/2.0 + ms*0.4*r*r
+		// The hemispheres' centers of mass sit 3r/8 beyond the ends of the
+		// cylinder
+		across := mc*(h*h/3.0+r*r/4.0) + ms*(0.4*r*r+h*h+0.75*h*r)
+		return MassProperties{
+			Mass:    mc + ms,
+			Volume:  cylinder + sphere,
+			Inertia: mgl32.Diag3(mgl32.Vec3{across, axial, across}),
+		}
+
+	case BoxCollider:
+		x, y, z := col.Size.Elem()
+		volume := 8.0 * x * y * z
+		mass := volume * density
+		return MassProperties{
+			Mass:   mass,
+			Volume: volume,
+			Inertia: mgl32.Diag3(mgl32.Vec3{
+				mass / 3.0 * (y*y + z*z),
+				mass / 3.0 * (x*x + z*z),
+				mass / 3.0 * (x*x + y*y),
+			}),
+		}
+
+	case ConvexHullCollider:
+		return polyhedronMassProperties(col.Points, col.Faces, density)
+
+	case CompoundCollider:
+		return compoundMassProperties(col, density)
+	}
+
+	return MassProperties{}
+}
+
+func compoundMassProperties(compound CompoundCollider, density float32) MassProperties {
+	total := MassProperties{}
+
+	children := make([]MassProperties, len(compound.Children))
+	for i, child := range compound.Children {
+		children[i] = ComputeMassProperties(child.Collider, density)
+		children[i].CenterOfMass = children[i].CenterOfMass.Add(child.Offset)
+
+		total.Mass += children[i].Mass
+		total.Volume += children[i].Volume
+		total.CenterOfMass = total.CenterOfMass.Add(children[i].CenterOfMass.Mul(children[i].Mass))
+	}
+	if total.Mass == 0.0 {
+		return total
+	}
+	total.CenterOfMass = total.CenterOfMass.Mul(1.0 / total.Mass)
+
+	// Move each child's inertia to the shared center of mass with the parallel
+	// axis theorem
+	for _, child := range children {
+		d := child.CenterOfMass.Sub(total.CenterOfMass)
+		shift := mgl32.Ident3().Mul(d.Dot(d)).Sub(d.OuterProd3(d)).Mul(child.Mass)
+		total.Inertia = total.Inertia.Add(child.Inertia).Add(shift)
+	}
+	return total
+}
+
+// ComputeMeshMassProperties finds the mass properties of a solid bounded by a
+// triangle mesh. The mesh must be watertight, with every edge shared by
+// exactly two triangles, but may be wound either way and be anywhere in space
+func ComputeMeshMassProperties(vertices []mgl32.Vec3, triangles [][3]int, density float32) (MassProperties, error) {
+	if len(triangles) < 4 {
+		return MassProperties{}, fmt.Errorf("Mesh needs at least 4 triangles to be closed, got %v", len(triangles))
+	}
+
+	welded := weldVertices(vertices)
+
+	// Each edge of a closed, consistently wound mesh shows up once in each
+	// direction
+	edges := map[[2]int]int{}
+	for _, tri := range triangles {
+		for e := 0; e < 3; e++ {
+			if tri[e] < 0 || tri[e] >= len(vertices) {
+				return MassProperties{}, fmt.Errorf("Mesh triangle has vertex %v out of range", tri[e])
+			}
+		}
+		for e := 0; e < 3; e++ {
+			a, b := welded[tri[e]], welded[tri[(e+1)%3]]
+			edges[[2]int{a, b}]++
+		}
+	}
+	for _, tri := range triangles {
+		for e := 0; e < 3; e++ {
+			a, b := welded[tri[e]], welded[tri[(e+1)%3]]
+			if edges[[2]int{a, b}] != 1 || edges[[2]int{b, a}] != 1 {
+				return MassProperties{}, fmt.Errorf("Mesh isn't watertight, edge %v-%v is used %v times one way and %v the other",
+					a, b, edges[[2]int{a, b}], edges[[2]int{b, a}])
+			}
+		}
+	}
+
+	// Integrate around the middle of the mesh rather than the origin, so that
+	// meshes far from the origin don't lose precision
+	center := mgl32.Vec3{}
+	for _, v := range vertices {
+		center = center.Add(v)
+	}
+	center = center.Mul(1.0 / float32(len(vertices)))
+
+	local := make([]mgl32.Vec3, len(vertices))
+	for i, v := range vertices {
+		local[i] = v.Sub(center)
+	}
+
+	props := polyhedronMassProperties(local, triangles, density)
+	if props.Volume == 0.0 {
+		return props, fmt.Errorf("Mesh has no volume")
+	}
+
+	// Inside out meshes come out with a negative volume, and so everything
+	// else negative too
+	if props.Volume < 0.0 {
+		props.Mass = -props.Mass
+		props.Volume = -props.Volume
+		props.Inertia = props.Inertia.Mul(-1.0)
+	}
+	props.CenterOfMass = props.CenterOfMass.Add(center)
+	return props, nil
+}
+
+// weldVertices maps every vertex to the first vertex at the same position.
+// Exporters often split vertices along seams in the UVs or normals, which
+// would otherwise look like holes in the mesh
+func weldVertices(vertices []mgl32.Vec3) []int {
+	welded := make([]int, len(vertices))
+	seen := map[mgl32.Vec3]int{}
+	for i, v := range vertices {
+		if first, ok := seen[v]; ok {
+			welded[i] = first
+		} else {
+			seen[v] = i
+			welded[i] = i
+		}
+	}
+	return welded
+}
+
+// polyhedronMassProperties integrates over a closed triangle mesh, by summing
+// up the signed tetrahedra between each face and the origin. See Blow and
+// Binstock, "How to find the inertia tensor (or other mass properties) of a 3D
+// solid body represented by a triangle mesh"
+func polyhedronMassProperties(points []mgl32.Vec3, faces [][3]int, density float32) MassProperties {
+	// Covariance of the canonical tetrahedron (0,0,0) (1,0,0) (0,1,0) (0,0,1)
+	canonical := [3][3]float64{
+		{1.0 / 60.0, 1.0 / 120.0, 1.0 / 120.0},
+		{1.0 / 120.0, 1.0 / 60.0, 1.0 / 120.0},
+		{1.0 / 120.0, 1.0 / 120.0, 1.0 / 60.0},
+	}
+
+	volume := 0.0
+	center := [3]float64{}
+	covariance := [3][3]float64{}
+
+	for _, face := range faces {
+		var m [3][3]float64
+		for col := 0; col < 3; col++ {
+			for row := 0; row < 3; row++ {
+				m[row][col] = float64(points[face[col]][row])
+			}
+		}
+
+		det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+			m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+			m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+
+		tetVolume := det / 6.0
+		volume += tetVolume
+		for i := 0; i < 3; i++ {
+			center[i] += tetVolume * (m[i][0] + m[i][1] + m[i][2]) / 4.0
+		}
+
+		// covariance += det * M * canonical * M^T
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				sum := 0.0
+				for k := 0; k < 3; k++ {
+					for l := 0; l < 3; l++ {
+						sum += m[i][k] * canonical[k][l] * m[j][l]
+					}
+				}
+				covariance[i][j] += det * sum
+			}
+		}
+	}
+
+	if volume == 0.0 {
+		return MassProperties{}
+	}
+	for i := 0; i < 3; i++ {
+		center[i] /= volume
+	}
+
+	// Move the covariance to the center of mass, and scale everything by the
+	// density to go from volume to mass
+	rho := float64(density)
+	mass := volume * rho
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			covariance[i][j] = (covariance[i][j] - volume*center[i]*center[j]) * rho
+		}
+	}
+
+	trace := covariance[0][0] + covariance[1][1] + covariance[2][2]
+	inertia := mgl32.Mat3{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			value := -covariance[i][j]
+			if i == j {
+				value += trace
+			}
+			inertia.Set(i, j, float32(value))
+		}
+	}
+
+	return MassProperties{
+		Mass:         float32(mass),
+		Volume:       float32(volume),
+		CenterOfMass: mgl32.Vec3{float32(center[0]), float32(center[1]), float32(center[2])},
+		Inertia:      inertia,
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// checkMassProperties compares two MassProperties, relative to the size of
+// what is expected
+func checkMassProperties(t *testing.T, name string, got, want MassProperties) {
+	t.Helper()
+	near := func(a, b float32) bool {
+		return math.Abs(float64(a-b)) <= 1e-3*math.Max(1.0, math.Abs(float64(b)))
+	}
+	if !near(got.Mass, want.Mass) {
+		t.Errorf("%s: mass = %v, want %v", name, got.Mass, want.Mass)
+	}
+	if !near(got.Volume, want.Volume) {
+		t.Errorf("%s: volume = %v, want %v", name, got.Volume, want.Volume)
+	}
+	if got.CenterOfMass.Sub(want.CenterOfMass).Len() > 1e-3 {
+		t.Errorf("%s: center of mass = %v, want %v", name, got.CenterOfMass, want.CenterOfMass)
+	}
+	for i := range want.Inertia {
+		if !near(got.Inertia[i], want.Inertia[i]) {
+			t.Errorf("%s: inertia = %v, want %v", name, got.Inertia, want.Inertia)
+			break
+		}
+	}
+}
+
+func TestComputeMassProperties(t *testing.T) {
+	sphereMass := float32(2 * 4.0 / 3.0 * math.Pi * 8)
+	tests := []struct {
+		name     string
+		collider Collider
+		want     MassProperties
+	}{
+		{"sphere", SphereCollider{Radius: 2}, MassProperties{
+			Mass:    sphereMass,
+			Volume:  sphereMass / 2,
+			Inertia: mgl32.Diag3(mgl32.Vec3{1, 1, 1}.Mul(0.4 * sphereMass * 4)),
+		}},
+		{"box", BoxCollider{Size: mgl32.Vec3{1, 2, 3}}, MassProperties{
+			Mass:    96,
+			Volume:  48,
+			Inertia: mgl32.Diag3(mgl32.Vec3{96.0 / 3 * 13, 96.0 / 3 * 10, 96.0 / 3 * 5}),
+		}},
+		// A capsule with no cylinder is a sphere
+		{"capsule without height", CapsuleCollider{Radius: 2}, MassProperties{
+			Mass:    sphereMass,
+			Volume:  sphereMass / 2,
+			Inertia: mgl32.Diag3(mgl32.Vec3{1, 1, 1}.Mul(0.4 * sphereMass * 4)),
+		}},
+		// Two boxes side by side are one box twice as long
+		{"compound", CompoundCollider{Children: []ChildCollider{
+			{Collider: BoxCollider{Size: mgl32.Vec3{1, 2, 3}}, Offset: mgl32.Vec3{4, 0, 0}},
+			{Collider: BoxCollider{Size: mgl32.Vec3{1, 2, 3}}, Offset: mgl32.Vec3{6, 0, 0}},
+		}}, MassProperties{
+			Mass:         192,
+			Volume:       96,
+			CenterOfMass: mgl32.Vec3{5, 0, 0},
+			Inertia:      mgl32.Diag3(mgl32.Vec3{192.0 / 3 * 13, 192.0 / 3 * 13, 192.0 / 3 * 8}),
+		}},
+	}
+	for _, test := range tests {
+		checkMassProperties(t, test.name, ComputeMassProperties(test.collider, 2), test.want)
+	}
+}
+
+func TestHullMassProperties(t *testing.T) {
+	size := mgl32.Vec3{1, 2, 0.5}
+	offset := mgl32.Vec3{1, -2, 3}
+	points := []mgl32.Vec3{}
+	for _, corner := range boxCorners(size) {
+		points = append(points, corner.Add(offset))
+	}
+	hull, err := NewConvexHullCollider(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A hull of a box's corners is the box, wherever it is
+	want := ComputeMassProperties(BoxCollider{Size: size}, 3)
+	want.CenterOfMass = offset
+	checkMassProperties(t, "box hull", ComputeMassProperties(hull, 3), want)
+}
+
+func TestComputeMeshMassProperties(t *testing.T) {
+	size := mgl32.Vec3{2, 1, 0.5}
+	cube, _ := NewConvexHullCollider(boxCorners(size))
+	box := ComputeMassProperties(BoxCollider{Size: size}, 2)
+
+	moved := func(offset mgl32.Vec3) []mgl32.Vec3 {
+		vertices := []mgl32.Vec3{}
+		for _, p := range cube.Points {
+			vertices = append(vertices, p.Add(offset))
+		}
+		return vertices
+	}
+	insideOut := [][3]int{}
+	for _, face := range cube.Faces {
+		insideOut = append(insideOut, [3]int{face[0], face[2], face[1]})
+	}
+	// Every triangle gets its own vertices, like a mesh with hard edges
+	split := []mgl32.Vec3{}
+	splitFaces := [][3]int{}
+	for _, face := range cube.Faces {
+		n := len(split)
+		split = append(split, cube.Points[face[0]], cube.Points[face[1]], cube.Points[face[2]])
+		splitFaces = append(splitFaces, [3]int{n, n + 1, n + 2})
+	}
+
+	tests := []struct {
+		name      string
+		vertices  []mgl32.Vec3
+		triangles [][3]int
+		err       bool
+		center    mgl32.Vec3
+	}{
+		{"cube", cube.Points, cube.Faces, false, mgl32.Vec3{}},
+		{"far from the origin", moved(mgl32.Vec3{1000, -500, 2000}), cube.Faces, false, mgl32.Vec3{1000, -500, 2000}},
+		{"inside out", cube.Points, insideOut, false, mgl32.Vec3{}},
+		{"split vertices", split, splitFaces, false, mgl32.Vec3{}},
+		{"missing a triangle", cube.Points, cube.Faces[1:], true, mgl32.Vec3{}},
+		{"vertex out of range", cube.Points, append([][3]int{{0, 1, 99}}, cube.Faces[1:]...), true, mgl32.Vec3{}},
+		{"too few triangles", cube.Points, cube.Faces[:3], true, mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		props, err := ComputeMeshMassProperties(test.vertices, test.triangles, 2)
+		if (err != nil) != test.err {
+			t.Errorf("%s: err = %v, want an error: %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		want := box
+		want.CenterOfMass = test.center
+		checkMassProperties(t, test.name, props, want)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MeshCollider is a triangle mesh in local space, for level geometry like
+// terrain that can't be built from convex shapes. It has no inside, so only
+// bodies with an infinite Mass should use it, and other meshes and planes
+// pass through it
+type MeshCollider struct {
+	Vertices  []mgl32.Vec3
+	Triangles [][3]int
+	// Bounds of every vertex, in local space
+	Bounds AABB
+}
+
+// NewMeshCollider creates a MeshCollider from a list of triangles, which can
+// come straight from a loaded Model
+func NewMeshCollider(vertices []mgl32.Vec3, triangles [][3]int) (MeshCollider, error) {
+	mesh := MeshCollider{Vertices: vertices, Triangles: triangles}
+	if len(vertices) == 0 || len(triangles) == 0 {
+		return mesh, fmt.Errorf("Mesh collider needs at least 1 triangle")
+	}
+
+	for _, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || v >= len(vertices) {
+				return mesh, fmt.Errorf("Mesh collider triangle refers to vertex %v, but there are only %v", v, len(vertices))
+			}
+		}
+	}
+
+	mesh.Bounds = AABB{Min: vertices[0], Max: vertices[0]}
+	for _, v := range vertices {
+		mesh.Bounds = mesh.Bounds.Union(AABB{Min: v, Max: v})
+	}
+	return mesh, nil
+}
+
+// toLocal finds the local space AABB around a world space one
+func (mesh MeshCollider) toLocal(bounds AABB, pos mgl32.Vec3, rot mgl32.Quat) AABB {
+	return transformBounds(bounds, rot.Conjugate(), rot.Conjugate().Rotate(pos.Mul(-1.0)))
+}
+
+// triangle returns the corners of triangle i in local space, and their bounds
+func (mesh MeshCollider) triangle(i int) ([3]mgl32.Vec3, AABB) {
+	tri := mesh.Triangles[i]
+	a := mesh.Vertices[tri[0]]
+	b := mesh.Vertices[tri[1]]
+	c := mesh.Vertices[tri[2]]
+	bounds := AABB{Min: a, Max: a}.Union(AABB{Min: b, Max: b}).Union(AABB{Min: c, Max: c})
+	return [3]mgl32.Vec3{a, b, c}, bounds
+}
+
+// triangleShape places triangle i in the world as a flat convex shape
+func (mesh MeshCollider) triangleShape(i int, pos mgl32.Vec3, rot mgl32.Quat) convexShape {
+	corners, _ := mesh.triangle(i)
+	for j := range corners {
+		corners[j] = pos.Add(rot.Rotate(corners[j]))
+	}
+	return convexShape{
+		Support: func(direction mgl32.Vec3) mgl32.Vec3 {
+			best := corners[0]
+			for _, p := range corners[1:] {
+				if p.Dot(direction) > best.Dot(direction) {
+					best = p
+				}
+			}
+			return best
+		},
+		Center: corners[0].Add(corners[1]).Add(corners[2]).Mul(1.0 / 3.0),
+	}
+}
+
+// pieces returns a convex shape for every triangle touching a world space AABB
+func (mesh MeshCollider) pieces(pos mgl32.Vec3, rot mgl32.Quat, bounds AABB) []convexShape {
+	local := mesh.toLocal(bounds, pos, rot)
+	if !local.Overlaps(mesh.Bounds) {
+		return nil
+	}
+
+	pieces := []convexShape{}
+	for i := range mesh.Triangles {
+		if _, triBounds := mesh.triangle(i); triBounds.Overlaps(local) {
+			pieces = append(pieces, mesh.triangleShape(i, pos, rot))
+		}
+	}
+	return pieces
+}
+
+// collideMesh finds the contacts between a convex Collider and every triangle
+// of a mesh it touches, each triangle is treated as a flat convex shape
+func collideMesh(col Collider, pos mgl32.Vec3, rot mgl32.Quat, mesh MeshCollider, meshPos mgl32.Vec3, meshRot mgl32.Quat) []Contact {
+	shape, ok := newConvexShape(col, pos, rot)
+	if !ok {
+		return nil
+	}
+	local := mesh.toLocal(colliderBounds(col, pos, rot), meshPos, meshRot)
+	if !local.Overlaps(mesh.Bounds) {
+		return nil
+	}
+
+	contacts := []Contact{}
+	for i := range mesh.Triangles {
+		if _, bounds := mesh.triangle(i); !bounds.Overlaps(local) {
+			continue
+		}
+
+		for _, c := range collideConvex(shape, mesh.triangleShape(i, meshPos, meshRot)) {
+			c.ID.Triangle = uint32(i + 1)
+			contacts = append(contacts, c)
+		}
+	}
+	return contacts
+}
+
+// raycastMesh finds the closest triangle a ray hits, from either side
+func raycastMesh(mesh MeshCollider, pos mgl32.Vec3, rot mgl32.Quat, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	inv := rot.Conjugate()
+	origin = inv.Rotate(origin.Sub(pos))
+	direction = inv.Rotate(direction)
+	if _, hit := mesh.Bounds.IntersectRay(origin, direction, maxDist); !hit {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	dist := maxDist
+	normal := mgl32.Vec3{}
+	found := false
+	for i := range mesh.Triangles {
+		corners, _ := mesh.triangle(i)
+
+		// Möller-Trumbore
+		e1 := corners[1].Sub(corners[0])
+		e2 := corners[2].Sub(corners[0])
+		p := direction.Cross(e2)
+		det := e1.Dot(p)
+		if det > -1e-8 && det < 1e-8 {
+			continue
+		}
+		s := origin.Sub(corners[0])
+		u := s.Dot(p) / det
+		if u < 0.0 || u > 1.0 {
+			continue
+		}
+		q := s.Cross(e1)
+		v := direction.Dot(q) / det
+		if v < 0.0 || u+v > 1.0 {
+			continue
+		}
+		t := e2.Dot(q) / det
+		if t < 0.0 || t > dist {
+			continue
+		}
+
+		dist = t
+		normal = e1.Cross(e2).Normalize()
+		if normal.Dot(direction) > 0.0 {
+			normal = normal.Mul(-1.0)
+		}
+		found = true
+	}
+	if !found {
+		return 0.0, mgl32.Vec3{}, false
+	}
+	return dist, rot.Rotate(normal), true
+}
@@ -15,6 +15,8 @@ const (
 	VERT_ATTRIB = 0
 	NORM_ATTRIB = 1
 	TXCD_ATTRIB = 2
+	OFST_ATTRIB = 3
+	COLR_ATTRIB = 4
 )
 
 type modelGroup struct {
@@ -26,6 +28,11 @@ type modelGroup struct {
 type Model struct {
 	Transform mgl32.Mat4
 
+	// Vertices and Triangles are the mesh from the last file loaded, kept
+	// around for working out its MassProperties
+	Vertices  []mgl32.Vec3
+	Triangles [][3]int
+
 	glVao  uint32
 	glVbos [3]uint32
 	groups []modelGroup
@@ -39,6 +46,17 @@ func NewModel() (*Model, error) {
 	}, nil
 }
 
+// NewMeshFromFile loads just the Vertices and Triangles of an OBJ file, without
+// creating anything to draw it with, for meshes that are only collided with
+func NewMeshFromFile(filename string) (*Model, error) {
+	model, err := NewModel()
+	if err != nil {
+		return model, err
+	}
+	_, _, _, err = model.loadMesh(filename)
+	return model, err
+}
+
 func NewModelFromFile(filename string) (*Model, error) {
 	model, err := NewModel()
 	if err != nil {
@@ -51,12 +69,74 @@ func NewModelFromFile(filename string) (*Model, error) {
 	return model, nil
 }
 
+// NewDynamicModel creates a Model of vertCount vertices, drawn as triangles,
+// whose vertices and normals are replaced every frame with UpdateVertices
+func NewDynamicModel(vertCount int) (*Model, error) {
+	model, err := NewModel()
+	if err != nil {
+		return model, err
+	}
+
+	gl.GenVertexArrays(1, &model.glVao)
+	gl.BindVertexArray(model.glVao)
+	gl.GenBuffers(3, &model.glVbos[0])
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, model.glVbos[0])
+	gl.BufferData(gl.ARRAY_BUFFER, vertCount*3*4, nil, gl.DYNAMIC_DRAW)
+	gl.VertexAttribPointer(VERT_ATTRIB, 3, gl.FLOAT, false, 0, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(VERT_ATTRIB)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, model.glVbos[1])
+	gl.BufferData(gl.ARRAY_BUFFER, vertCount*3*4, nil, gl.DYNAMIC_DRAW)
+	gl.VertexAttribPointer(NORM_ATTRIB, 3, gl.FLOAT, false, 0, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(NORM_ATTRIB)
+
+	gl.DeleteBuffers(1, &model.glVbos[2])
+	model.glVbos[2] = 0
+
+	model.groups = append(model.groups, modelGroup{
+		DrawMode: gl.TRIANGLES,
+		Start:    0,
+		Count:    int32(vertCount),
+	})
+	return model, nil
+}
+
+// UpdateVertices replaces the vertices and normals of a dynamic Model, both
+// hold 3 floats per vertex
+func (model *Model) UpdateVertices(verts, norms []float32) {
+	if len(verts) > 0 {
+		gl.BindBuffer(gl.ARRAY_BUFFER, model.glVbos[0])
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(verts)*4, gl.Ptr(verts))
+	}
+	if len(norms) > 0 {
+		gl.BindBuffer(gl.ARRAY_BUFFER, model.glVbos[1])
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(norms)*4, gl.Ptr(norms))
+	}
+}
+
+// Cleanup frees up resources. Models shared between Actors are cleaned up by
+// each of them, so the buffers are forgotten once they are deleted
 func (model *Model) Cleanup() {
 	gl.DeleteBuffers(3, &model.glVbos[0])
 	gl.DeleteVertexArrays(1, &model.glVao)
+	model.glVbos = [3]uint32{0, 0, 0}
+	model.glVao = 0
 }
 
 func (model *Model) LoadFromFile(filename string) error {
+	verts, norms, txcds, err := model.loadMesh(filename)
+	if err != nil {
+		return err
+	}
+	model.upload(verts, norms, txcds)
+	return nil
+}
+
+// loadMesh reads an OBJ file into Vertices, Triangles and the groups to draw,
+// and returns the vertices, normals and texture coordinates of every triangle
+// ready to upload
+func (model *Model) loadMesh(filename string) ([]float32, []float32, []float32, error) {
 	// Holds a material
 	type MatDef struct {
 		Ambient     mgl32.Vec3
@@ -87,7 +167,7 @@ func (model *Model) LoadFromFile(filename string) error {
 	// Open the .obj file
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 
 	// Create a reader with a specific buffer size, needed by reader.ReadLine()
@@ -132,7 +212,7 @@ func (model *Model) LoadFromFile(filename string) error {
 		case "mtllib":
 
 			if err != nil {
-				return err
+				return nil, nil, nil, err
 			}
 
 		case "o":
@@ -162,7 +242,7 @@ func (model *Model) LoadFromFile(filename string) error {
 					&tmpFace.NormInds[2],
 				)
 				if err != nil || count != 6 {
-					return fmt.Errorf("Malformed OBJ file '%v' %v", line)
+					return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 				}
 				// Test for and parse faces in the 'v/vt v/vt v/vt' format
 			} else if strings.Count(parts[1], "/") == 3 {
@@ -176,7 +256,7 @@ func (model *Model) LoadFromFile(filename string) error {
 					&tmpFace.TxcdInds[2],
 				)
 				if err != nil || count != 6 {
-					return fmt.Errorf("Malformed OBJ file '%v'", line)
+					return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 				}
 				// Test for and parse faces in the 'v/vt/vn v/vt/vn v/vt/vn' format
 			} else if strings.Count(parts[1], "/") == 6 {
@@ -193,10 +273,10 @@ func (model *Model) LoadFromFile(filename string) error {
 					&tmpFace.NormInds[2],
 				)
 				if err != nil || count != 9 {
-					return fmt.Errorf("Malformed OBJ file '%v'", line)
+					return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 				}
 			} else {
-				return fmt.Errorf("Malformed OBJ file '%v'", line)
+				return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 			}
 
 			group.Faces = append(group.Faces, tmpFace)
@@ -205,7 +285,7 @@ func (model *Model) LoadFromFile(filename string) error {
 
 			count, err = fmt.Sscanf(parts[1], "%f %f %f", &tmpVec3[0], &tmpVec3[1], &tmpVec3[2])
 			if err != nil || count != 3 {
-				return fmt.Errorf("Malformed OBJ file '%v'", line)
+				return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 			}
 
 			allVerts = append(allVerts, tmpVec3)
@@ -214,7 +294,7 @@ func (model *Model) LoadFromFile(filename string) error {
 
 			count, err = fmt.Sscanf(parts[1], "%f %f %f", &tmpVec3[0], &tmpVec3[1], &tmpVec3[2])
 			if err != nil || count != 3 {
-				return fmt.Errorf("Malformed OBJ file '%v'", line)
+				return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 			}
 
 			allNorms = append(allNorms, tmpVec3)
@@ -223,7 +303,7 @@ func (model *Model) LoadFromFile(filename string) error {
 
 			count, err = fmt.Sscanf(parts[1], "%f %f", &tmpVec2[0], &tmpVec2[1])
 			if err != nil || count != 2 {
-				return fmt.Errorf("Malformed OBJ file '%v'", line)
+				return nil, nil, nil, fmt.Errorf("Malformed OBJ file '%v'", line)
 			}
 
 			allTxcds = append(allTxcds, tmpVec2)
@@ -235,6 +315,9 @@ func (model *Model) LoadFromFile(filename string) error {
 		group.Name = "default"
 	}
 
+	model.Vertices = allVerts
+	model.Triangles = [][3]int{}
+
 	start := int32(0)
 	verts := []float32{}
 	norms := []float32{}
@@ -245,15 +328,15 @@ func (model *Model) LoadFromFile(filename string) error {
 		for f := range group.Faces {
 			face := &group.Faces[f]
 			for i := 0; i < 3; i++ {
-				// Adjust for negative indices
+				// Adjust for negative indices, -1 is the last one
 				if face.VertInds[i] < 0 {
-					face.VertInds[i] += len(allVerts)
+					face.VertInds[i] += len(allVerts) + 1
 				}
 				if face.NormInds[i] < 0 {
-					face.NormInds[i] += len(allNorms)
+					face.NormInds[i] += len(allNorms) + 1
 				}
 				if face.TxcdInds[i] < 0 {
-					face.TxcdInds[i] += len(allTxcds)
+					face.TxcdInds[i] += len(allTxcds) + 1
 				}
 
 				// Adjust for zero-indexing
@@ -281,6 +364,7 @@ func (model *Model) LoadFromFile(filename string) error {
 					)
 				}
 			}
+			model.Triangles = append(model.Triangles, face.VertInds)
 		}
 
 		vertCount := int32(len(group.Faces) * 3 * 3)
@@ -292,6 +376,11 @@ func (model *Model) LoadFromFile(filename string) error {
 		start += vertCount
 	}
 
+	return verts, norms, txcds, nil
+}
+
+// upload creates the vertex array and buffers to draw the Model with
+func (model *Model) upload(verts, norms, txcds []float32) {
 	gl.GenVertexArrays(1, &model.glVao)
 	gl.BindVertexArray(model.glVao)
 	gl.GenBuffers(3, &model.glVbos[0])
@@ -320,8 +409,13 @@ func (model *Model) LoadFromFile(filename string) error {
 		gl.VertexAttribPointer(TXCD_ATTRIB, 2, gl.FLOAT, false, 0, gl.PtrOffset(0))
 		gl.EnableVertexAttribArray(TXCD_ATTRIB)
 	}
+}
 
-	return nil
+// MassProperties works out the mass properties of the loaded mesh, as if it
+// were a solid made of a material with the given density. The mesh must be
+// closed, and is measured in its own space, ignoring Transform
+func (model *Model) MassProperties(density float32) (MassProperties, error) {
+	return ComputeMeshMassProperties(model.Vertices, model.Triangles, density)
 }
 
 func (model *Model) Render(shader *Shader) {
@@ -332,3 +426,14 @@ func (model *Model) Render(shader *Shader) {
 		gl.DrawArrays(group.DrawMode, group.Start, group.Count)
 	}
 }
+
+// RenderInstanced draws count copies of the Model in one call, the vertex
+// array must already have per instance attributes set up
+func (model *Model) RenderInstanced(shader *Shader, count int) {
+	gl.BindVertexArray(model.glVao)
+
+	for g := range model.groups {
+		group := &model.groups[g]
+		gl.DrawArraysInstanced(group.DrawMode, group.Start, group.Count, int32(count))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// OverlapSphere finds every body touching a sphere
+func (w *World) OverlapSphere(center mgl32.Vec3, radius float32, layerMask uint32) []*RigidBody {
+	return w.Overlap(SphereCollider{Radius: radius}, center, layerMask)
+}
+
+// OverlapBox finds every body touching a box with half-extents size
+func (w *World) OverlapBox(center, size mgl32.Vec3, layerMask uint32) []*RigidBody {
+	return w.Overlap(BoxCollider{Size: size}, center, layerMask)
+}
+
+// OverlapAABB finds every body touching an AABB
+func (w *World) OverlapAABB(bounds AABB, layerMask uint32) []*RigidBody {
+	return w.Overlap(BoxCollider{Size: bounds.Size()}, bounds.Center(), layerMask)
+}
+
+// Overlap finds every body touching any convex Collider placed at pos, in the
+// order they were added to the World. Triggers are ignored
+func (w *World) Overlap(shape Collider, pos mgl32.Vec3, layerMask uint32) []*RigidBody {
+	bodies := []*RigidBody{}
+
+	query, ok := newConvexShape(shape, pos, mgl32.QuatIdent())
+	if !ok {
+		return bodies
+	}
+
+	bounds := colliderBounds(shape, pos, mgl32.QuatIdent())
+	indices := []int{}
+	w.updateBroadphase()
+	w.broadphase.QueryAABB(bounds, func(rb *RigidBody, index int) bool {
+		if rb.IsTrigger || layerMask&(1<<rb.Layer) == 0 {
+			return true
+		}
+
+		transform := &rb.Parent.Transform
+		if plane, ok := rb.Collider.(PlaneCollider); ok {
+			if overlapPlane(query, plane, transform.Position, transform.Rotation) {
+				indices = append(indices, index)
+			}
+			return true
+		}
+
+		for _, target := range convexPieces(rb.Collider, transform.Position, transform.Rotation, bounds) {
+			if _, _, _, _, overlap := shapeDistance(query, target); overlap {
+				indices = append(indices, index)
+				break
+			}
+		}
+		return true
+	})
+
+	sort.Ints(indices)
+	for _, index := range indices {
+		bodies = append(bodies, w.Actors[index].RigidBody)
+	}
+	return bodies
+}
+
+// ClosestBody finds the body with the closest surface to point, within
+// radius. Point is the closest point on the body, Normal points from there
+// towards point, and Distance is 0 if point is inside the body
+func (w *World) ClosestBody(point mgl32.Vec3, radius float32, layerMask uint32) (RaycastHit, bool) {
+	closest := RaycastHit{}
+	found := false
+
+	query, _ := newConvexShape(SphereCollider{Radius: 0.0}, point, mgl32.QuatIdent())
+	bounds := NewAABB(point, mgl32.Vec3{radius, radius, radius})
+
+	w.updateBroadphase()
+	w.broadphase.QueryAABB(bounds, func(rb *RigidBody, index int) bool {
+		if rb.IsTrigger || layerMask&(1<<rb.Layer) == 0 {
+			return true
+		}
+
+		transform := &rb.Parent.Transform
+		if plane, ok := rb.Collider.(PlaneCollider); ok {
+			dist, pointB, normal := closestOnPlane(point, plane, transform.Position, transform.Rotation)
+			if dist <= radius && (!found || dist < closest.Distance) {
+				closest = RaycastHit{
+					Body:     rb,
+					Point:    pointB,
+					Normal:   normal,
+					Distance: dist,
+				}
+				found = true
+			}
+			return true
+		}
+
+		for _, target := range convexPieces(rb.Collider, transform.Position, transform.Rotation, bounds) {
+			dist, _, pointB, normal, overlap := shapeDistance(query, target)
+			if overlap {
+				dist = 0.0
+				pointB = point
+				normal = mgl32.Vec3{}
+			}
+			if dist > radius {
+				continue
+			}
+
+			if !found || dist < closest.Distance {
+				closest = RaycastHit{
+					Body:     rb,
+					Point:    pointB,
+					Normal:   normal.Mul(-1.0),
+					Distance: dist,
+				}
+				found = true
+			}
+		}
+		return true
+	})
+
+	return closest, found
+}
+
+// overlapPlane checks whether a shape reaches behind a plane, which is when
+// the point on the shape furthest behind it does
+func overlapPlane(shape convexShape, plane PlaneCollider, planePos mgl32.Vec3, planeRot mgl32.Quat) bool {
+	normal := planeRot.Rotate(plane.Normal).Normalize()
+	deepest := shape.Support(normal.Mul(-1.0)).Sub(normal.Mul(shape.Margin))
+	return normal.Dot(deepest.Sub(planePos))-plane.Offset <= 0.0
+}
+
+// closestOnPlane finds how far point is in front of a plane, the closest point
+// on it and its normal. Behind the plane is inside, so the distance is 0 there
+func closestOnPlane(point mgl32.Vec3, plane PlaneCollider, planePos mgl32.Vec3, planeRot mgl32.Quat) (float32, mgl32.Vec3, mgl32.Vec3) {
+	normal := planeRot.Rotate(plane.Normal).Normalize()
+	dist := normal.Dot(point.Sub(planePos)) - plane.Offset
+	if dist <= 0.0 {
+		return 0.0, point, mgl32.Vec3{}
+	}
+	return dist, point.Sub(normal.Mul(dist)), normal
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newOverlapWorld has unit spheres at x = 0, 3 and 6, with the last in layer 1
+func newOverlapWorld() (*World, []*RigidBody) {
+	w := NewWorld()
+	bodies := []*RigidBody{}
+	for i := 0; i < 3; i++ {
+		actor := NewActor()
+		actor.Transform.Position = mgl32.Vec3{float32(i) * 3, 0, 0}
+		actor.RigidBody.Collider = SphereCollider{Radius: 1}
+		w.AddActor(actor)
+		bodies = append(bodies, actor.RigidBody)
+	}
+	bodies[2].Layer = 1
+	return w, bodies
+}
+
+func TestOverlap(t *testing.T) {
+	w, bodies := newOverlapWorld()
+	tests := []struct {
+		name  string
+		found []*RigidBody
+		want  []int
+	}{
+		{"sphere touching one", w.OverlapSphere(mgl32.Vec3{0, 1.5, 0}, 1, AllLayers), []int{0}},
+		{"sphere between two", w.OverlapSphere(mgl32.Vec3{1.5, 0, 0}, 1, AllLayers), []int{0, 1}},
+		{"sphere touching none", w.OverlapSphere(mgl32.Vec3{1.5, 3, 0}, 1, AllLayers), []int{}},
+		{"box around all", w.OverlapBox(mgl32.Vec3{3, 0, 0}, mgl32.Vec3{5, 1, 1}, AllLayers), []int{0, 1, 2}},
+		{"box masking layer 1", w.OverlapBox(mgl32.Vec3{3, 0, 0}, mgl32.Vec3{5, 1, 1}, LayerMask(0)), []int{0, 1}},
+		{"AABB missing a corner", w.OverlapAABB(NewAABB(mgl32.Vec3{4.6, 1.2, 0}, mgl32.Vec3{0.5, 0.5, 0.5}), AllLayers), []int{}},
+	}
+	for _, test := range tests {
+		if len(test.found) != len(test.want) {
+			t.Errorf("%s: found %d bodies, want %d", test.name, len(test.found), len(test.want))
+			continue
+		}
+		for i, index := range test.want {
+			if test.found[i] != bodies[index] {
+				t.Errorf("%s: body %d isn't sphere %d", test.name, i, index)
+			}
+		}
+	}
+}
+
+func TestClosestBody(t *testing.T) {
+	w, bodies := newOverlapWorld()
+	tests := []struct {
+		name   string
+		point  mgl32.Vec3
+		radius float32
+		found  bool
+		body   int
+		dist   float32
+	}{
+		{"nearest of two", mgl32.Vec3{1.2, 0, 0}, 5, true, 0, 0.2},
+		{"inside", mgl32.Vec3{3, 0.5, 0}, 5, true, 1, 0},
+		{"out of radius", mgl32.Vec3{3, 5, 0}, 2, false, 0, 0},
+		{"above", mgl32.Vec3{6, 3, 0}, 5, true, 2, 2},
+	}
+	for _, test := range tests {
+		hit, found := w.ClosestBody(test.point, test.radius, AllLayers)
+		if found != test.found {
+			t.Errorf("%s: found = %v, want %v", test.name, found, test.found)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if hit.Body != bodies[test.body] {
+			t.Errorf("%s: closest body isn't sphere %d", test.name, test.body)
+		}
+		if math.Abs(float64(hit.Distance-test.dist)) > 1e-3 {
+			t.Errorf("%s: distance = %v, want %v", test.name, hit.Distance, test.dist)
+		}
+	}
+}
+
+func TestOverlapPlane(t *testing.T) {
+	w := NewWorld()
+	ground := NewActor()
+	ground.Transform.Position = mgl32.Vec3{0, -1, 0}
+	ground.RigidBody.Collider = PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}, Offset: 1}
+	ground.RigidBody.Mass = math.MaxFloat32
+	w.AddActor(ground)
+
+	// The ground is at y = 0, and everything below it is solid
+	overlaps := []struct {
+		name   string
+		center mgl32.Vec3
+		want   bool
+	}{
+		{"sphere below", mgl32.Vec3{0, -0.5, 0}, true},
+		{"sphere far below", mgl32.Vec3{40, -50, 0}, true},
+		{"sphere through", mgl32.Vec3{0, 0.5, 0}, true},
+		{"sphere above", mgl32.Vec3{0, 1.5, 0}, false},
+	}
+	for _, test := range overlaps {
+		found := w.OverlapSphere(test.center, 1, AllLayers)
+		if (len(found) == 1 && found[0] == ground.RigidBody) != test.want {
+			t.Errorf("%s: found %d bodies, want the ground: %v", test.name, len(found), test.want)
+		}
+	}
+	if found := w.OverlapBox(mgl32.Vec3{0, 1.5, 0}, mgl32.Vec3{1, 1, 1}, AllLayers); len(found) != 0 {
+		t.Errorf("box above: found %d bodies, want none", len(found))
+	}
+
+	closest := []struct {
+		name  string
+		point mgl32.Vec3
+		found bool
+		dist  float32
+	}{
+		{"above", mgl32.Vec3{3, 0.5, 0}, true, 0.5},
+		{"below", mgl32.Vec3{3, -0.5, 0}, true, 0},
+		{"out of radius", mgl32.Vec3{3, 4, 0}, false, 0},
+	}
+	for _, test := range closest {
+		hit, found := w.ClosestBody(test.point, 2, AllLayers)
+		if found != test.found {
+			t.Errorf("%s: found = %v, want %v", test.name, found, test.found)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if hit.Body != ground.RigidBody || math.Abs(float64(hit.Distance-test.dist)) > 1e-5 {
+			t.Errorf("%s: %v away, want the ground %v away", test.name, hit.Distance, test.dist)
+		}
+		if test.dist > 0.0 && (hit.Point.Sub(mgl32.Vec3{3, 0, 0}).Len() > 1e-5 || hit.Normal != (mgl32.Vec3{0, 1, 0})) {
+			t.Errorf("%s: closest point %v with normal %v", test.name, hit.Point, hit.Normal)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// particleColliders finds the bodies that particles within bounds could hit,
+// triggers and bodies on layers outside of mask are left out
+func (w *World) particleColliders(bounds AABB, mask uint32) []*RigidBody {
+	bodies := []*RigidBody{}
+	w.updateBroadphase()
+	w.broadphase.QueryAABB(bounds, func(rb *RigidBody, index int) bool {
+		if !rb.IsTrigger && mask&(1<<rb.Layer) != 0 {
+			bodies = append(bodies, rb)
+		}
+		return true
+	})
+	return bodies
+}
+
+// collideParticle pushes a particle with a radius of thickness, that moved
+// from prev to p, out of every body, and stops it moving into them. Friction
+// is how much of its sliding velocity it loses when it touches one. The
+// bodies aren't pushed back
+func collideParticle(prev, p, v mgl32.Vec3, thickness, friction float32, bodies []*RigidBody) (mgl32.Vec3, mgl32.Vec3) {
+	for _, rb := range bodies {
+		transform := &rb.Parent.Transform
+		p, v = collideParticleWith(prev, p, v, thickness, friction, rb.Collider, transform.Position, transform.Rotation)
+	}
+	return p, v
+}
+
+func collideParticleWith(prev, p, v mgl32.Vec3, thickness, friction float32, col Collider, pos mgl32.Vec3, rot mgl32.Quat) (mgl32.Vec3, mgl32.Vec3) {
+	if compound, ok := col.(CompoundCollider); ok {
+		for _, child := range compound.Children {
+			p, v = collideParticleWith(prev, p, v, thickness, friction, child.Collider, pos.Add(rot.Rotate(child.Offset)), rot)
+		}
+		return p, v
+	}
+
+	// Fast particles can pass right through thin colliders in one step, so
+	// check the path they took before checking where they ended up
+	var target, normal mgl32.Vec3
+	hit := false
+	if move := p.Sub(prev); move.Len() > 0.0 {
+		dist := move.Len()
+		if t, n, ok := raycastCollider(col, pos, rot, prev, move.Mul(1.0/dist), dist); ok {
+			target = prev.Add(move.Mul(t / dist)).Add(n.Mul(thickness))
+			normal = n
+			hit = true
+		}
+	}
+	if !hit {
+		target, normal, hit = particlePenetration(p, thickness, col, pos, rot)
+	}
+	if !hit {
+		return p, v
+	}
+
+	vn := v.Dot(normal)
+	if vn < 0.0 {
+		v = v.Sub(normal.Mul(vn)).Mul(1.0 - friction)
+	}
+	return target, v
+}
+
+// particlePenetration finds where a particle inside of a Collider should be
+// moved to, and the surface normal there
+func particlePenetration(p mgl32.Vec3, thickness float32, col Collider, pos mgl32.Vec3, rot mgl32.Quat) (mgl32.Vec3, mgl32.Vec3, bool) {
+	switch col := col.(type) {
+	case SphereCollider:
+		d := p.Sub(pos)
+		r := col.Radius + thickness
+		if d.LenSqr() >= r*r {
+			return p, mgl32.Vec3{}, false
+		}
+		n := mgl32.Vec3{0, 1, 0}
+		if d.LenSqr() > 0.0 {
+			n = d.Normalize()
+		}
+		return pos.Add(n.Mul(r)), n, true
+
+	case CapsuleCollider:
+		// Pushed out from the closest point on the line between the two ends
+		local := rot.Conjugate().Rotate(p.Sub(pos))
+		axis := mgl32.Vec3{0, mgl32.Clamp(local.Y(), -col.HalfHeight, col.HalfHeight), 0}
+		d := local.Sub(axis)
+		r := col.Radius + thickness
+		if d.LenSqr() >= r*r {
+			return p, mgl32.Vec3{}, false
+		}
+		n := mgl32.Vec3{1, 0, 0}
+		if d.LenSqr() > 0.0 {
+			n = d.Normalize()
+		}
+		n = rot.Rotate(n)
+		return pos.Add(rot.Rotate(axis)).Add(n.Mul(r)), n, true
+
+	case MeshCollider:
+		return particleMeshPenetration(p, thickness, col, pos, rot)
+
+	case BoxCollider:
+		local := rot.Conjugate().Rotate(p.Sub(pos))
+		axis := 0
+		best := float32(math.MaxFloat32)
+		for j := 0; j < 3; j++ {
+			d := col.Size[j] + thickness - float32(math.Abs(float64(local[j])))
+			if d <= 0.0 {
+				return p, mgl32.Vec3{}, false
+			}
+			if d < best {
+				best = d
+				axis = j
+			}
+		}
+		n := mgl32.Vec3{}
+		n[axis] = 1.0
+		if local[axis] < 0.0 {
+			n[axis] = -1.0
+		}
+		n = rot.Rotate(n)
+		return p.Add(n.Mul(best)), n, true
+
+	case PlaneCollider:
+		n := rot.Rotate(col.Normal).Normalize()
+		d := n.Dot(p.Sub(pos)) - col.Offset - thickness
+		if d >= 0.0 {
+			return p, mgl32.Vec3{}, false
+		}
+		return p.Sub(n.Mul(d)), n, true
+
+	case ConvexHullCollider:
+		// Push out through whichever face is closest
+		local := rot.Conjugate().Rotate(p.Sub(pos))
+		face := -1
+		best := float32(-math.MaxFloat32)
+		for j, n := range col.Normals {
+			d := n.Dot(local) - col.Offsets[j] - thickness
+			if d >= 0.0 {
+				return p, mgl32.Vec3{}, false
+			}
+			if d > best {
+				best = d
+				face = j
+			}
+		}
+		if face < 0 {
+			return p, mgl32.Vec3{}, false
+		}
+		n := rot.Rotate(col.Normals[face])
+		return p.Sub(n.Mul(best)), n, true
+	}
+	return p, mgl32.Vec3{}, false
+}
+
+// particleMeshPenetration pushes a particle out from the closest triangle of a
+// mesh within thickness of it. A mesh is only a surface, so a particle that is
+// already through it stays on the side it is on
+func particleMeshPenetration(p mgl32.Vec3, thickness float32, mesh MeshCollider, pos mgl32.Vec3, rot mgl32.Quat) (mgl32.Vec3, mgl32.Vec3, bool) {
+	point, _ := newConvexShape(SphereCollider{Radius: 0.0}, p, mgl32.QuatIdent())
+	local := mesh.toLocal(NewAABB(p, mgl32.Vec3{thickness, thickness, thickness}), pos, rot)
+	if !local.Overlaps(mesh.Bounds) {
+		return p, mgl32.Vec3{}, false
+	}
+
+	target, normal := p, mgl32.Vec3{}
+	best := thickness
+	for i := range mesh.Triangles {
+		corners, bounds := mesh.triangle(i)
+		if !bounds.Overlaps(local) {
+			continue
+		}
+		dist, _, closest, n, overlap := shapeDistance(point, mesh.triangleShape(i, pos, rot))
+		if overlap {
+			// Right on the triangle, so out through its face
+			dist = 0.0
+			closest = p
+			n = rot.Rotate(corners[1].Sub(corners[0]).Cross(corners[2].Sub(corners[0]))).Mul(-1.0)
+			if n.Len() == 0.0 {
+				continue
+			}
+			n = n.Normalize()
+		}
+		if dist < best {
+			best = dist
+			normal = n.Mul(-1.0)
+			target = closest.Add(normal.Mul(thickness))
+		}
+	}
+	return target, normal, best < thickness
+}
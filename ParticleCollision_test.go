@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestParticlePenetration(t *testing.T) {
+	// Two triangles making a square of ground from -1 to 1, facing up
+	ground, err := NewMeshCollider([]mgl32.Vec3{{-1, 0, -1}, {1, 0, -1}, {1, 0, 1}, {-1, 0, 1}}, [][3]int{{0, 2, 1}, {0, 3, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	turned := mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 0, 1})
+	capsule := CapsuleCollider{Radius: 0.5, HalfHeight: 1}
+	tipped := mgl32.QuatRotate(math.Pi/2, mgl32.Vec3{0, 0, 1})
+
+	tests := []struct {
+		name     string
+		p        mgl32.Vec3
+		collider Collider
+		rotation mgl32.Quat
+		hit      bool
+		target   mgl32.Vec3
+		normal   mgl32.Vec3
+	}{
+		{"inside a sphere", mgl32.Vec3{0, 0.5, 0}, SphereCollider{Radius: 1}, mgl32.QuatIdent(), true, mgl32.Vec3{0, 1.1, 0}, mgl32.Vec3{0, 1, 0}},
+		{"outside a sphere", mgl32.Vec3{0, 1.2, 0}, SphereCollider{Radius: 1}, mgl32.QuatIdent(), false, mgl32.Vec3{}, mgl32.Vec3{}},
+		{"inside a box", mgl32.Vec3{0.2, 0.9, 0}, BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.QuatIdent(), true, mgl32.Vec3{0.2, 1.1, 0}, mgl32.Vec3{0, 1, 0}},
+		{"inside a turned box", mgl32.Vec3{0.5, 0.5, 0}, BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, turned, true, mgl32.Vec3{0.7778175, 0.7778175, 0}, mgl32.Vec3{math.Sqrt2 / 2, math.Sqrt2 / 2, 0}},
+		{"by the corner of a turned box", mgl32.Vec3{0.9, 0.9, 0}, BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, turned, false, mgl32.Vec3{}, mgl32.Vec3{}},
+		{"behind a plane", mgl32.Vec3{0, -0.5, 0}, PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}}, mgl32.QuatIdent(), true, mgl32.Vec3{0, 0.1, 0}, mgl32.Vec3{0, 1, 0}},
+		{"in front of a plane", mgl32.Vec3{0, 0.5, 0}, PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}}, mgl32.QuatIdent(), false, mgl32.Vec3{}, mgl32.Vec3{}},
+		{"beside a capsule", mgl32.Vec3{0.3, 0.8, 0}, capsule, mgl32.QuatIdent(), true, mgl32.Vec3{0.6, 0.8, 0}, mgl32.Vec3{1, 0, 0}},
+		{"over a capsule", mgl32.Vec3{0, 1.2, 0}, capsule, mgl32.QuatIdent(), true, mgl32.Vec3{0, 1.6, 0}, mgl32.Vec3{0, 1, 0}},
+		{"past a capsule", mgl32.Vec3{0, 1.7, 0}, capsule, mgl32.QuatIdent(), false, mgl32.Vec3{}, mgl32.Vec3{}},
+		{"on a tipped capsule", mgl32.Vec3{-0.8, 0.3, 0}, capsule, tipped, true, mgl32.Vec3{-0.8, 0.6, 0}, mgl32.Vec3{0, 1, 0}},
+		{"just over a mesh", mgl32.Vec3{0.5, 0.05, 0.2}, ground, mgl32.QuatIdent(), true, mgl32.Vec3{0.5, 0.1, 0.2}, mgl32.Vec3{0, 1, 0}},
+		{"just under a mesh", mgl32.Vec3{0.5, -0.05, 0.2}, ground, mgl32.QuatIdent(), true, mgl32.Vec3{0.5, -0.1, 0.2}, mgl32.Vec3{0, -1, 0}},
+		{"on a mesh", mgl32.Vec3{-0.5, 0, 0.2}, ground, mgl32.QuatIdent(), true, mgl32.Vec3{-0.5, 0.1, 0.2}, mgl32.Vec3{0, 1, 0}},
+		{"off the edge of a mesh", mgl32.Vec3{1.05, 0, 0}, ground, mgl32.QuatIdent(), true, mgl32.Vec3{1.1, 0, 0}, mgl32.Vec3{1, 0, 0}},
+		{"over a mesh", mgl32.Vec3{0, 0.5, 0}, ground, mgl32.QuatIdent(), false, mgl32.Vec3{}, mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		target, normal, hit := particlePenetration(test.p, 0.1, test.collider, mgl32.Vec3{}, test.rotation)
+		if hit != test.hit {
+			t.Errorf("%s: hit = %v, want %v", test.name, hit, test.hit)
+			continue
+		}
+		if hit && (target.Sub(test.target).Len() > 1e-4 || normal.Sub(test.normal).Len() > 1e-4) {
+			t.Errorf("%s: pushed to %v along %v, want %v along %v", test.name, target, normal, test.target, test.normal)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Particle is a point with no collider of its own, that lives for Lifetime
+// seconds
+type Particle struct {
+	Position mgl32.Vec3
+	// Velocity is per frame, like RigidBody.Velocity
+	Velocity mgl32.Vec3
+	Age      float32
+	Lifetime float32
+	// Emitter the particle came from, which it takes its size and colour from
+	Emitter *ParticleEmitter
+}
+
+// ParticleEmitter spawns particles into a ParticleSystem, in a cone around
+// Direction
+type ParticleEmitter struct {
+	Position  mgl32.Vec3
+	Direction mgl32.Vec3
+	// ConeAngle is the largest angle from Direction particles leave at, in
+	// radians
+	ConeAngle float32
+	// Particles leave at a random speed between MinSpeed and MaxSpeed, per
+	// frame like Velocity
+	MinSpeed float32
+	MaxSpeed float32
+	// Rate is the number of particles spawned every second, 0 only spawns
+	// them with Burst
+	Rate float32
+	// Particles live for a random time between MinLifetime and MaxLifetime,
+	// in seconds
+	MinLifetime float32
+	MaxLifetime float32
+
+	// Particles fade from StartColor to EndColor, and change from StartSize
+	// to EndSize, over their life
+	StartColor mgl32.Vec4
+	EndColor   mgl32.Vec4
+	StartSize  float32
+	EndSize    float32
+
+	pending float32
+}
+
+// NewParticleEmitter creates a ParticleEmitter with appropriate defaults
+func NewParticleEmitter(position, direction mgl32.Vec3) *ParticleEmitter {
+	return &ParticleEmitter{
+		Position:    position,
+		Direction:   direction,
+		ConeAngle:   mgl32.DegToRad(15.0),
+		MinSpeed:    0.2,
+		MaxSpeed:    0.4,
+		Rate:        50.0,
+		MinLifetime: 1.0,
+		MaxLifetime: 2.0,
+		StartColor:  mgl32.Vec4{1.0, 0.9, 0.4, 1.0},
+		EndColor:    mgl32.Vec4{0.6, 0.1, 0.0, 1.0},
+		StartSize:   0.3,
+		EndSize:     0.1,
+	}
+}
+
+// minParticleLifetime is the shortest time a particle can live for, in seconds
+const minParticleLifetime = 0.001
+
+// spawn creates a particle with a random speed, direction and lifetime
+func (emitter *ParticleEmitter) spawn() Particle {
+	dir := emitter.Direction
+	if dir.Len() == 0.0 {
+		dir = mgl32.Vec3{0, 1, 0}
+	}
+	dir = dir.Normalize()
+
+	side := dir.Cross(mgl32.Vec3{0, 1, 0})
+	if side.Len() < 0.1 {
+		side = dir.Cross(mgl32.Vec3{1, 0, 0})
+	}
+	side = side.Normalize()
+	up := side.Cross(dir)
+
+	// Pick evenly over the cap of a sphere within the cone
+	cosAngle := 1.0 - rand.Float64()*(1.0-math.Cos(float64(emitter.ConeAngle)))
+	sinAngle := math.Sqrt(1.0 - cosAngle*cosAngle)
+	around := rand.Float64() * 2.0 * math.Pi
+	dir = dir.Mul(float32(cosAngle)).
+		Add(side.Mul(float32(sinAngle * math.Cos(around)))).
+		Add(up.Mul(float32(sinAngle * math.Sin(around))))
+
+	speed := emitter.MinSpeed + rand.Float32()*(emitter.MaxSpeed-emitter.MinSpeed)
+	lifetime := emitter.MinLifetime + rand.Float32()*(emitter.MaxLifetime-emitter.MinLifetime)
+	return Particle{
+		Position: emitter.Position,
+		Velocity: dir.Mul(speed),
+		// Particles live for at least a moment, so their age can be divided by
+		// their lifetime
+		Lifetime: float32(math.Max(float64(lifetime), minParticleLifetime)),
+		Emitter:  emitter,
+	}
+}
+
+// ParticleSphere is a sphere particles bounce off
+type ParticleSphere struct {
+	Center mgl32.Vec3
+	Radius float32
+}
+
+// ParticleSystem moves lots of short lived particles, for effects like sparks
+// and smoke. Particles don't collide with each other or with RigidBodies,
+// only with the Planes and Spheres given, and are all drawn in one call
+type ParticleSystem struct {
+	Emitters  []*ParticleEmitter
+	Particles []Particle
+	// MaxParticles stops emitters spawning more
+	MaxParticles int
+
+	Gravity mgl32.Vec3
+	// Damping is air resistance, in the same way as RigidBody.LinearDamping
+	Damping float32
+
+	// Planes and Spheres particles bounce off, in world space
+	Planes  []PlaneCollider
+	Spheres []ParticleSphere
+	// Restitution is how much of a particle's speed into a surface it keeps
+	// when it bounces, and Friction how much of its sliding speed it loses
+	Restitution float32
+	Friction    float32
+
+	// Model is drawn for every particle, scaled to its size
+	Model *Model
+
+	mesh    instancedMesh
+	offsets []mgl32.Vec4
+	colors  []mgl32.Vec4
+}
+
+// NewParticleSystem creates an empty ParticleSystem with appropriate defaults
+func NewParticleSystem(maxParticles int) *ParticleSystem {
+	return &ParticleSystem{
+		Emitters:     []*ParticleEmitter{},
+		Particles:    make([]Particle, 0, maxParticles),
+		MaxParticles: maxParticles,
+		Gravity:      mgl32.Vec3{0, -9.81, 0},
+		Damping:      0.1,
+		Planes:       []PlaneCollider{},
+		Spheres:      []ParticleSphere{},
+		Restitution:  0.5,
+		Friction:     0.2,
+	}
+}
+
+// Cleanup frees up resources
+func (ps *ParticleSystem) Cleanup() {
+	ps.mesh.Cleanup()
+}
+
+// AddEmitter adds an emitter, which spawns particles every Update
+func (ps *ParticleSystem) AddEmitter(emitter *ParticleEmitter) {
+	ps.Emitters = append(ps.Emitters, emitter)
+}
+
+// RemoveEmitter stops an emitter spawning particles, the ones it already
+// spawned carry on
+func (ps *ParticleSystem) RemoveEmitter(emitter *ParticleEmitter) {
+	for i := range ps.Emitters {
+		if ps.Emitters[i] == emitter {
+			ps.Emitters = append(ps.Emitters[:i], ps.Emitters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Burst spawns count particles from emitter at once, which doesn't need to
+// have been added to the system
+func (ps *ParticleSystem) Burst(emitter *ParticleEmitter, count int) {
+	for i := 0; i < count && len(ps.Particles) < ps.MaxParticles; i++ {
+		ps.Particles = append(ps.Particles, emitter.spawn())
+	}
+}
+
+// Update spawns new particles, removes dead ones and moves the rest forward
+func (ps *ParticleSystem) Update(delta, elapsed float32) {
+	for _, emitter := range ps.Emitters {
+		emitter.pending += emitter.Rate * elapsed
+		count := int(emitter.pending)
+		emitter.pending -= float32(count)
+		ps.Burst(emitter, count)
+	}
+
+	keep := 1.0 / (1.0 + elapsed*ps.Damping)
+	for i := 0; i < len(ps.Particles); i++ {
+		p := &ps.Particles[i]
+		p.Age += elapsed
+		if p.Age >= p.Lifetime {
+			// Order doesn't matter, so fill the gap with the last particle
+			last := len(ps.Particles) - 1
+			ps.Particles[i] = ps.Particles[last]
+			ps.Particles = ps.Particles[:last]
+			i--
+			continue
+		}
+
+		p.Velocity = p.Velocity.Add(ps.Gravity.Mul(elapsed)).Mul(keep)
+		p.Position = p.Position.Add(p.Velocity.Mul(delta))
+		ps.collide(p)
+	}
+}
+
+// collide pushes a particle out of every plane and sphere, and bounces it off
+func (ps *ParticleSystem) collide(p *Particle) {
+	for _, plane := range ps.Planes {
+		normal := plane.Normal.Normalize()
+		if depth := plane.Offset - normal.Dot(p.Position); depth > 0.0 {
+			ps.bounce(p, normal, depth)
+		}
+	}
+	for _, sphere := range ps.Spheres {
+		d := p.Position.Sub(sphere.Center)
+		dist := d.Len()
+		if dist < sphere.Radius && dist > 0.0 {
+			ps.bounce(p, d.Mul(1.0/dist), sphere.Radius-dist)
+		}
+	}
+}
+
+func (ps *ParticleSystem) bounce(p *Particle, normal mgl32.Vec3, depth float32) {
+	p.Position = p.Position.Add(normal.Mul(depth))
+
+	speed := p.Velocity.Dot(normal)
+	if speed >= 0.0 {
+		return
+	}
+	sliding := p.Velocity.Sub(normal.Mul(speed)).Mul(1.0 - ps.Friction)
+	p.Velocity = sliding.Sub(normal.Mul(speed * ps.Restitution))
+}
+
+// Render draws Model for every particle, with the instanced shader
+func (ps *ParticleSystem) Render(shader *Shader) {
+	if ps.Model == nil {
+		return
+	}
+
+	ps.offsets = ps.offsets[:0]
+	ps.colors = ps.colors[:0]
+	for _, p := range ps.Particles {
+		t := p.Age / p.Lifetime
+		emitter := p.Emitter
+		size := emitter.StartSize + (emitter.EndSize-emitter.StartSize)*t
+		color := emitter.StartColor.Add(emitter.EndColor.Sub(emitter.StartColor).Mul(t))
+		ps.offsets = append(ps.offsets, p.Position.Vec4(size))
+		ps.colors = append(ps.colors, color)
+	}
+	ps.mesh.Render(shader, ps.Model, ps.offsets, ps.colors)
+}
+
+func (w *World) AddParticleSystem(ps *ParticleSystem) {
+	w.ParticleSystems = append(w.ParticleSystems, ps)
+}
+
+func (w *World) RemoveParticleSystem(ps *ParticleSystem) {
+	for i := range w.ParticleSystems {
+		if w.ParticleSystems[i] == ps {
+			w.ParticleSystems = append(w.ParticleSystems[:i], w.ParticleSystems[i+1:]...)
+			return
+		}
+	}
+}
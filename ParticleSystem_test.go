@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestParticleEmitterSpawn(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction mgl32.Vec3
+		cone      float32
+		lifetime  float32
+		want      mgl32.Vec3
+	}{
+		{"straight", mgl32.Vec3{1, 0, 0}, 0, 1, mgl32.Vec3{1, 0, 0}},
+		{"cone", mgl32.Vec3{0, 0, -2}, mgl32.DegToRad(30), 1, mgl32.Vec3{0, 0, -1}},
+		{"wide cone", mgl32.Vec3{0, 1, 0}, mgl32.DegToRad(170), 1, mgl32.Vec3{0, 1, 0}},
+		{"no direction", mgl32.Vec3{}, 0, 1, mgl32.Vec3{0, 1, 0}},
+		{"no lifetime", mgl32.Vec3{0, 1, 0}, 0, 0, mgl32.Vec3{0, 1, 0}},
+	}
+	for _, test := range tests {
+		emitter := NewParticleEmitter(mgl32.Vec3{1, 2, 3}, test.direction)
+		emitter.ConeAngle = test.cone
+		emitter.MinLifetime = test.lifetime
+		emitter.MaxLifetime = test.lifetime
+
+		for i := 0; i < 100; i++ {
+			p := emitter.spawn()
+			speed := p.Velocity.Len()
+			if speed < emitter.MinSpeed-1e-5 || speed > emitter.MaxSpeed+1e-5 {
+				t.Fatalf("%s: particle has speed %v, want between %v and %v", test.name, speed, emitter.MinSpeed, emitter.MaxSpeed)
+			}
+			angle := math.Acos(math.Min(float64(p.Velocity.Mul(1/speed).Dot(test.want)), 1))
+			if angle > float64(test.cone)+1e-3 {
+				t.Fatalf("%s: particle leaves %v radians from its direction, want within %v", test.name, angle, test.cone)
+			}
+			if p.Lifetime < minParticleLifetime || p.Position != emitter.Position || p.Emitter != emitter {
+				t.Fatalf("%s: particle spawned as %+v", test.name, p)
+			}
+		}
+	}
+}
+
+func TestParticleSystemUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		rate         float32
+		lifetime     float32
+		maxParticles int
+		frames       int
+		want         int
+	}{
+		{"one second", 60, 10, 1000, 60, 60},
+		{"half a particle a frame", 30, 10, 1000, 60, 30},
+		{"full", 60, 10, 25, 60, 25},
+		{"dying as fast as they spawn", 60, 0.5, 1000, 120, 30},
+	}
+	for _, test := range tests {
+		ps := NewParticleSystem(test.maxParticles)
+		emitter := NewParticleEmitter(mgl32.Vec3{}, mgl32.Vec3{0, 1, 0})
+		emitter.Rate = test.rate
+		emitter.MinLifetime = test.lifetime
+		emitter.MaxLifetime = test.lifetime
+		ps.AddEmitter(emitter)
+		for i := 0; i < test.frames; i++ {
+			ps.Update(1.0, 1.0/60.0)
+		}
+
+		if len(ps.Particles) < test.want-1 || len(ps.Particles) > test.want {
+			t.Errorf("%s: %d particles alive, want %d", test.name, len(ps.Particles), test.want)
+		}
+	}
+}
+
+func TestParticleSystemBounces(t *testing.T) {
+	ps := NewParticleSystem(100)
+	ps.Planes = append(ps.Planes, PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}})
+	ps.Spheres = append(ps.Spheres, ParticleSphere{Center: mgl32.Vec3{5, 0, 0}, Radius: 2})
+	emitter := NewParticleEmitter(mgl32.Vec3{0, 5, 0}, mgl32.Vec3{1, 0, 0})
+	emitter.MinLifetime = 10
+	emitter.MaxLifetime = 10
+	ps.Burst(emitter, 50)
+
+	for i := 0; i < 300; i++ {
+		ps.Update(1.0, 1.0/60.0)
+		for _, p := range ps.Particles {
+			if p.Position.Y() < -1e-4 {
+				t.Fatalf("particle fell through the floor to %v", p.Position)
+			}
+			if p.Position.Sub(ps.Spheres[0].Center).Len() < ps.Spheres[0].Radius-1e-4 {
+				t.Fatalf("particle is inside the sphere at %v", p.Position)
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// RaycastHit describes where a ray hit a RigidBody
+type RaycastHit struct {
+	Body     *RigidBody
+	Point    mgl32.Vec3
+	Normal   mgl32.Vec3
+	Distance float32
+}
+
+// Raycast finds the first body hit by a ray, only considering bodies in one
+// of the layers in layerMask. Triggers, and bodies the ray starts inside of,
+// are ignored
+func (w *World) Raycast(origin, direction mgl32.Vec3, maxDist float32, layerMask uint32) (RaycastHit, bool) {
+	closest := RaycastHit{}
+	found := false
+
+	w.raycast(origin, direction, maxDist, layerMask, func(hit RaycastHit) float32 {
+		closest = hit
+		found = true
+		return hit.Distance
+	})
+
+	return closest, found
+}
+
+// RaycastAll finds every body hit by a ray, sorted by distance
+func (w *World) RaycastAll(origin, direction mgl32.Vec3, maxDist float32, layerMask uint32) []RaycastHit {
+	hits := []RaycastHit{}
+
+	w.raycast(origin, direction, maxDist, layerMask, func(hit RaycastHit) float32 {
+		hits = append(hits, hit)
+		return maxDist
+	})
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Distance < hits[j].Distance
+	})
+	return hits
+}
+
+// raycast calls fn with each hit, fn returns the distance to keep searching up
+// to
+func (w *World) raycast(origin, direction mgl32.Vec3, maxDist float32, layerMask uint32, fn func(hit RaycastHit) float32) {
+	if direction.Len() == 0.0 {
+		return
+	}
+	direction = direction.Normalize()
+
+	w.updateBroadphase()
+	w.broadphase.QueryRay(origin, direction, maxDist, func(rb *RigidBody, index int) float32 {
+		if rb.IsTrigger || layerMask&(1<<rb.Layer) == 0 {
+			return maxDist
+		}
+
+		dist, normal, hit := rb.Raycast(origin, direction, maxDist)
+		if !hit {
+			return maxDist
+		}
+
+		maxDist = fn(RaycastHit{
+			Body:     rb,
+			Point:    origin.Add(direction.Mul(dist)),
+			Normal:   normal,
+			Distance: dist,
+		})
+		return maxDist
+	})
+}
+
+// Raycast tests a ray against the Collider, direction must be normalized
+func (rb *RigidBody) Raycast(origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	transform := &rb.Parent.Transform
+	return raycastCollider(rb.Collider, transform.Position, transform.Rotation, origin, direction, maxDist)
+}
+
+func raycastCollider(col Collider, pos mgl32.Vec3, rot mgl32.Quat, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	switch col := col.(type) {
+	case SphereCollider:
+		return raycastSphere(pos, col.Radius, origin, direction, maxDist)
+	case BoxCollider:
+		// Cast in the box's own space, where it is axis aligned
+		inv := rot.Conjugate()
+		dist, normal, hit := raycastBox(NewAABB(mgl32.Vec3{}, col.Size), inv.Rotate(origin.Sub(pos)), inv.Rotate(direction), maxDist)
+		return dist, rot.Rotate(normal), hit
+	case CapsuleCollider:
+		return raycastCapsule(col, pos, rot, origin, direction, maxDist)
+	case ConvexHullCollider:
+		return raycastHull(col, pos, rot, origin, direction, maxDist)
+	case PlaneCollider:
+		return raycastPlane(col, pos, rot, origin, direction, maxDist)
+	case MeshCollider:
+		return raycastMesh(col, pos, rot, origin, direction, maxDist)
+	case CompoundCollider:
+		dist := maxDist
+		normal := mgl32.Vec3{}
+		found := false
+		for _, child := range col.Children {
+			childPos := pos.Add(rot.Rotate(child.Offset))
+			if d, n, hit := raycastCollider(child.Collider, childPos, rot, origin, direction, dist); hit {
+				dist = d
+				normal = n
+				found = true
+			}
+		}
+		return dist, normal, found
+	}
+	return 0.0, mgl32.Vec3{}, false
+}
+
+func raycastPlane(plane PlaneCollider, pos mgl32.Vec3, rot mgl32.Quat, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	normal := rot.Rotate(plane.Normal).Normalize()
+	dist := normal.Dot(origin.Sub(pos)) - plane.Offset
+	denom := normal.Dot(direction)
+
+	// Starting behind the plane, or in front and pointing away
+	if dist <= 0.0 || denom >= 0.0 {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	t := -dist / denom
+	if t > maxDist {
+		return 0.0, mgl32.Vec3{}, false
+	}
+	return t, normal, true
+}
+
+func raycastSphere(center mgl32.Vec3, radius float32, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	m := origin.Sub(center)
+	b := m.Dot(direction)
+	c := m.Dot(m) - radius*radius
+
+	// Starting inside, or outside and pointing away
+	if c <= 0.0 || b > 0.0 {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	disc := b*b - c
+	if disc < 0.0 {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	t := -b - float32(math.Sqrt(float64(disc)))
+	if t > maxDist {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	normal := m.Add(direction.Mul(t)).Mul(1.0 / radius)
+	return t, normal, true
+}
+
+func raycastCapsule(capsule CapsuleCollider, pos mgl32.Vec3, rot mgl32.Quat, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	inv := rot.Conjugate()
+	o := inv.Rotate(origin.Sub(pos))
+	d := inv.Rotate(direction)
+	r, h := capsule.Radius, capsule.HalfHeight
+
+	// Starting inside
+	closest := mgl32.Vec3{0, mgl32.Clamp(o.Y(), -h, h), 0}
+	if o.Sub(closest).Len() <= r {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	dist := maxDist
+	normal := mgl32.Vec3{}
+	found := false
+
+	// The side, which is only hit between the two ends
+	a := d.X()*d.X() + d.Z()*d.Z()
+	b := o.X()*d.X() + o.Z()*d.Z()
+	c := o.X()*o.X() + o.Z()*o.Z() - r*r
+	if disc := b*b - a*c; a > 0.0 && disc >= 0.0 {
+		t := (-b - float32(math.Sqrt(float64(disc)))) / a
+		p := o.Add(d.Mul(t))
+		if t >= 0.0 && t <= dist && p.Y() >= -h && p.Y() <= h {
+			dist = t
+			normal = mgl32.Vec3{p.X(), 0, p.Z()}.Mul(1.0 / r)
+			found = true
+		}
+	}
+
+	// The round ends
+	for _, end := range []float32{-h, h} {
+		if t, n, hit := raycastSphere(mgl32.Vec3{0, end, 0}, r, o, d, dist); hit {
+			dist = t
+			normal = n
+			found = true
+		}
+	}
+
+	if !found {
+		return 0.0, mgl32.Vec3{}, false
+	}
+	return dist, rot.Rotate(normal), true
+}
+
+func raycastBox(box AABB, origin, direction mgl32.Vec3, maxDist float32) (float32, mgl32.Vec3, bool) {
+	if box.Contains(origin) {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	t, hit := box.IntersectRay(origin, direction, maxDist)
+	if !hit {
+		return 0.0, mgl32.Vec3{}, false
+	}
+
+	// The face that was hit is the one the point is furthest out towards
+	center := box.Center()
+	size := box.Size()
+	local := origin.Add(direction.Mul(t)).Sub(center)
+	axis := 0
+	best := float32(-1.0)
+	for i := 0; i < 3; i++ {
+		d := float32(math.Abs(float64(local[i])))
+		if size[i] > 0.0 {
+			d /= size[i]
+		} else {
+			d = math.MaxFloat32
+		}
+		if d > best {
+			best = d
+			axis = i
+		}
+	}
+
+	// The ray started outside, so it went in through the face facing it.
+	// Going by the hit point picks the wrong side of a flat box, like a floor
+	normal := mgl32.Vec3{}
+	normal[axis] = 1.0
+	if direction[axis] > 0.0 {
+		normal[axis] = -1.0
+	}
+	return t, normal, true
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestRaycastCollider(t *testing.T) {
+	down := mgl32.Vec3{0, -1, 0}
+	up := mgl32.Vec3{0, 1, 0}
+	tests := []struct {
+		name     string
+		collider Collider
+		rotation mgl32.Quat
+		origin   mgl32.Vec3
+		hit      bool
+		dist     float32
+		normal   mgl32.Vec3
+	}{
+		{"sphere", SphereCollider{Radius: 1}, mgl32.QuatIdent(), mgl32.Vec3{0, 5, 0}, true, 4, up},
+		{"sphere missed", SphereCollider{Radius: 1}, mgl32.QuatIdent(), mgl32.Vec3{2, 5, 0}, false, 0, mgl32.Vec3{}},
+		{"box", BoxCollider{Size: mgl32.Vec3{1, 2, 1}}, mgl32.QuatIdent(), mgl32.Vec3{0, 5, 0}, true, 3, up},
+		{"box turned on its side", BoxCollider{Size: mgl32.Vec3{1, 2, 1}}, mgl32.QuatRotate(math.Pi/2, mgl32.Vec3{0, 0, 1}), mgl32.Vec3{0, 5, 0}, true, 4, up},
+		{"box turned 45 degrees", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.QuatRotate(math.Pi/4, mgl32.Vec3{0, 0, 1}), mgl32.Vec3{0, 5, 0}, true, 5 - math.Sqrt2, mgl32.Vec3{}},
+		{"box missed", BoxCollider{Size: mgl32.Vec3{1, 1, 1}}, mgl32.QuatIdent(), mgl32.Vec3{1.5, 5, 0}, false, 0, mgl32.Vec3{}},
+		{"capsule", CapsuleCollider{Radius: 0.5, HalfHeight: 1}, mgl32.QuatIdent(), mgl32.Vec3{0, 5, 0}, true, 3.5, up},
+		{"plane", PlaneCollider{Normal: up, Offset: -1}, mgl32.QuatIdent(), mgl32.Vec3{3, 5, 3}, true, 6, up},
+		{"out of range", SphereCollider{Radius: 1}, mgl32.QuatIdent(), mgl32.Vec3{0, 20, 0}, false, 0, mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dist, normal, hit := raycastCollider(test.collider, mgl32.Vec3{}, test.rotation, test.origin, down, 10)
+			if hit != test.hit {
+				t.Fatalf("hit = %v, want %v", hit, test.hit)
+			}
+			if !hit {
+				return
+			}
+			if math.Abs(float64(dist-test.dist)) > 1e-4 {
+				t.Errorf("distance = %v, want %v", dist, test.dist)
+			}
+			if test.normal != (mgl32.Vec3{}) && normal.Sub(test.normal).Len() > 1e-4 {
+				t.Errorf("normal = %v, want %v", normal, test.normal)
+			}
+		})
+	}
+}
+
+func TestWorldRaycast(t *testing.T) {
+	w := NewWorld()
+	addSphere := func(y float32, layer uint8, trigger bool) *Actor {
+		actor := NewActor()
+		actor.Transform.Position = mgl32.Vec3{0, y, 0}
+		actor.RigidBody.Collider = SphereCollider{Radius: 1}
+		actor.RigidBody.Layer = layer
+		actor.RigidBody.IsTrigger = trigger
+		w.AddActor(actor)
+		return actor
+	}
+	far := addSphere(0, 0, false)
+	near := addSphere(5, 1, false)
+	addSphere(10, 0, true)
+
+	origin := mgl32.Vec3{0, 20, 0}
+	down := mgl32.Vec3{0, -1, 0}
+	tests := []struct {
+		name      string
+		layerMask uint32
+		want      *Actor
+	}{
+		{"closest", AllLayers, near},
+		{"skips masked layer", LayerMask(0), far},
+		{"nothing in layer", LayerMask(2), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hit, found := w.Raycast(origin, down, 100, test.layerMask)
+			if test.want == nil {
+				if found {
+					t.Errorf("hit a body at %v", hit.Point)
+				}
+				return
+			}
+			if !found || hit.Body != test.want.RigidBody {
+				t.Fatalf("found = %v, hit body at %v", found, hit.Point)
+			}
+			wantDist := origin.Y() - test.want.Transform.Position.Y() - 1
+			if math.Abs(float64(hit.Distance-wantDist)) > 1e-4 {
+				t.Errorf("distance = %v, want %v", hit.Distance, wantDist)
+			}
+		})
+	}
+
+	hits := w.RaycastAll(origin, down, 100, AllLayers)
+	if len(hits) != 2 || hits[0].Body != near.RigidBody || hits[1].Body != far.RigidBody {
+		t.Errorf("RaycastAll got %d hits, want the near then the far sphere", len(hits))
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"math"
+
 	"github.com/go-gl/mathgl/mgl32"
 )
 
@@ -18,6 +20,18 @@ const (
 	VelocityChange = iota
 )
 
+// AllLayers is a layer mask that includes every layer
+const AllLayers = ^uint32(0)
+
+// LayerMask returns a mask containing each of the given layers
+func LayerMask(layers ...uint8) uint32 {
+	mask := uint32(0)
+	for _, layer := range layers {
+		mask |= 1 << layer
+	}
+	return mask
+}
+
 type Collider interface {
 }
 
@@ -25,34 +39,232 @@ type SphereCollider struct {
 	Radius float32
 }
 
+// CapsuleCollider is a line segment along the local Y axis grown by Radius,
+// so it is HalfHeight+Radius tall above and below its center. It rotates with
+// the body
+type CapsuleCollider struct {
+	Radius     float32
+	HalfHeight float32
+}
+
+// BoxCollider is a box, Size is the half-extent along each axis so it matches
+// assets/cube.obj scaled by Size. It rotates with the body
 type BoxCollider struct {
 	Size mgl32.Vec3
 }
 
+// PlaneCollider is an infinite plane, with everything behind it solid. The
+// plane is Offset along Normal from the body's position, and Normal rotates
+// with the body. Planes can't move, so give the body an infinite Mass
+type PlaneCollider struct {
+	Normal mgl32.Vec3
+	Offset float32
+}
+
+// CompoundCollider is made up of several colliders, each offset from the
+// body's position
+type CompoundCollider struct {
+	Children []ChildCollider
+}
+
+type ChildCollider struct {
+	Offset   mgl32.Vec3
+	Collider Collider
+}
+
 // RigidBody is a physics body implemented with Rigid Body dynamics
 type RigidBody struct {
-	Parent       *Actor
-	Collider     Collider
-	Mass         float32
+	Parent   *Actor
+	Collider Collider
+	// IsTrigger makes the Collider only report overlaps, without pushing
+	// anything out of the way
+	IsTrigger bool
+	// Layer is the layer the body is in, between 0 and 31, and CollisionMask
+	// has a bit set for every layer it collides with
+	Layer         uint8
+	CollisionMask uint32
+	Mass          float32
+	// CenterOfMass is in local space, and Inertia is the inertia tensor about
+	// it. An empty Inertia is worked out from the Collider and Mass
+	CenterOfMass mgl32.Vec3
+	Inertia      mgl32.Mat3
+	Restitution  float32
+	// Friction is how hard the body grips what it touches, as a fraction of
+	// how hard they are pushed together
+	Friction     float32
 	Velocity     mgl32.Vec3
 	Acceleration mgl32.Vec3
+	// AngularVelocity is the axis of rotation, scaled by radians per frame
+	AngularVelocity     mgl32.Vec3
+	AngularAcceleration mgl32.Vec3
+	// LinearDamping and AngularDamping slow the body down, like air
+	// resistance, by about that fraction of its velocity every second
+	LinearDamping  float32
+	AngularDamping float32
+
+	// Called when this body starts touching, keeps touching, or stops
+	// touching another body
+	OnCollisionEnter CollisionFunc
+	OnCollisionStay  CollisionFunc
+	OnCollisionExit  CollisionFunc
+
+	// Called when this body starts or stops overlapping another body, where
+	// either of them is a trigger
+	OnTriggerEnter CollisionFunc
+	OnTriggerExit  CollisionFunc
+
+	ignored map[*RigidBody]bool
+
+	// The Mass that Inertia was computed for by SetDensity
+	inertiaMass float32
+
+	// Velocity that only moves the body, to push it out of overlap without
+	// giving it energy. It is used up at the end of every World.Update
+	pushVelocity        mgl32.Vec3
+	pushAngularVelocity mgl32.Vec3
 }
 
 // NewRigidBody creates a new RigidBody with appropriate defaults
 func NewRigidBody() *RigidBody {
 	return &RigidBody{
-		Parent:       nil,
-		Mass:         1.0,
-		Velocity:     mgl32.Vec3{0, 0, 0},
-		Acceleration: mgl32.Vec3{0, 0, 0},
+		Parent:        nil,
+		CollisionMask: AllLayers,
+		Mass:          1.0,
+		Restitution:   1.0,
+		Friction:      0.5,
+		Velocity:      mgl32.Vec3{0, 0, 0},
+		Acceleration:  mgl32.Vec3{0, 0, 0},
+
+		AngularVelocity:     mgl32.Vec3{0, 0, 0},
+		AngularAcceleration: mgl32.Vec3{0, 0, 0},
+
+		ignored: map[*RigidBody]bool{},
 	}
 }
 
 // Cleanup frees up resources
 func (rb *RigidBody) Cleanup() {
+	for other := range rb.ignored {
+		delete(other.ignored, rb)
+	}
+	rb.ignored = map[*RigidBody]bool{}
 	rb.Parent = nil
 }
 
+// IgnoreCollision stops, or resumes, collisions between two bodies
+// regardless of their layers
+func (rb *RigidBody) IgnoreCollision(other *RigidBody, ignore bool) {
+	if ignore {
+		rb.ignored[other] = true
+		other.ignored[rb] = true
+	} else {
+		delete(rb.ignored, other)
+		delete(other.ignored, rb)
+	}
+}
+
+// ShouldCollide checks the layers and ignore lists of both bodies, to see if
+// they should be tested for collision at all
+func (rb *RigidBody) ShouldCollide(other *RigidBody) bool {
+	if rb.ignored[other] {
+		return false
+	}
+	return rb.CollisionMask&(1<<other.Layer) != 0 &&
+		other.CollisionMask&(1<<rb.Layer) != 0
+}
+
+// Bounds returns the world space AABB of the Collider
+func (rb *RigidBody) Bounds() AABB {
+	return colliderBounds(rb.Collider, rb.Parent.Transform.Position, rb.Parent.Transform.Rotation)
+}
+
+func colliderBounds(col Collider, pos mgl32.Vec3, rot mgl32.Quat) AABB {
+	switch col := col.(type) {
+	case SphereCollider:
+		return NewAABB(pos, mgl32.Vec3{col.Radius, col.Radius, col.Radius})
+	case BoxCollider:
+		return transformBounds(AABB{Min: col.Size.Mul(-1.0), Max: col.Size}, rot, pos)
+	case CompoundCollider:
+		bounds := AABB{Min: pos, Max: pos}
+		for i, child := range col.Children {
+			childBounds := colliderBounds(child.Collider, pos.Add(rot.Rotate(child.Offset)), rot)
+			if i == 0 {
+				bounds = childBounds
+			} else {
+				bounds = bounds.Union(childBounds)
+			}
+		}
+		return bounds
+	case PlaneCollider:
+		return InfiniteAABB
+	case MeshCollider:
+		return transformBounds(col.Bounds, rot, pos)
+	}
+
+	if shape, ok := newConvexShape(col, pos, rot); ok {
+		bounds := AABB{}
+		for i := 0; i < 3; i++ {
+			axis := mgl32.Vec3{}
+			axis[i] = 1.0
+			bounds.Min[i] = shape.Support(axis.Mul(-1.0))[i] - shape.Margin
+			bounds.Max[i] = shape.Support(axis)[i] + shape.Margin
+		}
+		return bounds
+	}
+	return AABB{Min: pos, Max: pos}
+}
+
+// SetDensity works out Mass, CenterOfMass and Inertia from the volume of the
+// Collider. Setting Mass afterwards overrides it, and scales Inertia to match
+func (rb *RigidBody) SetDensity(density float32) {
+	props := ComputeMassProperties(rb.Collider, density)
+	rb.Mass = props.Mass
+	rb.CenterOfMass = props.CenterOfMass
+	rb.Inertia = props.Inertia
+	rb.inertiaMass = props.Mass
+}
+
+// WorldCenterOfMass returns CenterOfMass in world space
+func (rb *RigidBody) WorldCenterOfMass() mgl32.Vec3 {
+	transform := &rb.Parent.Transform
+	return transform.Position.Add(transform.Rotation.Rotate(rb.CenterOfMass))
+}
+
+// InverseInertia returns the inverse of the inertia tensor in world space, or
+// zero for immovable bodies
+func (rb *RigidBody) InverseInertia() mgl32.Mat3 {
+	if rb.InverseMass() == 0.0 {
+		return mgl32.Mat3{}
+	}
+
+	rot := rb.Parent.Transform.Rotation.Mat4().Mat3()
+	return rot.Mul3(rb.localInertia().Inv()).Mul3(rot.Transpose())
+}
+
+// localInertia returns the inertia tensor in local space, scaled to Mass
+func (rb *RigidBody) localInertia() mgl32.Mat3 {
+	inertia := rb.Inertia
+	if inertia == (mgl32.Mat3{}) {
+		// Use the shape of the Collider, at whatever the Mass is
+		props := ComputeMassProperties(rb.Collider, 1.0)
+		inertia = mgl32.Ident3().Mul(rb.Mass)
+		if props.Mass > 0.0 {
+			inertia = props.Inertia.Mul(rb.Mass / props.Mass)
+		}
+	} else if rb.inertiaMass > 0.0 {
+		inertia = inertia.Mul(rb.Mass / rb.inertiaMass)
+	}
+	return inertia
+}
+
+// InverseMass returns 1 / Mass, or 0 for immovable bodies
+func (rb *RigidBody) InverseMass() float32 {
+	if rb.Mass <= 0.0 || rb.Mass >= math.MaxFloat32 {
+		return 0.0
+	}
+	return 1.0 / rb.Mass
+}
+
 // ApplyForce adds a force to the object, how it is added depenends on the mode
 func (rb *RigidBody) ApplyForce(force mgl32.Vec3, mode ForceMode) {
 	switch mode {
@@ -69,6 +281,50 @@ func (rb *RigidBody) ApplyForce(force mgl32.Vec3, mode ForceMode) {
 	}
 }
 
+// ApplyTorque adds a torque to the object, how it is added depends on the mode
+// in the same way as ApplyForce
+func (rb *RigidBody) ApplyTorque(torque mgl32.Vec3, mode ForceMode) {
+	switch mode {
+	case ConstantForce:
+		torque = rb.InverseInertia().Mul3x1(torque)
+		rb.AngularAcceleration = rb.AngularAcceleration.Add(torque)
+	case Acceleration:
+		rb.AngularAcceleration = rb.AngularAcceleration.Add(torque)
+	case Impulse:
+		torque = rb.InverseInertia().Mul3x1(torque)
+		rb.AngularVelocity = rb.AngularVelocity.Add(torque)
+	case VelocityChange:
+		rb.AngularVelocity = rb.AngularVelocity.Add(torque)
+	}
+}
+
+// ApplyForceAtPosition adds a force at a point in world space, which also
+// spins the object if the point isn't its center of mass. Acceleration and
+// VelocityChange ignore mass, and so act as if applied at the center of mass
+func (rb *RigidBody) ApplyForceAtPosition(force, position mgl32.Vec3, mode ForceMode) {
+	rb.ApplyForce(force, mode)
+
+	switch mode {
+	case ConstantForce, Impulse:
+		arm := position.Sub(rb.WorldCenterOfMass())
+		rb.ApplyTorque(arm.Cross(force), mode)
+	}
+}
+
+// applyForceOverTime applies a continuous force for elapsed seconds, as an
+// instant change in velocity. Forces that persist between steps, like
+// gravity, can use ApplyForce instead
+func (rb *RigidBody) applyForceOverTime(force mgl32.Vec3, mode ForceMode, elapsed float32) {
+	switch mode {
+	case ConstantForce:
+		rb.ApplyForce(force.Mul(elapsed), Impulse)
+	case Acceleration:
+		rb.ApplyForce(force.Mul(elapsed), VelocityChange)
+	default:
+		rb.ApplyForce(force, mode)
+	}
+}
+
 /*
 var LowerBound = mgl32.Vec3{0, 0, 0}
 var UpperBound = mgl32.Vec3{100, 100, 100}
@@ -129,6 +385,13 @@ func (rb *RigidBody) Update(delta, elapsed float32) {
 	rb.Velocity = mgl32.Vec3{vx, vy, vz}
 	rb.Acceleration = mgl32.Vec3{ax, ay, az}
 
+	rb.displace(mgl32.Vec3{}, rb.AngularVelocity.Mul(delta))
+	rb.AngularVelocity = rb.AngularVelocity.Add(rb.AngularAcceleration.Mul(elapsed))
+
+	// Damping is applied implicitly, so large values can't reverse the velocity
+	rb.Velocity = rb.Velocity.Mul(1.0 / (1.0 + elapsed*rb.LinearDamping))
+	rb.AngularVelocity = rb.AngularVelocity.Mul(1.0 / (1.0 + elapsed*rb.AngularDamping))
+
 	// Min/Max Velocity
 	if rb.Velocity.Len() > 100.0 {
 		rb.Velocity = rb.Velocity.Normalize().Mul(100.0)
@@ -138,43 +401,41 @@ func (rb *RigidBody) Update(delta, elapsed float32) {
 	}
 }
 
-func (rb *RigidBody) CheckCollide(other *RigidBody) {
-	pos := rb.Parent.Transform.Position
-	otherPos := other.Parent.Transform.Position
-
-	switch rb.Collider.(type) {
-	case SphereCollider:
-		switch other.Collider.(type) {
-		case SphereCollider:
-			dist := DistanceSquared(pos, otherPos)
-			col := rb.Collider.(SphereCollider)
-			otherCol := other.Collider.(SphereCollider)
-			if dist < (col.Radius+otherCol.Radius)*(col.Radius+otherCol.Radius) {
-				rb.Collide(other)
-			}
-		}
-	case BoxCollider:
-
-	}
+// displace moves the body by offset, and turns it by turn radians about its
+// axis. It spins about the center of mass, rather than the origin
+func (rb *RigidBody) displace(offset, turn mgl32.Vec3) {
+	transform := &rb.Parent.Transform
+	center := rb.WorldCenterOfMass().Add(offset)
+	spin := mgl32.Quat{W: 0.0, V: turn.Mul(0.5)}
+	transform.Rotation = transform.Rotation.Add(spin.Mul(transform.Rotation)).Normalize()
+	transform.Position = center.Sub(transform.Rotation.Rotate(rb.CenterOfMass))
 }
 
-func (rb *RigidBody) Collide(other *RigidBody) {
-	diff := rb.Parent.Transform.Position.Add(other.Parent.Transform.Position.Mul(-1.0))
+// applyPush moves the body by what the solver pushed it with to fix overlap
+func (rb *RigidBody) applyPush(delta float32) {
+	rb.displace(rb.pushVelocity.Mul(delta), rb.pushAngularVelocity.Mul(delta))
+	rb.pushVelocity = mgl32.Vec3{}
+	rb.pushAngularVelocity = mgl32.Vec3{}
+}
 
-	x := diff.Normalize()
-	v1 := rb.Velocity
-	x1 := x.Dot(v1)
-	v1x := x.Mul(x1)
-	v1y := v1.Add(v1x.Mul(-1.0))
-	m1 := rb.Mass
+// CheckCollide tests for overlap with another RigidBody, and returns the
+// contacts between them, or nil if they aren't touching
+func (rb *RigidBody) CheckCollide(other *RigidBody) *ContactManifold {
+	a := &rb.Parent.Transform
+	b := &other.Parent.Transform
 
-	x = x.Mul(-1.0)
-	v2 := other.Velocity
-	x2 := x.Dot(v2)
-	v2x := x.Mul(x2)
-	v2y := v2.Add(v2x.Mul(-1.0))
-	m2 := other.Mass
+	contacts := collideColliders(rb.Collider, a.Position, a.Rotation, other.Collider, b.Position, b.Rotation)
+	if len(contacts) == 0 {
+		return nil
+	}
 
-	rb.Velocity = v1x.Mul((m1 - m2) / (m1 + m2)).Add(v2x.Mul((2 * m2) / (m1 + m2)).Add(v1y))
-	other.Velocity = v1x.Mul((2 * m1) / (m1 + m2)).Add(v2x.Mul((m2 - m1) / (m1 + m2)).Add(v2y))
+	m := &ContactManifold{A: rb, B: other, Contacts: contacts}
+	deepest := 0
+	for i := range contacts {
+		if contacts[i].Depth > contacts[deepest].Depth {
+			deepest = i
+		}
+	}
+	m.Normal = contacts[deepest].Normal
+	return m
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestLayerMask(t *testing.T) {
+	tests := []struct {
+		layers []uint8
+		want   uint32
+	}{
+		{nil, 0},
+		{[]uint8{0}, 1},
+		{[]uint8{1, 3}, 0xa},
+		{[]uint8{31}, 1 << 31},
+		{[]uint8{2, 2}, 4},
+	}
+	for _, test := range tests {
+		if mask := LayerMask(test.layers...); mask != test.want {
+			t.Errorf("LayerMask(%v) = %#x, want %#x", test.layers, mask, test.want)
+		}
+	}
+}
+
+func TestShouldCollide(t *testing.T) {
+	tests := []struct {
+		name           string
+		layerA, layerB uint8
+		maskA, maskB   uint32
+		ignored        bool
+		want           bool
+	}{
+		{"defaults", 0, 0, AllLayers, AllLayers, false, true},
+		{"ignored", 0, 0, AllLayers, AllLayers, true, false},
+		{"A masks out B", 0, 1, LayerMask(0), AllLayers, false, false},
+		{"B masks out A", 0, 1, AllLayers, LayerMask(1), false, false},
+		{"both include each other", 2, 5, LayerMask(5), LayerMask(2), false, true},
+		{"same layer masked out", 3, 3, ^LayerMask(3), ^LayerMask(3), false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := NewRigidBody(), NewRigidBody()
+			a.Layer, a.CollisionMask = test.layerA, test.maskA
+			b.Layer, b.CollisionMask = test.layerB, test.maskB
+			a.IgnoreCollision(b, test.ignored)
+
+			if got := a.ShouldCollide(b); got != test.want {
+				t.Errorf("a.ShouldCollide(b) = %v, want %v", got, test.want)
+			}
+			if got := b.ShouldCollide(a); got != test.want {
+				t.Errorf("b.ShouldCollide(a) = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDamping(t *testing.T) {
+	tests := []struct {
+		name    string
+		damping float32
+		frames  int
+		want    float32
+	}{
+		{"none", 0, 60, 10},
+		{"one frame", 6, 1, 10 / 1.1},
+		{"one second", 1, 60, float32(10 / math.Pow(1+1.0/60, 60))},
+		{"huge", 1e9, 1, 10 / (1 + 1e9/60.0)},
+	}
+	for _, test := range tests {
+		actor := NewActor()
+		rb := actor.RigidBody
+		rb.Velocity = mgl32.Vec3{10, 0, 0}
+		rb.AngularVelocity = mgl32.Vec3{0, 0, 10}
+		rb.LinearDamping = test.damping
+		rb.AngularDamping = test.damping
+		for i := 0; i < test.frames; i++ {
+			rb.Update(1.0, 1.0/60.0)
+		}
+
+		// Damping can stop a body, but never send it backwards
+		tolerance := math.Max(1e-4*float64(test.want), 1e-5)
+
+		if v := rb.Velocity.X(); math.Abs(float64(v-test.want)) > tolerance || v < 0 {
+			t.Errorf("%s: velocity = %v, want %v", test.name, v, test.want)
+		}
+		if v := rb.AngularVelocity.Z(); math.Abs(float64(v-test.want)) > tolerance || v < 0 {
+			t.Errorf("%s: angular velocity = %v, want %v", test.name, v, test.want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Number of vertices around each ring of the tube a Rope is drawn as
+const ropeSides = 6
+
+// RopeAnchor ties the end of a Rope to a RigidBody, or to a fixed point
+type RopeAnchor struct {
+	// Body the rope is tied to, or nil for a fixed point in the world
+	Body *RigidBody
+	// Point is in Body's local space, or world space without a Body
+	Point mgl32.Vec3
+
+	// Where the anchor started this Update, and the impulse solving has
+	// pushed it with since
+	point   mgl32.Vec3
+	impulse mgl32.Vec3
+	// Where solving has moved Body's center of mass and turned it to, which
+	// are only given to Body once solving is done
+	center     mgl32.Vec3
+	rotation   mgl32.Quat
+	invInertia mgl32.Mat3
+}
+
+// NewRopeAnchor creates an anchor at point in world space, which then moves
+// with body if it isn't nil
+func NewRopeAnchor(body *RigidBody, point mgl32.Vec3) *RopeAnchor {
+	if body == nil {
+		return &RopeAnchor{Point: point}
+	}
+	transform := &body.Parent.Transform
+	local := transform.Rotation.Conjugate().Rotate(point.Sub(transform.Position))
+	return &RopeAnchor{Body: body, Point: local}
+}
+
+// WorldPoint returns where the anchor is in world space
+func (anchor *RopeAnchor) WorldPoint() mgl32.Vec3 {
+	if anchor.Body == nil {
+		return anchor.Point
+	}
+	transform := &anchor.Body.Parent.Transform
+	return transform.Position.Add(transform.Rotation.Rotate(anchor.Point))
+}
+
+func (anchor *RopeAnchor) begin() {
+	anchor.point = anchor.WorldPoint()
+	anchor.impulse = mgl32.Vec3{}
+	if rb := anchor.Body; rb != nil {
+		anchor.center = rb.WorldCenterOfMass()
+		anchor.rotation = rb.Parent.Transform.Rotation
+		anchor.invInertia = rb.InverseInertia()
+	}
+}
+
+// movable returns true if solving can move the anchor
+func (anchor *RopeAnchor) movable() bool {
+	return anchor.Body != nil && anchor.Body.InverseMass() != 0.0
+}
+
+func (anchor *RopeAnchor) current() mgl32.Vec3 {
+	if !anchor.movable() {
+		return anchor.point
+	}
+	return anchor.center.Add(anchor.rotation.Rotate(anchor.Point.Sub(anchor.Body.CenterOfMass)))
+}
+
+// inverseMass returns how easily the anchor moves along dir, including the
+// body turning about its center of mass
+func (anchor *RopeAnchor) inverseMass(dir mgl32.Vec3) float32 {
+	if !anchor.movable() {
+		return 0.0
+	}
+
+	arm := anchor.current().Sub(anchor.center).Cross(dir)
+	return anchor.Body.InverseMass() + arm.Dot(anchor.invInertia.Mul3x1(arm))
+}
+
+// push moves and turns the body the anchor is tied to with an impulse at the
+// anchor. The body is turned properly rather than by a small angle, since
+// a rope tied to the edge of a body can turn it a long way in one Update
+func (anchor *RopeAnchor) push(impulse mgl32.Vec3) {
+	if !anchor.movable() {
+		return
+	}
+
+	turn := anchor.invInertia.Mul3x1(anchor.current().Sub(anchor.center).Cross(impulse))
+	anchor.center = anchor.center.Add(impulse.Mul(anchor.Body.InverseMass()))
+	if angle := turn.Len(); angle > 0.0 {
+		anchor.rotation = mgl32.QuatRotate(angle, turn.Mul(1.0/angle)).Mul(anchor.rotation).Normalize()
+	}
+	anchor.impulse = anchor.impulse.Add(impulse)
+}
+
+// apply moves the body the anchor is tied to where solving left it, and
+// gives it the velocity to match
+func (anchor *RopeAnchor) apply(delta float32) {
+	if !anchor.movable() || anchor.impulse.LenSqr() == 0.0 {
+		return
+	}
+
+	rb := anchor.Body
+	transform := &rb.Parent.Transform
+	transform.Rotation = anchor.rotation
+	transform.Position = anchor.center.Sub(anchor.rotation.Rotate(rb.CenterOfMass))
+
+	rb.ApplyForceAtPosition(anchor.impulse.Mul(1.0/delta), anchor.point, Impulse)
+}
+
+// Rope is a chain of particles kept SegmentLength apart, simulated with
+// position-based dynamics. Either end can be tied to a RigidBody, which the
+// rope pulls on, or to a fixed point. It is pushed out of any other RigidBody
+// colliders it touches, but doesn't push back on them
+type Rope struct {
+	Positions  []mgl32.Vec3
+	Velocities []mgl32.Vec3
+	// SegmentLength is the rest length between neighbouring particles
+	SegmentLength float32
+
+	// Start and End tie the first and last particle down, nil leaves them free
+	Start *RopeAnchor
+	End   *RopeAnchor
+
+	ParticleMass float32
+	// Stiffness of the rope, from 0 for elastic to 1 for unstretchable
+	Stiffness float32
+	// Iterations is how many times the constraints are solved every Update
+	Iterations int
+
+	Gravity mgl32.Vec3
+	// Damping is air resistance, in the same way as RigidBody.LinearDamping
+	Damping float32
+
+	// Thickness is the radius of the rope
+	Thickness float32
+	// Friction is how much of a particle's sliding velocity is lost when it
+	// is pushed out of a collider
+	Friction float32
+	// CollisionMask has a bit set for every layer the rope collides with
+	CollisionMask uint32
+
+	predicted []mgl32.Vec3
+
+	mesh      dynamicMesh
+	rings     []mgl32.Vec3
+	triangles [][3]int
+}
+
+// NewRope creates a straight rope from start to end, split into segments
+func NewRope(start, end mgl32.Vec3, segments int) (*Rope, error) {
+	if segments < 1 {
+		return nil, fmt.Errorf("Rope needs at least 1 segment, got %v", segments)
+	}
+
+	rope := &Rope{
+		Positions:     make([]mgl32.Vec3, segments+1),
+		Velocities:    make([]mgl32.Vec3, segments+1),
+		SegmentLength: end.Sub(start).Len() / float32(segments),
+
+		ParticleMass: 0.1,
+		Stiffness:    1.0,
+		Iterations:   20,
+
+		Gravity: mgl32.Vec3{0, -9.81, 0},
+		Damping: 0.1,
+
+		Thickness:     0.3,
+		Friction:      0.5,
+		CollisionMask: AllLayers,
+
+		predicted: make([]mgl32.Vec3, segments+1),
+	}
+
+	for i := range rope.Positions {
+		rope.Positions[i] = start.Add(end.Sub(start).Mul(float32(i) / float32(segments)))
+	}
+	return rope, nil
+}
+
+// Cleanup frees up resources
+func (rope *Rope) Cleanup() {
+	rope.mesh.Cleanup()
+}
+
+// Length returns the current length along the rope
+func (rope *Rope) Length() float32 {
+	length := float32(0.0)
+	for i := 0; i+1 < len(rope.Positions); i++ {
+		length += rope.Positions[i+1].Sub(rope.Positions[i]).Len()
+	}
+	return length
+}
+
+// Bounds returns the AABB of every particle
+func (rope *Rope) Bounds() AABB {
+	bounds := AABB{Min: rope.Positions[0], Max: rope.Positions[0]}
+	for _, p := range rope.Positions {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	return bounds
+}
+
+// Update moves the rope forward, colliding with the bodies in w, and pulling
+// on the bodies it is tied to
+func (rope *Rope) Update(w *World, delta, elapsed float32) {
+	if delta <= 0.0 {
+		return
+	}
+
+	for i := range rope.Positions {
+		v := rope.Velocities[i].Add(rope.Gravity.Mul(elapsed))
+		rope.Velocities[i] = v.Mul(1.0 / (1.0 + elapsed*rope.Damping))
+		rope.predicted[i] = rope.Positions[i].Add(rope.Velocities[i].Mul(delta))
+	}
+
+	// Anything the rope could reach this frame, apart from what it's tied to
+	bounds := rope.Bounds()
+	for _, p := range rope.predicted {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	thickness := mgl32.Vec3{rope.Thickness, rope.Thickness, rope.Thickness}
+	bodies := []*RigidBody{}
+	for _, rb := range w.particleColliders(AABB{Min: bounds.Min.Sub(thickness), Max: bounds.Max.Add(thickness)}, rope.CollisionMask) {
+		if (rope.Start == nil || rope.Start.Body != rb) && (rope.End == nil || rope.End.Body != rb) {
+			bodies = append(bodies, rb)
+		}
+	}
+
+	iterations := rope.Iterations
+	if iterations < 1 {
+		iterations = 1
+	}
+	stiffness := 1.0 - float32(math.Pow(float64(1.0-rope.Stiffness), 1.0/float64(iterations)))
+	invMass := 1.0 / rope.ParticleMass
+	last := len(rope.predicted) - 1
+
+	// The anchors are moved like particles while solving, then the bodies
+	// they are tied to are moved to match
+	if rope.Start != nil {
+		rope.Start.begin()
+	}
+	if rope.End != nil {
+		rope.End.begin()
+	}
+
+	for it := 0; it < iterations; it++ {
+		for i := 0; i < last; i++ {
+			rope.solveSegment(i, stiffness)
+		}
+
+		// Keep the anchors within the length of the rope of each other
+		// directly, so heavy bodies don't stretch it out
+		if rope.Start != nil && rope.End != nil {
+			rope.solveTether()
+		}
+
+		if rope.Start != nil {
+			rope.solveAnchor(0, rope.Start, invMass)
+		}
+		if rope.End != nil {
+			rope.solveAnchor(last, rope.End, invMass)
+		}
+
+		for i := range rope.predicted {
+			rope.predicted[i], _ = collideParticle(rope.Positions[i], rope.predicted[i], mgl32.Vec3{},
+				rope.Thickness, 0.0, bodies)
+		}
+	}
+
+	for i := range rope.Positions {
+		v := rope.predicted[i].Sub(rope.Positions[i]).Mul(1.0 / delta)
+		rope.Positions[i], rope.Velocities[i] = collideParticle(rope.Positions[i], rope.predicted[i], v,
+			rope.Thickness, rope.Friction, bodies)
+	}
+
+	if rope.Start != nil {
+		rope.Start.apply(delta)
+	}
+	if rope.End != nil {
+		rope.End.apply(delta)
+	}
+}
+
+func (rope *Rope) solveSegment(i int, stiffness float32) {
+	d := rope.predicted[i+1].Sub(rope.predicted[i])
+	length := d.Len()
+	if length == 0.0 {
+		return
+	}
+
+	correction := d.Mul((length - rope.SegmentLength) / (length * 2.0) * stiffness)
+	rope.predicted[i] = rope.predicted[i].Add(correction)
+	rope.predicted[i+1] = rope.predicted[i+1].Sub(correction)
+}
+
+// solveAnchor pulls particle i and an anchor together, sharing the correction
+// by how easily each of them moves
+func (rope *Rope) solveAnchor(i int, anchor *RopeAnchor, invMass float32) {
+	d := anchor.current().Sub(rope.predicted[i])
+	length := d.Len()
+	if length == 0.0 {
+		return
+	}
+
+	anchorInvMass := anchor.inverseMass(d.Mul(1.0 / length))
+	total := anchorInvMass + invMass
+	rope.predicted[i] = rope.predicted[i].Add(d.Mul(invMass / total))
+	anchor.push(d.Mul(-1.0 / total))
+}
+
+// solveTether pulls the two anchors back together if they are further apart
+// than the length of the rope
+func (rope *Rope) solveTether() {
+	d := rope.End.current().Sub(rope.Start.current())
+	length := d.Len()
+	stretch := length - rope.SegmentLength*float32(len(rope.Positions)-1)
+	if stretch <= 0.0 {
+		return
+	}
+
+	dir := d.Mul(1.0 / length)
+	startInvMass := rope.Start.inverseMass(dir)
+	endInvMass := rope.End.inverseMass(dir)
+	total := startInvMass + endInvMass
+	if total == 0.0 {
+		return
+	}
+
+	impulse := dir.Mul(stretch / total)
+	rope.Start.push(impulse)
+	rope.End.push(impulse.Mul(-1.0))
+}
+
+func (rope *Rope) Render(shader *Shader) {
+	count := len(rope.Positions)
+	if len(rope.triangles) == 0 {
+		for i := 0; i+1 < count; i++ {
+			for s := 0; s < ropeSides; s++ {
+				a := i*ropeSides + s
+				b := i*ropeSides + (s+1)%ropeSides
+				c := a + ropeSides
+				d := b + ropeSides
+				rope.triangles = append(rope.triangles, [3]int{a, b, c}, [3]int{b, d, c})
+			}
+		}
+		rope.rings = make([]mgl32.Vec3, count*ropeSides)
+	}
+
+	// Build a ring around each particle, facing along the rope
+	for i, p := range rope.Positions {
+		var tangent mgl32.Vec3
+		if i+1 < count {
+			tangent = rope.Positions[i+1].Sub(p)
+		} else {
+			tangent = p.Sub(rope.Positions[i-1])
+		}
+		if tangent.Len() == 0.0 {
+			tangent = mgl32.Vec3{0, 1, 0}
+		}
+		tangent = tangent.Normalize()
+
+		side := tangent.Cross(mgl32.Vec3{0, 1, 0})
+		if side.Len() < 0.1 {
+			side = tangent.Cross(mgl32.Vec3{1, 0, 0})
+		}
+		side = side.Normalize()
+		up := side.Cross(tangent)
+
+		for s := 0; s < ropeSides; s++ {
+			angle := float64(s) / ropeSides * 2.0 * math.Pi
+			offset := side.Mul(float32(math.Cos(angle))).Add(up.Mul(float32(math.Sin(angle))))
+			rope.rings[i*ropeSides+s] = p.Add(offset.Mul(rope.Thickness))
+		}
+	}
+
+	rope.mesh.Render(shader, rope.rings, rope.triangles)
+}
+
+func (w *World) AddRope(rope *Rope) {
+	w.Ropes = append(w.Ropes, rope)
+}
+
+func (w *World) RemoveRope(rope *Rope) {
+	for i := range w.Ropes {
+		if w.Ropes[i] == rope {
+			w.Ropes = append(w.Ropes[:i], w.Ropes[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewRope(t *testing.T) {
+	tests := []struct {
+		segments int
+		err      bool
+		length   float32
+	}{
+		{0, true, 0},
+		{1, false, 5},
+		{10, false, 0.5},
+	}
+	for _, test := range tests {
+		rope, err := NewRope(mgl32.Vec3{0, 10, 0}, mgl32.Vec3{3, 6, 0}, test.segments)
+		if (err != nil) != test.err {
+			t.Errorf("%d segments: err = %v, want an error: %v", test.segments, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(rope.Positions) != test.segments+1 {
+			t.Errorf("%d segments: got %d particles", test.segments, len(rope.Positions))
+		}
+		if math.Abs(float64(rope.SegmentLength-test.length)) > 1e-5 || math.Abs(float64(rope.Length()-5)) > 1e-4 {
+			t.Errorf("%d segments: segments are %v long, rope is %v, want %v and 5", test.segments, rope.SegmentLength, rope.Length(), test.length)
+		}
+	}
+}
+
+func TestRopeAnchorWorldPoint(t *testing.T) {
+	actor := NewActor()
+	actor.Transform.Position = mgl32.Vec3{1, 2, 3}
+	actor.Transform.Rotation = mgl32.QuatRotate(1.0, mgl32.Vec3{0, 1, 0})
+	point := mgl32.Vec3{2, 3, 4}
+
+	anchor := NewRopeAnchor(actor.RigidBody, point)
+	if p := anchor.WorldPoint(); p.Sub(point).Len() > 1e-5 {
+		t.Errorf("anchor starts at %v, want %v", p, point)
+	}
+
+	// It moves with the body
+	actor.Transform.Position = actor.Transform.Position.Add(mgl32.Vec3{0, 5, 0})
+	if p := anchor.WorldPoint(); p.Sub(point.Add(mgl32.Vec3{0, 5, 0})).Len() > 1e-5 {
+		t.Errorf("anchor didn't follow the body, it is at %v", p)
+	}
+
+	fixed := NewRopeAnchor(nil, point)
+	if fixed.WorldPoint() != point {
+		t.Errorf("fixed anchor is at %v, want %v", fixed.WorldPoint(), point)
+	}
+}
+
+func TestRopeHoldsBody(t *testing.T) {
+	w := NewWorld()
+	ball := NewActor()
+	ball.Transform.Position = mgl32.Vec3{4, 10, 0}
+	ball.RigidBody.Collider = SphereCollider{Radius: 0.5}
+	ball.RigidBody.ApplyForce(mgl32.Vec3{0, -9.81, 0}, Acceleration)
+	w.AddActor(ball)
+
+	top := mgl32.Vec3{0, 10, 0}
+	rope, _ := NewRope(top, mgl32.Vec3{3.5, 10, 0}, 10)
+	rope.Start = NewRopeAnchor(nil, top)
+	rope.End = NewRopeAnchor(ball.RigidBody, mgl32.Vec3{3.5, 10, 0})
+	w.AddRope(rope)
+
+	// Swing the ball down on the rope, which should never let it go further
+	// than the rope's length
+	length := rope.Length() + 0.5
+	for i := 0; i < 300; i++ {
+		w.Update(1.0, 1.0/60.0)
+		if dist := ball.Transform.Position.Sub(top).Len(); dist > length*1.02 {
+			t.Fatalf("ball is %v from the top after %d frames, the rope is %v long", dist, i, length)
+		}
+	}
+	if p := rope.Positions[0]; p != top {
+		t.Errorf("fixed end of the rope moved to %v", p)
+	}
+	if y := ball.Transform.Position.Y(); y > 8 {
+		t.Errorf("ball is at y = %v, it should have swung down", y)
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Sensor takes readings of the World at the end of every Update
+type Sensor interface {
+	Update(w *World, delta, elapsed float32)
+}
+
+// sensorClock keeps time for a Sensor, to take readings at its own rate
+type sensorClock struct {
+	// Seconds since the Sensor was added
+	time float32
+	// Seconds towards the next reading, which keeps what was left over from
+	// the last one so readings keep to the rate
+	since float32
+	// Seconds since the last reading, and between the last two readings,
+	// which readings average over
+	sinceReading float32
+	interval     float32
+}
+
+// tick moves the clock on by elapsed, and returns whether a reading is due at
+// rate readings a second. A rate of 0, or one faster than the World updates,
+// takes a reading every Update
+func (clock *sensorClock) tick(rate, elapsed float32) bool {
+	clock.time += elapsed
+	clock.since += elapsed
+	clock.sinceReading += elapsed
+	if rate > 0.0 {
+		period := 1.0 / rate
+		if clock.since < period {
+			return false
+		}
+		// Only one reading is taken an Update, so drop any more that are due
+		// rather than falling behind
+		clock.since = float32(math.Mod(float64(clock.since), float64(period)))
+	} else {
+		clock.since = 0.0
+	}
+
+	clock.interval = clock.sinceReading
+	clock.sinceReading = 0.0
+	return true
+}
+
+// randomNormal returns a number from a normal distribution with a standard
+// deviation of stddev, from rng or the global source when it is nil
+func randomNormal(rng *rand.Rand, stddev float32) float32 {
+	if stddev == 0.0 {
+		return 0.0
+	}
+	if rng != nil {
+		return float32(rng.NormFloat64()) * stddev
+	}
+	return float32(rand.NormFloat64()) * stddev
+}
+
+// sensorNoise returns a vector with every axis drawn from a normal
+// distribution with a standard deviation of stddev
+func sensorNoise(rng *rand.Rand, stddev float32) mgl32.Vec3 {
+	return mgl32.Vec3{randomNormal(rng, stddev), randomNormal(rng, stddev), randomNormal(rng, stddev)}
+}
+
+// IMUReading is what an IMUSensor measured, in the sensor's frame
+type IMUReading struct {
+	Time float32
+	// Acceleration is how the sensor sped up since the last reading, less
+	// gravity, so it reads the opposite of gravity when still. It is in the
+	// same units as RigidBody.Acceleration
+	Acceleration mgl32.Vec3
+	// AngularVelocity is in radians per second
+	AngularVelocity mgl32.Vec3
+}
+
+// IMUSensor is an accelerometer and gyroscope fixed to an Actor
+type IMUSensor struct {
+	Actor *Actor
+	// Offset and Rotation place the sensor in the Actor's local space
+	Offset   mgl32.Vec3
+	Rotation mgl32.Quat
+	// Rate is how many readings are taken a second
+	Rate float32
+	// Gravity is what an accelerometer can't tell apart from accelerating
+	// the other way
+	Gravity mgl32.Vec3
+
+	// Noise is the standard deviation of the random error added to each
+	// axis of every reading, and Bias is a constant error
+	AccelerationNoise    float32
+	AccelerationBias     mgl32.Vec3
+	AngularVelocityNoise float32
+	AngularVelocityBias  mgl32.Vec3
+	// Rand makes the noise, set it to get the same noise every run. The
+	// global source is used when it is nil
+	Rand *rand.Rand
+
+	// Reading is the latest reading, which is also passed to OnReading
+	Reading   IMUReading
+	OnReading func(reading IMUReading)
+
+	clock sensorClock
+	// Velocity of the sensor at the last reading, per frame
+	velocity mgl32.Vec3
+	started  bool
+}
+
+// NewIMUSensor creates an IMUSensor at the Actor's origin, taking 100
+// readings a second without any noise
+func NewIMUSensor(actor *Actor) *IMUSensor {
+	return &IMUSensor{
+		Actor:    actor,
+		Rotation: mgl32.QuatIdent(),
+		Rate:     100.0,
+		Gravity:  mgl32.Vec3{0, -9.81, 0},
+	}
+}
+
+// pointVelocity returns the velocity of the sensor in world space, per frame
+func (imu *IMUSensor) pointVelocity() mgl32.Vec3 {
+	body := imu.Actor.RigidBody
+	transform := &imu.Actor.Transform
+	point := transform.Position.Add(transform.Rotation.Rotate(imu.Offset))
+	return body.Velocity.Add(body.AngularVelocity.Cross(point.Sub(body.WorldCenterOfMass())))
+}
+
+func (imu *IMUSensor) Update(w *World, delta, elapsed float32) {
+	if !imu.started {
+		imu.velocity = imu.pointVelocity()
+		imu.started = true
+	}
+	if elapsed <= 0.0 || !imu.clock.tick(imu.Rate, elapsed) {
+		return
+	}
+
+	// Velocity is per frame, but changes by Acceleration every second
+	velocity := imu.pointVelocity()
+	acceleration := velocity.Sub(imu.velocity).Mul(1.0 / imu.clock.interval).Sub(imu.Gravity)
+	angularVelocity := imu.Actor.RigidBody.AngularVelocity.Mul(delta / elapsed)
+	imu.velocity = velocity
+
+	toSensor := imu.Actor.Transform.Rotation.Mul(imu.Rotation).Conjugate()
+	imu.Reading = IMUReading{
+		Time: imu.clock.time,
+		Acceleration: toSensor.Rotate(acceleration).
+			Add(imu.AccelerationBias).
+			Add(sensorNoise(imu.Rand, imu.AccelerationNoise)),
+		AngularVelocity: toSensor.Rotate(angularVelocity).
+			Add(imu.AngularVelocityBias).
+			Add(sensorNoise(imu.Rand, imu.AngularVelocityNoise)),
+	}
+	if imu.OnReading != nil {
+		imu.OnReading(imu.Reading)
+	}
+}
+
+// ContactReading is what a ContactSensor measured, in world space
+type ContactReading struct {
+	Time float32
+	// Touching has every body the Actor is touching, and Points are where
+	// they touch
+	Touching []*RigidBody
+	Points   []mgl32.Vec3
+	// Force is the average force contacts pushed on the Actor with since the
+	// last reading
+	Force mgl32.Vec3
+}
+
+// ContactSensor reports what an Actor is touching, and how hard
+type ContactSensor struct {
+	Actor *Actor
+	// Rate is how many readings are taken a second
+	Rate float32
+	// ForceNoise is the standard deviation of the random error added to each
+	// axis of Force
+	ForceNoise float32
+	// Rand makes the noise, the global source is used when it is nil
+	Rand *rand.Rand
+
+	// Reading is the latest reading, which is also passed to OnReading
+	Reading   ContactReading
+	OnReading func(reading ContactReading)
+
+	clock   sensorClock
+	impulse mgl32.Vec3
+}
+
+// NewContactSensor creates a ContactSensor taking 100 readings a second
+func NewContactSensor(actor *Actor) *ContactSensor {
+	return &ContactSensor{
+		Actor: actor,
+		Rate:  100.0,
+	}
+}
+
+func (sensor *ContactSensor) Update(w *World, delta, elapsed float32) {
+	body := sensor.Actor.RigidBody
+	touching := []*RigidBody{}
+	points := []mgl32.Vec3{}
+	for _, m := range w.active {
+		impulse := mgl32.Vec3{}
+		for i := range m.Contacts {
+			c := &m.Contacts[i]
+			impulse = impulse.Add(c.Normal.Mul(c.NormalImpulse))
+		}
+
+		// The normals point from A to B, and push them apart
+		switch body {
+		case m.A:
+			sensor.impulse = sensor.impulse.Sub(impulse)
+			touching = append(touching, m.B)
+		case m.B:
+			sensor.impulse = sensor.impulse.Add(impulse)
+			touching = append(touching, m.A)
+		default:
+			continue
+		}
+		for i := range m.Contacts {
+			points = append(points, m.Contacts[i].Point)
+		}
+	}
+
+	if elapsed <= 0.0 || !sensor.clock.tick(sensor.Rate, elapsed) {
+		return
+	}
+
+	// An impulse changes the velocity per frame, like a force does over a
+	// second
+	force := sensor.impulse.Mul(1.0 / sensor.clock.interval)
+	sensor.impulse = mgl32.Vec3{}
+
+	sensor.Reading = ContactReading{
+		Time:     sensor.clock.time,
+		Touching: touching,
+		Points:   points,
+		Force:    force.Add(sensorNoise(sensor.Rand, sensor.ForceNoise)),
+	}
+	if sensor.OnReading != nil {
+		sensor.OnReading(sensor.Reading)
+	}
+}
+
+// ForceTorqueReading is what a ForceTorqueSensor measured, in the link's
+// frame
+type ForceTorqueReading struct {
+	Time float32
+	// Force and Torque are the average the parent pushed on the link with
+	// through the joint since the last reading, with the torque about the
+	// joint
+	Force  mgl32.Vec3
+	Torque mgl32.Vec3
+	// JointForce is the part of Torque about the axis of a RevoluteJoint, or
+	// of Force along the axis of a PrismaticJoint, which is what a motor
+	// driving the joint would push with
+	JointForce float32
+}
+
+// ForceTorqueSensor measures the force and torque passing through the joint
+// of an ArticulationLink. On the root of a FixedBase Articulation, it measures
+// what holds the rest of the links up
+type ForceTorqueSensor struct {
+	Link *ArticulationLink
+	// Rate is how many readings are taken a second
+	Rate float32
+
+	// Noise is the standard deviation of the random error added to each
+	// axis of every reading, and Bias is a constant error
+	ForceNoise  float32
+	ForceBias   mgl32.Vec3
+	TorqueNoise float32
+	TorqueBias  mgl32.Vec3
+	// Rand makes the noise, the global source is used when it is nil
+	Rand *rand.Rand
+
+	// Reading is the latest reading, which is also passed to OnReading
+	Reading   ForceTorqueReading
+	OnReading func(reading ForceTorqueReading)
+
+	clock   sensorClock
+	impulse spatialVector
+}
+
+// NewForceTorqueSensor creates a ForceTorqueSensor taking 100 readings a
+// second
+func NewForceTorqueSensor(link *ArticulationLink) *ForceTorqueSensor {
+	return &ForceTorqueSensor{
+		Link: link,
+		Rate: 100.0,
+	}
+}
+
+func (sensor *ForceTorqueSensor) Update(w *World, delta, elapsed float32) {
+	sensor.impulse = sensor.impulse.Add(sensor.Link.jointImpulse)
+
+	if elapsed <= 0.0 || !sensor.clock.tick(sensor.Rate, elapsed) {
+		return
+	}
+
+	force := sensor.impulse.Linear.Mul(1.0 / sensor.clock.interval)
+	torque := sensor.impulse.Angular.Mul(1.0 / sensor.clock.interval)
+	sensor.impulse = spatialVector{}
+
+	force = force.Add(sensor.ForceBias).Add(sensorNoise(sensor.Rand, sensor.ForceNoise))
+	torque = torque.Add(sensor.TorqueBias).Add(sensorNoise(sensor.Rand, sensor.TorqueNoise))
+	jointForce := float32(0.0)
+	switch sensor.Link.Joint {
+	case RevoluteJoint:
+		jointForce = torque.Dot(sensor.Link.Axis.Normalize())
+	case PrismaticJoint:
+		jointForce = force.Dot(sensor.Link.Axis.Normalize())
+	}
+
+	sensor.Reading = ForceTorqueReading{
+		Time:       sensor.clock.time,
+		Force:      force,
+		Torque:     torque,
+		JointForce: jointForce,
+	}
+	if sensor.OnReading != nil {
+		sensor.OnReading(sensor.Reading)
+	}
+}
+
+func (w *World) AddSensor(sensor Sensor) {
+	w.Sensors = append(w.Sensors, sensor)
+}
+
+func (w *World) RemoveSensor(sensor Sensor) {
+	for i := range w.Sensors {
+		if w.Sensors[i] == sensor {
+			w.Sensors = append(w.Sensors[:i], w.Sensors[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSensorClock(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float32
+		readings int
+		interval float32
+	}{
+		{"25 Hz", 25, 250, 1.0 / 25.0},
+		{"every update", 0, 600, 1.0 / 60.0},
+		{"as fast as the updates", 60, 600, 1.0 / 60.0},
+		{"faster than the updates", 200, 600, 1.0 / 60.0},
+		{"1 Hz", 1, 10, 1},
+	}
+	for _, test := range tests {
+		clock := sensorClock{}
+		readings := 0
+		for i := 0; i < 600; i++ {
+			if clock.tick(test.rate, 1.0/60.0) {
+				readings++
+				// A 25 Hz sensor can only read on a frame, so it reads 2 or 3
+				// frames apart
+				if math.Abs(float64(clock.interval-test.interval)) > 1.0/60.0+1e-4 {
+					t.Errorf("%s: reading %v after the last", test.name, clock.interval)
+				}
+			}
+		}
+
+		if readings < test.readings-1 || readings > test.readings {
+			t.Errorf("%s: took %d readings in 10 seconds, want %d", test.name, readings, test.readings)
+		}
+		if math.Abs(float64(clock.time-10)) > 1e-3 {
+			t.Errorf("%s: clock reads %v, want 10", test.name, clock.time)
+		}
+	}
+}
+
+func TestIMUSensor(t *testing.T) {
+	tests := []struct {
+		name     string
+		floor    bool
+		rotation mgl32.Quat
+		want     mgl32.Vec3
+	}{
+		{"resting", true, mgl32.QuatIdent(), mgl32.Vec3{0, 9.81, 0}},
+		{"resting on its side", true, mgl32.QuatRotate(math.Pi/2, mgl32.Vec3{0, 0, 1}), mgl32.Vec3{9.81, 0, 0}},
+		{"falling", false, mgl32.QuatIdent(), mgl32.Vec3{}},
+	}
+	for _, test := range tests {
+		w := NewWorld()
+		if test.floor {
+			newTestFloor(w)
+		}
+		box := newTestBox(w, mgl32.Vec3{0, 1, 0}, mgl32.Vec3{1, 1, 1})
+		box.RigidBody.Restitution = 0
+		imu := NewIMUSensor(box)
+		imu.Rotation = test.rotation
+		w.AddSensor(imu)
+
+		readings := 0
+		imu.OnReading = func(reading IMUReading) {
+			readings++
+		}
+		for i := 0; i < 60; i++ {
+			w.Update(1.0, 1.0/60.0)
+		}
+
+		if readings != 60 {
+			t.Errorf("%s: took %d readings, want 60", test.name, readings)
+		}
+		if got := imu.Reading.Acceleration; got.Sub(test.want).Len() > 0.05 {
+			t.Errorf("%s: acceleration = %v, want %v", test.name, got, test.want)
+		}
+		if got := imu.Reading.AngularVelocity; got.Len() > 1e-3 {
+			t.Errorf("%s: angular velocity = %v, want 0", test.name, got)
+		}
+	}
+
+	// Spinning at a radian a second reads in radians a second, not per frame
+	w := NewWorld()
+	spinner := NewActor()
+	spinner.RigidBody.Collider = SphereCollider{Radius: 1}
+	spinner.RigidBody.AngularVelocity = mgl32.Vec3{0, 1.0 / 60.0, 0}
+	w.AddActor(spinner)
+	imu := NewIMUSensor(spinner)
+	w.AddSensor(imu)
+	w.Update(1.0, 1.0/60.0)
+	if got := imu.Reading.AngularVelocity; got.Sub(mgl32.Vec3{0, 1, 0}).Len() > 1e-3 {
+		t.Errorf("spinning: angular velocity = %v, want (0, 1, 0)", got)
+	}
+}
+
+func TestContactSensor(t *testing.T) {
+	w := NewWorld()
+	floor := newTestFloor(w)
+	box := newTestBox(w, mgl32.Vec3{0, 1, 0}, mgl32.Vec3{1, 1, 1})
+	box.RigidBody.Restitution = 0
+	sensor := NewContactSensor(box)
+	sensor.Rate = 20
+	w.AddSensor(sensor)
+	for i := 0; i < 120; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	reading := sensor.Reading
+	if len(reading.Touching) != 1 || reading.Touching[0] != floor.RigidBody || len(reading.Points) == 0 {
+		t.Fatalf("touching %v at %v, want the floor", reading.Touching, reading.Points)
+	}
+	// Held up against its weight
+	weight := mgl32.Vec3{0, box.RigidBody.Mass * 9.81, 0}
+	if reading.Force.Sub(weight).Len() > 0.01*weight.Len() {
+		t.Errorf("force = %v, want %v", reading.Force, weight)
+	}
+	if math.Abs(float64(reading.Time-2)) > 1e-3 {
+		t.Errorf("last reading at %v, want 2", reading.Time)
+	}
+}
+
+func TestForceTorqueSensor(t *testing.T) {
+	w := NewWorld()
+	post := NewActor()
+	w.AddActor(post)
+
+	// A bob hanging still below its joint
+	bob := NewActor()
+	bob.RigidBody.Collider = SphereCollider{Radius: 1}
+	bob.RigidBody.SetDensity(1)
+	bob.RigidBody.CenterOfMass = mgl32.Vec3{0, -4, 0}
+	w.AddActor(bob)
+	pendulum := NewArticulation(post, true)
+	link := pendulum.AddLink(pendulum.Root(), bob, RevoluteJoint, mgl32.Vec3{}, mgl32.Vec3{0, 0, 1})
+	w.AddArticulation(pendulum)
+
+	sensor := NewForceTorqueSensor(link)
+	w.AddSensor(sensor)
+	for i := 0; i < 60; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	reading := sensor.Reading
+	weight := bob.RigidBody.Mass * 9.81
+	if reading.Force.Sub(mgl32.Vec3{0, weight, 0}).Len() > 0.01*weight {
+		t.Errorf("force = %v, want the weight %v up", reading.Force, weight)
+	}
+	if reading.Torque.Len() > 0.01*weight || math.Abs(float64(reading.JointForce)) > 0.01*float64(weight) {
+		t.Errorf("torque = %v and joint force = %v, want 0 hanging straight down", reading.Torque, reading.JointForce)
+	}
+}
+
+func TestForceTorqueSensorSpinning(t *testing.T) {
+	w := NewWorld()
+	post := NewActor()
+	w.AddActor(post)
+
+	// A bob swung around the post, half a turn a frame, so it turns a long way
+	// during a frame
+	bob := NewActor()
+	bob.RigidBody.Collider = SphereCollider{Radius: 1}
+	bob.RigidBody.SetDensity(1)
+	bob.RigidBody.CenterOfMass = mgl32.Vec3{4, 0, 0}
+	w.AddActor(bob)
+	spinner := NewArticulation(post, true)
+	spinner.Substeps = 8
+	spinner.Gravity = mgl32.Vec3{}
+	link := spinner.AddLink(spinner.Root(), bob, RevoluteJoint, mgl32.Vec3{}, mgl32.Vec3{0, 1, 0})
+	link.Velocity = 0.5
+	w.AddArticulation(spinner)
+
+	sensor := NewForceTorqueSensor(link)
+	sensor.Rate = 0
+	w.AddSensor(sensor)
+	for i := 0; i < 10; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	// Pulled in towards the post, which the bob is out along X from. Forces
+	// are per second but velocities are per frame, like the weight
+	want := mgl32.Vec3{-bob.RigidBody.Mass * 0.5 * 0.5 * 4 * 60, 0, 0}
+	if reading := sensor.Reading; reading.Force.Sub(want).Len() > 0.01*want.Len() {
+		t.Errorf("force = %v, want %v", reading.Force, want)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	castMaxIterations = 32
+	// How close a cast shape has to get to count as touching
+	castTolerance = float32(0.001)
+)
+
+// SphereCast sweeps a sphere from origin along direction, and finds the first
+// body it would hit
+func (w *World) SphereCast(origin mgl32.Vec3, radius float32, direction mgl32.Vec3, maxDist float32, layerMask uint32) (RaycastHit, bool) {
+	return w.ConvexCast(SphereCollider{Radius: radius}, origin, direction, maxDist, layerMask)
+}
+
+// BoxCast sweeps a box, with half-extents size, from center along direction,
+// and finds the first body it would hit
+func (w *World) BoxCast(center, size mgl32.Vec3, direction mgl32.Vec3, maxDist float32, layerMask uint32) (RaycastHit, bool) {
+	return w.ConvexCast(BoxCollider{Size: size}, center, direction, maxDist, layerMask)
+}
+
+// ConvexCast sweeps any convex Collider from origin along direction, and
+// finds the first body it would hit. Distance is how far the shape travels
+// before touching, Point is on the surface of the body that was hit, and
+// Normal is that surface's normal. Shapes that start out overlapping a body
+// hit it at a distance of 0, with the normal facing back along direction, or
+// the normal of a plane
+func (w *World) ConvexCast(shape Collider, origin, direction mgl32.Vec3, maxDist float32, layerMask uint32) (RaycastHit, bool) {
+	return w.sweep(shape, origin, direction, maxDist, layerMask, nil)
+}
+
+// sweep is ConvexCast, skipping the body ignore so a shape can be swept from
+// where its own body is
+func (w *World) sweep(shape Collider, origin, direction mgl32.Vec3, maxDist float32, layerMask uint32, ignore *RigidBody) (RaycastHit, bool) {
+	closest := RaycastHit{}
+	found := false
+
+	if direction.Len() == 0.0 {
+		return closest, false
+	}
+	direction = direction.Normalize()
+
+	start := colliderBounds(shape, origin, mgl32.QuatIdent())
+	end := AABB{Min: start.Min.Add(direction.Mul(maxDist)), Max: start.Max.Add(direction.Mul(maxDist))}
+	bounds := start.Union(end)
+
+	w.updateBroadphase()
+	w.broadphase.QueryAABB(bounds, func(rb *RigidBody, index int) bool {
+		if rb == ignore || rb.IsTrigger || layerMask&(1<<rb.Layer) == 0 {
+			return true
+		}
+
+		hit := func(dist float32, point, normal mgl32.Vec3) {
+			maxDist = dist
+			closest = RaycastHit{
+				Body:     rb,
+				Point:    point,
+				Normal:   normal,
+				Distance: dist,
+			}
+			found = true
+		}
+
+		transform := &rb.Parent.Transform
+		if plane, ok := rb.Collider.(PlaneCollider); ok {
+			if dist, point, normal, ok := castPlane(shape, origin, direction, maxDist, plane, transform.Position, transform.Rotation); ok {
+				hit(dist, point, normal)
+			}
+			return true
+		}
+
+		for _, target := range convexPieces(rb.Collider, transform.Position, transform.Rotation, bounds) {
+			if dist, point, normal, ok := convexCast(shape, origin, direction, maxDist, target); ok {
+				hit(dist, point, normal)
+			}
+		}
+		return true
+	})
+
+	return closest, found
+}
+
+// castPlane finds when a shape moving along direction first touches a plane,
+// which is when the point on the shape furthest behind the plane reaches it
+func castPlane(shape Collider, origin, direction mgl32.Vec3, maxDist float32, plane PlaneCollider, planePos mgl32.Vec3, planeRot mgl32.Quat) (float32, mgl32.Vec3, mgl32.Vec3, bool) {
+	moving, ok := newConvexShape(shape, origin, mgl32.QuatIdent())
+	if !ok {
+		return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, false
+	}
+
+	normal := planeRot.Rotate(plane.Normal).Normalize()
+	deepest := moving.Support(normal.Mul(-1.0)).Sub(normal.Mul(moving.Margin))
+	gap := normal.Dot(deepest.Sub(planePos)) - plane.Offset
+	if gap <= 0.0 {
+		return 0.0, deepest.Sub(normal.Mul(gap)), normal, true
+	}
+
+	closing := -normal.Dot(direction)
+	if closing <= 0.0 || gap/closing > maxDist {
+		return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, false
+	}
+	t := gap / closing
+	return t, deepest.Add(direction.Mul(t)), normal, true
+}
+
+// convexCast finds when a shape moving along direction first touches target,
+// using conservative advancement. Every step moves the shape forward by the
+// gap between them, divided by how fast it is closing that gap, which can never
+// overshoot
+func convexCast(shape Collider, origin, direction mgl32.Vec3, maxDist float32, target convexShape) (float32, mgl32.Vec3, mgl32.Vec3, bool) {
+	t := float32(0.0)
+	point := origin
+	normal := direction
+
+	for it := 0; it < castMaxIterations; it++ {
+		moving, ok := newConvexShape(shape, origin.Add(direction.Mul(t)), mgl32.QuatIdent())
+		if !ok {
+			break
+		}
+
+		dist, _, pointB, n, overlap := shapeDistance(moving, target)
+		if overlap {
+			// Only possible on the first step, or through rounding error
+			return t, point, normal.Mul(-1.0), true
+		}
+		point = pointB
+		normal = n
+		if dist <= castTolerance {
+			return t, point, normal.Mul(-1.0), true
+		}
+
+		closing := direction.Dot(normal)
+		if closing <= 0.0 {
+			break
+		}
+
+		t += dist / closing
+		if t > maxDist {
+			break
+		}
+	}
+
+	return 0.0, mgl32.Vec3{}, mgl32.Vec3{}, false
+}
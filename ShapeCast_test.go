@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestConvexCast(t *testing.T) {
+	w := NewWorld()
+	box := NewActor()
+	box.Transform.Position = mgl32.Vec3{0, 0, 0}
+	box.RigidBody.Collider = BoxCollider{Size: mgl32.Vec3{1, 1, 1}}
+	w.AddActor(box)
+	ground := NewActor()
+	ground.Transform.Position = mgl32.Vec3{20, 0, 0}
+	ground.RigidBody.Collider = PlaneCollider{Normal: mgl32.Vec3{0, 1, 0}, Offset: -1}
+	w.AddActor(ground)
+
+	down := mgl32.Vec3{0, -1, 0}
+	right := mgl32.Vec3{1, 0, 0}
+	tests := []struct {
+		name      string
+		shape     Collider
+		origin    mgl32.Vec3
+		direction mgl32.Vec3
+		hit       bool
+		dist      float32
+		normal    mgl32.Vec3
+	}{
+		{"sphere onto box", SphereCollider{Radius: 0.5}, mgl32.Vec3{0, 5, 0}, down, true, 3.5, mgl32.Vec3{0, 1, 0}},
+		{"box onto box", BoxCollider{Size: mgl32.Vec3{0.5, 0.5, 0.5}}, mgl32.Vec3{0.5, 5, 0.5}, down, true, 3.5, mgl32.Vec3{0, 1, 0}},
+		{"sphere over box", SphereCollider{Radius: 0.5}, mgl32.Vec3{-5, 2, 0}, right, false, 0, mgl32.Vec3{}},
+		{"sphere onto plane", SphereCollider{Radius: 0.5}, mgl32.Vec3{20, 5, 0}, down, true, 5.5, mgl32.Vec3{0, 1, 0}},
+		{"start overlapping", SphereCollider{Radius: 0.5}, mgl32.Vec3{0, 1, 0}, down, true, 0, mgl32.Vec3{0, 1, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hit, found := w.ConvexCast(test.shape, test.origin, test.direction, 10, AllLayers)
+			if found != test.hit {
+				t.Fatalf("found = %v, want %v", found, test.hit)
+			}
+			if !found {
+				return
+			}
+			if math.Abs(float64(hit.Distance-test.dist)) > 0.01 {
+				t.Errorf("distance = %v, want %v", hit.Distance, test.dist)
+			}
+			if hit.Normal.Sub(test.normal).Len() > 0.01 {
+				t.Errorf("normal = %v, want %v", hit.Normal, test.normal)
+			}
+		})
+	}
+}
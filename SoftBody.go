@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// DistanceConstraint keeps two particles RestLength apart
+type DistanceConstraint struct {
+	A, B       int
+	RestLength float32
+}
+
+// SoftBody is a closed surface mesh of particles, simulated with
+// position-based dynamics. Distance constraints along every edge keep its
+// shape, and a volume constraint stops it collapsing. It is pushed out of any
+// RigidBody colliders it touches, but doesn't push back on them
+type SoftBody struct {
+	Positions  []mgl32.Vec3
+	Velocities []mgl32.Vec3
+	// InverseMasses of 0 pin a particle in place
+	InverseMasses []float32
+	// Triangles of the surface, wound counter-clockwise when seen from outside
+	Triangles   [][3]int
+	Constraints []DistanceConstraint
+	RestVolume  float32
+
+	// Stiffness of the distance constraints, and VolumeStiffness of the volume
+	// constraint, from 0 for none to 1 for fully rigid
+	Stiffness       float32
+	VolumeStiffness float32
+	// Pressure scales the volume the body tries to keep, above 1 inflates it
+	Pressure float32
+	// Iterations is how many times the constraints are solved every Update
+	Iterations int
+
+	Gravity mgl32.Vec3
+	// Damping is air resistance, in the same way as RigidBody.LinearDamping
+	Damping float32
+
+	// Thickness is how far the surface is kept from colliders
+	Thickness float32
+	// Friction is how much of a particle's sliding velocity is lost when it
+	// is pushed out of a collider
+	Friction float32
+	// CollisionMask has a bit set for every layer the body collides with
+	CollisionMask uint32
+
+	predicted []mgl32.Vec3
+	gradients []mgl32.Vec3
+
+	mesh dynamicMesh
+}
+
+// NewSoftBody creates a SoftBody of the given mass from the mesh loaded into
+// model, placed with transform. The mesh must be closed
+func NewSoftBody(model *Model, transform Transform, mass float32) (*SoftBody, error) {
+	if _, err := ComputeMeshMassProperties(model.Vertices, model.Triangles, 1.0); err != nil {
+		return nil, err
+	}
+	if mass <= 0.0 {
+		return nil, fmt.Errorf("Soft body needs a positive mass, got %v", mass)
+	}
+
+	body := &SoftBody{
+		Positions:   []mgl32.Vec3{},
+		Triangles:   [][3]int{},
+		Constraints: []DistanceConstraint{},
+
+		Stiffness:       0.5,
+		VolumeStiffness: 1.0,
+		Pressure:        1.0,
+		Iterations:      10,
+
+		Gravity: mgl32.Vec3{0, -9.81, 0},
+		Damping: 0.1,
+
+		Thickness:     0.1,
+		Friction:      0.5,
+		CollisionMask: AllLayers,
+	}
+
+	// One particle for every distinct vertex position
+	matrix := transform.GetMatrix()
+	welded := weldVertices(model.Vertices)
+	particles := map[int]int{}
+	for i, v := range model.Vertices {
+		if welded[i] != i {
+			continue
+		}
+		particles[i] = len(body.Positions)
+		body.Positions = append(body.Positions, matrix.Mul4x1(v.Vec4(1.0)).Vec3())
+	}
+
+	for _, tri := range model.Triangles {
+		face := [3]int{particles[welded[tri[0]]], particles[welded[tri[1]]], particles[welded[tri[2]]]}
+		if face[0] == face[1] || face[1] == face[2] || face[2] == face[0] {
+			continue
+		}
+		body.Triangles = append(body.Triangles, face)
+	}
+
+	// Make sure the surface winds outwards, for the volume constraint
+	body.RestVolume = body.volume(body.Positions)
+	if body.RestVolume < 0.0 {
+		for i := range body.Triangles {
+			body.Triangles[i][1], body.Triangles[i][2] = body.Triangles[i][2], body.Triangles[i][1]
+		}
+		body.RestVolume = -body.RestVolume
+	}
+
+	edges := map[[2]int]bool{}
+	for _, tri := range body.Triangles {
+		for e := 0; e < 3; e++ {
+			a, b := tri[e], tri[(e+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			if edges[[2]int{a, b}] {
+				continue
+			}
+			edges[[2]int{a, b}] = true
+			body.Constraints = append(body.Constraints, DistanceConstraint{
+				A:          a,
+				B:          b,
+				RestLength: body.Positions[b].Sub(body.Positions[a]).Len(),
+			})
+		}
+	}
+
+	count := len(body.Positions)
+	body.Velocities = make([]mgl32.Vec3, count)
+	body.InverseMasses = make([]float32, count)
+	for i := range body.InverseMasses {
+		body.InverseMasses[i] = float32(count) / mass
+	}
+	body.predicted = make([]mgl32.Vec3, count)
+	body.gradients = make([]mgl32.Vec3, count)
+
+	return body, nil
+}
+
+// Cleanup frees up resources
+func (body *SoftBody) Cleanup() {
+	body.mesh.Cleanup()
+}
+
+// Bounds returns the AABB of every particle
+func (body *SoftBody) Bounds() AABB {
+	bounds := AABB{Min: body.Positions[0], Max: body.Positions[0]}
+	for _, p := range body.Positions {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	return bounds
+}
+
+// Volume returns the volume currently enclosed by the surface
+func (body *SoftBody) Volume() float32 {
+	return body.volume(body.Positions)
+}
+
+// volume sums up the signed tetrahedra between each face and the origin
+func (body *SoftBody) volume(positions []mgl32.Vec3) float32 {
+	volume := float32(0.0)
+	for _, tri := range body.Triangles {
+		a := positions[tri[0]]
+		b := positions[tri[1]]
+		c := positions[tri[2]]
+		volume += a.Dot(b.Cross(c)) / 6.0
+	}
+	return volume
+}
+
+// Update moves the body forward, colliding with the bodies in w
+func (body *SoftBody) Update(w *World, delta, elapsed float32) {
+	if delta <= 0.0 {
+		return
+	}
+
+	for i := range body.Positions {
+		if body.InverseMasses[i] == 0.0 {
+			body.predicted[i] = body.Positions[i]
+			continue
+		}
+		v := body.Velocities[i].Add(body.Gravity.Mul(elapsed))
+		body.Velocities[i] = v.Mul(1.0 / (1.0 + elapsed*body.Damping))
+		body.predicted[i] = body.Positions[i].Add(body.Velocities[i].Mul(delta))
+	}
+
+	// Anything the body could reach this frame
+	bounds := body.Bounds()
+	for _, p := range body.predicted {
+		bounds = bounds.Union(AABB{Min: p, Max: p})
+	}
+	thickness := mgl32.Vec3{body.Thickness, body.Thickness, body.Thickness}
+	bodies := w.particleColliders(AABB{Min: bounds.Min.Sub(thickness), Max: bounds.Max.Add(thickness)}, body.CollisionMask)
+
+	// Stiffness is applied every iteration, so scale it down to match the
+	// stiffness of solving once
+	iterations := body.Iterations
+	if iterations < 1 {
+		iterations = 1
+	}
+	stiffness := 1.0 - float32(math.Pow(float64(1.0-body.Stiffness), 1.0/float64(iterations)))
+
+	for it := 0; it < iterations; it++ {
+		for _, c := range body.Constraints {
+			body.solveDistance(c, stiffness)
+		}
+		body.solveVolume()
+
+		for i := range body.predicted {
+			if body.InverseMasses[i] == 0.0 {
+				continue
+			}
+			body.predicted[i], _ = collideParticle(body.Positions[i], body.predicted[i], mgl32.Vec3{},
+				body.Thickness, 0.0, bodies)
+		}
+	}
+
+	// The velocity is however far the particles ended up moving
+	for i := range body.Positions {
+		v := body.predicted[i].Sub(body.Positions[i]).Mul(1.0 / delta)
+		body.Positions[i], body.Velocities[i] = collideParticle(body.Positions[i], body.predicted[i], v,
+			body.Thickness, body.Friction, bodies)
+	}
+}
+
+func (body *SoftBody) solveDistance(c DistanceConstraint, stiffness float32) {
+	wa := body.InverseMasses[c.A]
+	wb := body.InverseMasses[c.B]
+	if wa+wb == 0.0 {
+		return
+	}
+
+	d := body.predicted[c.B].Sub(body.predicted[c.A])
+	length := d.Len()
+	if length == 0.0 {
+		return
+	}
+
+	correction := d.Mul((length - c.RestLength) / (length * (wa + wb)) * stiffness)
+	body.predicted[c.A] = body.predicted[c.A].Add(correction.Mul(wa))
+	body.predicted[c.B] = body.predicted[c.B].Sub(correction.Mul(wb))
+}
+
+// solveVolume moves every particle along the gradient of the volume, so the
+// body gets back to Pressure times its RestVolume
+func (body *SoftBody) solveVolume() {
+	if body.VolumeStiffness == 0.0 {
+		return
+	}
+
+	for i := range body.gradients {
+		body.gradients[i] = mgl32.Vec3{0, 0, 0}
+	}
+	for _, tri := range body.Triangles {
+		a := body.predicted[tri[0]]
+		b := body.predicted[tri[1]]
+		c := body.predicted[tri[2]]
+		body.gradients[tri[0]] = body.gradients[tri[0]].Add(b.Cross(c).Mul(1.0 / 6.0))
+		body.gradients[tri[1]] = body.gradients[tri[1]].Add(c.Cross(a).Mul(1.0 / 6.0))
+		body.gradients[tri[2]] = body.gradients[tri[2]].Add(a.Cross(b).Mul(1.0 / 6.0))
+	}
+
+	denom := float32(0.0)
+	for i, g := range body.gradients {
+		denom += body.InverseMasses[i] * g.LenSqr()
+	}
+	if denom == 0.0 {
+		return
+	}
+
+	lambda := -(body.volume(body.predicted) - body.Pressure*body.RestVolume) / denom * body.VolumeStiffness
+	for i, g := range body.gradients {
+		body.predicted[i] = body.predicted[i].Add(g.Mul(lambda * body.InverseMasses[i]))
+	}
+}
+
+func (body *SoftBody) Render(shader *Shader) {
+	body.mesh.Render(shader, body.Positions, body.Triangles)
+}
+
+func (w *World) AddSoftBody(body *SoftBody) {
+	w.SoftBodies = append(w.SoftBodies, body)
+}
+
+func (w *World) RemoveSoftBody(body *SoftBody) {
+	for i := range w.SoftBodies {
+		if w.SoftBodies[i] == body {
+			w.SoftBodies = append(w.SoftBodies[:i], w.SoftBodies[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// newTestCubeMesh is a closed cube mesh, with a half-extent of size
+func newTestCubeMesh(size mgl32.Vec3) *Model {
+	hull, _ := NewConvexHullCollider(boxCorners(size))
+	return &Model{Vertices: hull.Points, Triangles: hull.Faces}
+}
+
+func TestNewSoftBody(t *testing.T) {
+	cube := newTestCubeMesh(mgl32.Vec3{1, 1, 1})
+	open := &Model{Vertices: cube.Vertices, Triangles: cube.Triangles[1:]}
+	transform := NewTransform()
+	transform.Position = mgl32.Vec3{0, 5, 0}
+	transform.Scale = mgl32.Vec3{2, 2, 2}
+
+	tests := []struct {
+		name  string
+		model *Model
+		mass  float32
+		err   bool
+	}{
+		{"cube", cube, 8, false},
+		{"open mesh", open, 8, true},
+		{"no mass", cube, 0, true},
+	}
+	for _, test := range tests {
+		body, err := NewSoftBody(test.model, transform, test.mass)
+		if (err != nil) != test.err {
+			t.Errorf("%s: err = %v, want an error: %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		// 12 edges and a diagonal across each of the 6 faces
+		if len(body.Positions) != 8 || len(body.Constraints) != 18 {
+			t.Errorf("%s: got %d particles and %d constraints, want 8 and 18", test.name, len(body.Positions), len(body.Constraints))
+		}
+		if volume := body.Volume(); math.Abs(float64(volume-64)) > 1e-3 {
+			t.Errorf("%s: volume = %v, want 64", test.name, volume)
+		}
+		if center := body.Bounds().Center(); center.Sub(transform.Position).Len() > 1e-4 {
+			t.Errorf("%s: centered at %v, want %v", test.name, center, transform.Position)
+		}
+		if total := float32(len(body.Positions)) / body.InverseMasses[0]; math.Abs(float64(total-test.mass)) > 1e-4 {
+			t.Errorf("%s: total mass = %v, want %v", test.name, total, test.mass)
+		}
+	}
+}
+
+func TestSoftBodyLands(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	transform := NewTransform()
+	transform.Position = mgl32.Vec3{0, 3, 0}
+	body, err := NewSoftBody(newTestCubeMesh(mgl32.Vec3{1, 1, 1}), transform, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.AddSoftBody(body)
+	for i := 0; i < 180; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	for i, p := range body.Positions {
+		if p.Y() < body.Thickness*0.9 {
+			t.Fatalf("particle %d sank to %v", i, p)
+		}
+	}
+	if bottom := body.Bounds().Min.Y(); bottom > body.Thickness+0.1 {
+		t.Errorf("bottom of the body is at %v, it should be on the floor", bottom)
+	}
+	// The volume constraint keeps it from being squashed flat
+	if volume := body.Volume(); volume < body.RestVolume*0.8 {
+		t.Errorf("volume = %v, want about %v", volume, body.RestVolume)
+	}
+}
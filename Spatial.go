@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// spatialVector is a 6D vector from Featherstone's spatial algebra. As a
+// motion it is an angular velocity, and the velocity of the point at the
+// origin. As a force it is a torque about the origin, and a force
+type spatialVector struct {
+	Angular mgl32.Vec3
+	Linear  mgl32.Vec3
+}
+
+func (a spatialVector) Add(b spatialVector) spatialVector {
+	return spatialVector{a.Angular.Add(b.Angular), a.Linear.Add(b.Linear)}
+}
+
+func (a spatialVector) Sub(b spatialVector) spatialVector {
+	return spatialVector{a.Angular.Sub(b.Angular), a.Linear.Sub(b.Linear)}
+}
+
+func (a spatialVector) Mul(s float32) spatialVector {
+	return spatialVector{a.Angular.Mul(s), a.Linear.Mul(s)}
+}
+
+// Dot is the power of a force moving at a motion, or the other way round
+func (a spatialVector) Dot(b spatialVector) float32 {
+	return a.Angular.Dot(b.Angular) + a.Linear.Dot(b.Linear)
+}
+
+// crossMotion is how the motion m changes when carried along by the motion v
+func (v spatialVector) crossMotion(m spatialVector) spatialVector {
+	return spatialVector{
+		v.Angular.Cross(m.Angular),
+		v.Angular.Cross(m.Linear).Add(v.Linear.Cross(m.Angular)),
+	}
+}
+
+// crossForce is how the force f changes when carried along by the motion v
+func (v spatialVector) crossForce(f spatialVector) spatialVector {
+	return spatialVector{
+		v.Angular.Cross(f.Angular).Add(v.Linear.Cross(f.Linear)),
+		v.Angular.Cross(f.Linear),
+	}
+}
+
+// spatialForceAt returns a force acting at point as a spatialVector
+func spatialForceAt(force, point mgl32.Vec3) spatialVector {
+	return spatialVector{point.Cross(force), force}
+}
+
+// spatialMatrix is a 6x6 matrix made of 3x3 blocks, mapping motions to forces
+// like an inertia
+//
+//	| A B |
+//	| C D |
+type spatialMatrix struct {
+	A, B, C, D mgl32.Mat3
+}
+
+// spatialInertia is the inertia of a rigid body about the origin, where
+// center is its center of mass and inertia is the tensor about that
+func spatialInertia(mass float32, center mgl32.Vec3, inertia mgl32.Mat3) spatialMatrix {
+	skew := skewMatrix(center).Mul(mass)
+	return spatialMatrix{
+		A: inertia.Add(mgl32.Ident3().Mul(mass * center.Dot(center)).Sub(center.OuterProd3(center).Mul(mass))),
+		B: skew,
+		C: skew.Transpose(),
+		D: mgl32.Ident3().Mul(mass),
+	}
+}
+
+// skewMatrix returns the matrix that crosses v with whatever it multiplies
+func skewMatrix(v mgl32.Vec3) mgl32.Mat3 {
+	return mgl32.Mat3{
+		0, v.Z(), -v.Y(),
+		-v.Z(), 0, v.X(),
+		v.Y(), -v.X(), 0,
+	}
+}
+
+// spatialOuter returns a times b transposed
+func spatialOuter(a, b spatialVector) spatialMatrix {
+	return spatialMatrix{
+		A: a.Angular.OuterProd3(b.Angular),
+		B: a.Angular.OuterProd3(b.Linear),
+		C: a.Linear.OuterProd3(b.Angular),
+		D: a.Linear.OuterProd3(b.Linear),
+	}
+}
+
+func (m spatialMatrix) Add(o spatialMatrix) spatialMatrix {
+	return spatialMatrix{m.A.Add(o.A), m.B.Add(o.B), m.C.Add(o.C), m.D.Add(o.D)}
+}
+
+func (m spatialMatrix) Sub(o spatialMatrix) spatialMatrix {
+	return spatialMatrix{m.A.Sub(o.A), m.B.Sub(o.B), m.C.Sub(o.C), m.D.Sub(o.D)}
+}
+
+func (m spatialMatrix) Scale(s float32) spatialMatrix {
+	return spatialMatrix{m.A.Mul(s), m.B.Mul(s), m.C.Mul(s), m.D.Mul(s)}
+}
+
+func (m spatialMatrix) Mul(v spatialVector) spatialVector {
+	return spatialVector{
+		m.A.Mul3x1(v.Angular).Add(m.B.Mul3x1(v.Linear)),
+		m.C.Mul3x1(v.Angular).Add(m.D.Mul3x1(v.Linear)),
+	}
+}
+
+// Solve finds x where m x = v, with Gaussian elimination. It returns false if
+// m is singular
+func (m spatialMatrix) Solve(v spatialVector) (spatialVector, bool) {
+	var rows [6][7]float64
+	blocks := [2][2]mgl32.Mat3{{m.A, m.B}, {m.C, m.D}}
+	for r := 0; r < 6; r++ {
+		for c := 0; c < 6; c++ {
+			// Mat3 is stored a column at a time
+			rows[r][c] = float64(blocks[r/3][c/3][(c%3)*3+r%3])
+		}
+	}
+	for r := 0; r < 3; r++ {
+		rows[r][6] = float64(v.Angular[r])
+		rows[r+3][6] = float64(v.Linear[r])
+	}
+
+	for col := 0; col < 6; col++ {
+		pivot := col
+		for r := col + 1; r < 6; r++ {
+			if math.Abs(rows[r][col]) > math.Abs(rows[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(rows[pivot][col]) < 1e-12 {
+			return spatialVector{}, false
+		}
+		rows[col], rows[pivot] = rows[pivot], rows[col]
+
+		for r := 0; r < 6; r++ {
+			if r == col {
+				continue
+			}
+			f := rows[r][col] / rows[col][col]
+			for c := col; c < 7; c++ {
+				rows[r][c] -= f * rows[col][c]
+			}
+		}
+	}
+
+	x := spatialVector{}
+	for r := 0; r < 3; r++ {
+		x.Angular[r] = float32(rows[r][6] / rows[r][r])
+		x.Linear[r] = float32(rows[r+3][6] / rows[r+3][r+3])
+	}
+	return x, true
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// SpatialHash buckets points into a uniform grid of cells, for finding the
+// points near to another quickly. It works best with CellSize equal to the
+// radius that is searched
+type SpatialHash struct {
+	CellSize float32
+
+	positions []mgl32.Vec3
+	cells     map[[3]int32][]int
+}
+
+// NewSpatialHash creates an empty SpatialHash
+func NewSpatialHash(cellSize float32) *SpatialHash {
+	return &SpatialHash{
+		CellSize: cellSize,
+		cells:    map[[3]int32][]int{},
+	}
+}
+
+func (hash *SpatialHash) cell(p mgl32.Vec3) [3]int32 {
+	return [3]int32{
+		int32(math.Floor(float64(p.X() / hash.CellSize))),
+		int32(math.Floor(float64(p.Y() / hash.CellSize))),
+		int32(math.Floor(float64(p.Z() / hash.CellSize))),
+	}
+}
+
+// Build replaces everything in the hash with positions, which are referred to
+// by their index. The slice is kept, and must not change until the next Build
+func (hash *SpatialHash) Build(positions []mgl32.Vec3) {
+	// Keep the cells that were used last time, as they are likely to be used
+	// again, and drop the rest
+	for key, indices := range hash.cells {
+		if len(indices) == 0 {
+			delete(hash.cells, key)
+		} else {
+			hash.cells[key] = indices[:0]
+		}
+	}
+
+	hash.positions = positions
+	for i, p := range positions {
+		key := hash.cell(p)
+		hash.cells[key] = append(hash.cells[key], i)
+	}
+}
+
+// Query appends the index of every point within radius of p to found, and
+// returns it
+func (hash *SpatialHash) Query(p mgl32.Vec3, radius float32, found []int) []int {
+	offset := mgl32.Vec3{radius, radius, radius}
+	min := hash.cell(p.Sub(offset))
+	max := hash.cell(p.Add(offset))
+	radiusSqr := radius * radius
+
+	for x := min[0]; x <= max[0]; x++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for z := min[2]; z <= max[2]; z++ {
+				for _, i := range hash.cells[[3]int32{x, y, z}] {
+					if hash.positions[i].Sub(p).LenSqr() <= radiusSqr {
+						found = append(found, i)
+					}
+				}
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSpatialHashQuery(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	positions := make([]mgl32.Vec3, 500)
+	for i := range positions {
+		positions[i] = mgl32.Vec3{random.Float32()*20 - 10, random.Float32()*20 - 10, random.Float32()*20 - 10}
+	}
+	hash := NewSpatialHash(1.5)
+	hash.Build(positions)
+
+	tests := []struct {
+		point  mgl32.Vec3
+		radius float32
+	}{
+		{mgl32.Vec3{0, 0, 0}, 1.5},
+		{mgl32.Vec3{-3.2, 4.7, -9.9}, 1.5},
+		{mgl32.Vec3{5, -5, 5}, 0.5},
+		{mgl32.Vec3{2, 2, 2}, 4},
+		{mgl32.Vec3{50, 50, 50}, 2},
+	}
+	for _, test := range tests {
+		want := []int{}
+		for i, p := range positions {
+			if p.Sub(test.point).Len() <= test.radius {
+				want = append(want, i)
+			}
+		}
+		found := hash.Query(test.point, test.radius, nil)
+		sort.Ints(found)
+
+		if len(found) != len(want) {
+			t.Errorf("query at %v within %v found %d points, want %d", test.point, test.radius, len(found), len(want))
+			continue
+		}
+		for i := range want {
+			if found[i] != want[i] {
+				t.Errorf("query at %v within %v found point %d, want %d", test.point, test.radius, found[i], want[i])
+				break
+			}
+		}
+	}
+
+	// Rebuilding with fewer points forgets the rest
+	hash.Build(positions[:1])
+	if found := hash.Query(positions[1], 0.1, nil); len(found) != 0 {
+		t.Errorf("found %v after rebuilding without it", found)
+	}
+	if found := hash.Query(positions[0], 0.1, nil); len(found) != 1 || found[0] != 0 {
+		t.Errorf("found %v, want the only point", found)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestSkewMatrix(t *testing.T) {
+	tests := []struct {
+		a, b mgl32.Vec3
+	}{
+		{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 1, 0}},
+		{mgl32.Vec3{1, 2, 3}, mgl32.Vec3{-4, 5, 0.5}},
+		{mgl32.Vec3{0, 0, 0}, mgl32.Vec3{7, 8, 9}},
+	}
+	for _, test := range tests {
+		if got, want := skewMatrix(test.a).Mul3x1(test.b), test.a.Cross(test.b); got.Sub(want).Len() > 1e-5 {
+			t.Errorf("skewMatrix(%v) * %v = %v, want %v", test.a, test.b, got, want)
+		}
+	}
+}
+
+func TestSpatialCross(t *testing.T) {
+	v := spatialVector{mgl32.Vec3{0.3, -1, 2}, mgl32.Vec3{1, 0.5, -0.2}}
+	m := spatialVector{mgl32.Vec3{-1, 2, 0.7}, mgl32.Vec3{0.1, 3, -1}}
+	f := spatialVector{mgl32.Vec3{2, 0, -1}, mgl32.Vec3{0.4, -0.6, 1.5}}
+
+	// A motion carried along by itself doesn't change
+	if got := v.crossMotion(v); got.Angular.Len() > 1e-5 || got.Linear.Len() > 1e-5 {
+		t.Errorf("v crossMotion v = %v, want 0", got)
+	}
+	// The two cross products are duals, so carrying both a force and a motion
+	// along leaves their power unchanged
+	if a, b := v.crossMotion(m).Dot(f), -m.Dot(v.crossForce(f)); a-b > 1e-4 || b-a > 1e-4 {
+		t.Errorf("(v x m).f = %v, want -m.(v x* f) = %v", a, b)
+	}
+	// A force at a point has the torque about the origin
+	force := spatialForceAt(mgl32.Vec3{0, -2, 0}, mgl32.Vec3{3, 0, 0})
+	if want := (mgl32.Vec3{0, 0, -6}); force.Angular.Sub(want).Len() > 1e-5 {
+		t.Errorf("torque = %v, want %v", force.Angular, want)
+	}
+}
+
+func TestSpatialInertia(t *testing.T) {
+	mass := float32(2)
+	center := mgl32.Vec3{1, -2, 0.5}
+	inertia := mgl32.Diag3(mgl32.Vec3{0.5, 0.8, 1.1})
+	I := spatialInertia(mass, center, inertia)
+
+	tests := []spatialVector{
+		{mgl32.Vec3{}, mgl32.Vec3{1, 2, 3}},
+		{mgl32.Vec3{0, 0, 1}, mgl32.Vec3{}},
+		{mgl32.Vec3{0.3, -1, 2}, mgl32.Vec3{1, 0.5, -0.2}},
+	}
+	for _, v := range tests {
+		// The kinetic energy matches the body's own, with the velocity of its
+		// center of mass and its spin
+		velocity := v.Linear.Add(v.Angular.Cross(center))
+		want := 0.5*mass*velocity.Dot(velocity) + 0.5*v.Angular.Dot(inertia.Mul3x1(v.Angular))
+		if got := 0.5 * v.Dot(I.Mul(v)); got-want > 1e-4 || want-got > 1e-4 {
+			t.Errorf("energy moving at %v = %v, want %v", v, got, want)
+		}
+
+		// Solving undoes multiplying
+		x, ok := I.Solve(I.Mul(v))
+		if !ok || x.Angular.Sub(v.Angular).Len() > 1e-4 || x.Linear.Sub(v.Linear).Len() > 1e-4 {
+			t.Errorf("solved %v, want %v", x, v)
+		}
+	}
+
+	// A point mass can't be spun about itself
+	if _, ok := spatialInertia(mass, center, mgl32.Mat3{}).Solve(spatialVector{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{}}); ok {
+		t.Errorf("solved a singular inertia")
+	}
+}
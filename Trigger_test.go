@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestTriggerHasNoResponse(t *testing.T) {
+	w := NewWorld()
+	trigger := NewActor()
+	trigger.Transform.Scale = mgl32.Vec3{5, 1, 5}
+	trigger.RigidBody.Collider = BoxCollider{Size: trigger.Transform.Scale}
+	trigger.RigidBody.Mass = math.MaxFloat32
+	trigger.RigidBody.IsTrigger = true
+	w.AddActor(trigger)
+	box := newTestBox(w, mgl32.Vec3{0, 5, 0}, mgl32.Vec3{1, 1, 1})
+
+	enters, exits := 0, 0
+	w.OnTriggerEnter = func(event CollisionEvent) {
+		enters++
+	}
+	w.OnTriggerExit = func(event CollisionEvent) {
+		exits++
+	}
+	collisions := 0
+	w.OnCollisionEnter = func(event CollisionEvent) {
+		collisions++
+	}
+
+	for i := 0; i < 60; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	if enters != 1 || exits != 1 {
+		t.Errorf("box falling through the trigger got %d enters and %d exits, want 1 and 1", enters, exits)
+	}
+	if collisions != 0 {
+		t.Errorf("trigger fired %d collision events", collisions)
+	}
+	if y := box.Transform.Position.Y(); y > -2.0 {
+		t.Errorf("box stopped at y = %v, it should fall straight through the trigger", y)
+	}
+	if y := trigger.Transform.Position.Y(); y != 0.0 {
+		t.Errorf("trigger moved to y = %v", y)
+	}
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The parts of a URDF file that are read, everything else is ignored
+type urdfRobot struct {
+	Name   string      `xml:"name,attr"`
+	Links  []urdfLink  `xml:"link"`
+	Joints []urdfJoint `xml:"joint"`
+}
+
+type urdfOrigin struct {
+	XYZ string `xml:"xyz,attr"`
+	RPY string `xml:"rpy,attr"`
+}
+
+type urdfGeometry struct {
+	Box *struct {
+		Size string `xml:"size,attr"`
+	} `xml:"box"`
+	Cylinder *struct {
+		Radius float32 `xml:"radius,attr"`
+		Length float32 `xml:"length,attr"`
+	} `xml:"cylinder"`
+	Sphere *struct {
+		Radius float32 `xml:"radius,attr"`
+	} `xml:"sphere"`
+	Mesh *struct {
+		Filename string `xml:"filename,attr"`
+		Scale    string `xml:"scale,attr"`
+	} `xml:"mesh"`
+}
+
+// urdfShape is a visual or a collision, a geometry placed in the link's frame
+type urdfShape struct {
+	Origin   *urdfOrigin  `xml:"origin"`
+	Geometry urdfGeometry `xml:"geometry"`
+}
+
+type urdfLink struct {
+	Name     string `xml:"name,attr"`
+	Inertial *struct {
+		Origin *urdfOrigin `xml:"origin"`
+		Mass   struct {
+			Value float32 `xml:"value,attr"`
+		} `xml:"mass"`
+		Inertia struct {
+			Ixx float32 `xml:"ixx,attr"`
+			Ixy float32 `xml:"ixy,attr"`
+			Ixz float32 `xml:"ixz,attr"`
+			Iyy float32 `xml:"iyy,attr"`
+			Iyz float32 `xml:"iyz,attr"`
+			Izz float32 `xml:"izz,attr"`
+		} `xml:"inertia"`
+	} `xml:"inertial"`
+	Visuals    []urdfShape `xml:"visual"`
+	Collisions []urdfShape `xml:"collision"`
+}
+
+type urdfJoint struct {
+	Name   string      `xml:"name,attr"`
+	Type   string      `xml:"type,attr"`
+	Origin *urdfOrigin `xml:"origin"`
+	Parent struct {
+		Link string `xml:"link,attr"`
+	} `xml:"parent"`
+	Child struct {
+		Link string `xml:"link,attr"`
+	} `xml:"child"`
+	Axis *struct {
+		XYZ string `xml:"xyz,attr"`
+	} `xml:"axis"`
+	Limit *struct {
+		Lower float32 `xml:"lower,attr"`
+		Upper float32 `xml:"upper,attr"`
+	} `xml:"limit"`
+	Dynamics *struct {
+		Damping float32 `xml:"damping,attr"`
+	} `xml:"dynamics"`
+}
+
+// Robot is a robot loaded from a URDF file, an Articulation with a link for
+// every link in the file
+type Robot struct {
+	Name         string
+	Articulation *Articulation
+	// Links and Joints by their names in the file, each joint is the
+	// ArticulationLink that it moves
+	Links  map[string]*ArticulationLink
+	Joints map[string]*ArticulationLink
+}
+
+// LoadURDF loads a robot from a URDF file, with its root link at position.
+// URDF is Z up, so the robot is turned to stand up along Y. Revolute,
+// continuous, prismatic and fixed joints are supported. Meshes must be OBJ
+// files, and collide as their convex hulls
+func LoadURDF(filename string, position mgl32.Vec3, fixedBase bool) (*Robot, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	desc := urdfRobot{}
+	if err := xml.Unmarshal(data, &desc); err != nil {
+		return nil, err
+	}
+	if len(desc.Links) == 0 {
+		return nil, fmt.Errorf("URDF '%v' has no links", filename)
+	}
+
+	loader := urdfLoader{dir: filepath.Dir(filename), models: map[string]*Model{}, meshes: map[string]*Model{}}
+	actors := map[string]*Actor{}
+	for _, link := range desc.Links {
+		actor, err := loader.loadLink(link)
+		if err != nil {
+			return nil, fmt.Errorf("URDF link '%v': %v", link.Name, err)
+		}
+		actors[link.Name] = actor
+	}
+
+	// The root is the only link that isn't a joint's child
+	isChild := map[string]bool{}
+	for _, joint := range desc.Joints {
+		if actors[joint.Parent.Link] == nil || actors[joint.Child.Link] == nil {
+			return nil, fmt.Errorf("URDF joint '%v' joins unknown links", joint.Name)
+		}
+		if isChild[joint.Child.Link] {
+			return nil, fmt.Errorf("URDF link '%v' has more than one parent", joint.Child.Link)
+		}
+		isChild[joint.Child.Link] = true
+	}
+	roots := []string{}
+	for _, link := range desc.Links {
+		if !isChild[link.Name] {
+			roots = append(roots, link.Name)
+		}
+	}
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("URDF '%v' should have one root link, found %v", filename, roots)
+	}
+
+	root := actors[roots[0]]
+	root.Transform.Position = position
+	root.Transform.Rotation = mgl32.QuatRotate(-math.Pi/2.0, mgl32.Vec3{1, 0, 0})
+	robot := &Robot{
+		Name:         desc.Name,
+		Articulation: NewArticulation(root, fixedBase),
+		Links:        map[string]*ArticulationLink{},
+		Joints:       map[string]*ArticulationLink{},
+	}
+	robot.Links[roots[0]] = robot.Articulation.Root()
+
+	// Add the joints parents first, so Links stays in order
+	added := true
+	for added {
+		added = false
+		for _, joint := range desc.Joints {
+			parent := robot.Links[joint.Parent.Link]
+			if parent == nil || robot.Joints[joint.Name] != nil {
+				continue
+			}
+			link, err := robot.addJoint(parent, actors[joint.Child.Link], joint)
+			if err != nil {
+				return nil, fmt.Errorf("URDF joint '%v': %v", joint.Name, err)
+			}
+			robot.Links[joint.Child.Link] = link
+			robot.Joints[joint.Name] = link
+			added = true
+		}
+	}
+
+	robot.Articulation.place()
+	for _, link := range robot.Articulation.Links {
+		link.Actor.Transform.Position = link.origin
+		link.Actor.Transform.Rotation = link.rotation
+	}
+	return robot, nil
+}
+
+// addJoint adds the link that joint moves to the Articulation
+func (robot *Robot) addJoint(parent *ArticulationLink, actor *Actor, joint urdfJoint) (*ArticulationLink, error) {
+	types := map[string]JointType{
+		"revolute":   RevoluteJoint,
+		"continuous": RevoluteJoint,
+		"prismatic":  PrismaticJoint,
+		"fixed":      FixedJoint,
+	}
+	jointType, ok := types[joint.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported joint type '%v'", joint.Type)
+	}
+
+	offset, rotation, err := joint.Origin.transform()
+	if err != nil {
+		return nil, err
+	}
+	axis := mgl32.Vec3{1, 0, 0}
+	if joint.Axis != nil {
+		if axis, err = parseURDFVec3(joint.Axis.XYZ, axis); err != nil {
+			return nil, err
+		}
+	}
+	if jointType == FixedJoint {
+		axis = mgl32.Vec3{}
+	} else if axis.Len() == 0.0 {
+		return nil, fmt.Errorf("Joint axis is zero")
+	}
+
+	link := robot.Articulation.AddLink(parent, actor, jointType, offset, axis)
+	link.JointRotation = rotation
+	if joint.Dynamics != nil {
+		link.Damping = joint.Dynamics.Damping
+	}
+	if joint.Limit != nil && joint.Type != "continuous" && jointType != FixedJoint {
+		link.Limited = true
+		link.Lower = joint.Limit.Lower
+		link.Upper = joint.Limit.Upper
+		if link.Position < link.Lower || link.Position > link.Upper {
+			link.Position = link.Lower
+		}
+	}
+	return link, nil
+}
+
+func (w *World) AddRobot(robot *Robot) {
+	for _, link := range robot.Articulation.Links {
+		w.AddActor(link.Actor)
+	}
+	w.AddArticulation(robot.Articulation)
+}
+
+// urdfLoader builds the Actors for the links of one URDF file, sharing the
+// meshes that are used more than once. Meshes only used for collisions are
+// kept apart from the Models that are drawn
+type urdfLoader struct {
+	dir    string
+	models map[string]*Model
+	meshes map[string]*Model
+}
+
+// loadLink creates an Actor for link with its visuals, collisions and inertia
+func (loader *urdfLoader) loadLink(link urdfLink) (*Actor, error) {
+	actor := NewActor()
+	body := actor.RigidBody
+	// Robots are mostly hard and don't bounce
+	body.Restitution = 0.0
+
+	for _, visual := range link.Visuals {
+		model, transform, err := loader.loadVisual(visual)
+		if err != nil {
+			return nil, err
+		}
+		actor.AddModelAt(model, transform)
+	}
+
+	children := []ChildCollider{}
+	for _, collision := range link.Collisions {
+		child, err := loader.loadCollision(collision)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 && children[0].Offset == (mgl32.Vec3{}) {
+		body.Collider = children[0].Collider
+	} else if len(children) > 0 {
+		body.Collider = CompoundCollider{Children: children}
+	}
+
+	if link.Inertial != nil && link.Inertial.Mass.Value > 0.0 {
+		center, rotation, err := link.Inertial.Origin.transform()
+		if err != nil {
+			return nil, err
+		}
+		i := link.Inertial.Inertia
+		inertia := mgl32.Mat3{
+			i.Ixx, i.Ixy, i.Ixz,
+			i.Ixy, i.Iyy, i.Iyz,
+			i.Ixz, i.Iyz, i.Izz,
+		}
+		rot := rotation.Mat4().Mat3()
+		body.Mass = link.Inertial.Mass.Value
+		body.CenterOfMass = center
+		body.Inertia = rot.Mul3(inertia).Mul3(rot.Transpose())
+	} else if body.Collider != nil {
+		// Without any inertia given, the link weighs as much as its
+		// collisions would if they were water
+		body.SetDensity(1000.0)
+	} else {
+		// Links only there to give a name to a frame weigh next to nothing
+		body.Mass = 0.001
+	}
+	return actor, nil
+}
+
+// loadVisual loads the Model of a visual, and the transform that places it in
+// the link's frame
+func (loader *urdfLoader) loadVisual(visual urdfShape) (*Model, mgl32.Mat4, error) {
+	offset, rotation, err := visual.Origin.transform()
+	if err != nil {
+		return nil, mgl32.Mat4{}, err
+	}
+
+	geometry := visual.Geometry
+	var filename string
+	var scale mgl32.Vec3
+	switch {
+	case geometry.Box != nil:
+		size, err := parseURDFVec3(geometry.Box.Size, mgl32.Vec3{})
+		if err != nil {
+			return nil, mgl32.Mat4{}, err
+		}
+		filename, scale = "assets/cube.obj", size.Mul(0.5)
+	case geometry.Cylinder != nil:
+		r, h := geometry.Cylinder.Radius, geometry.Cylinder.Length*0.5
+		filename, scale = "assets/cylinder.obj", mgl32.Vec3{r, r, h}
+	case geometry.Sphere != nil:
+		r := geometry.Sphere.Radius
+		filename, scale = "assets/sphere.obj", mgl32.Vec3{r, r, r}
+	case geometry.Mesh != nil:
+		if filename, err = loader.meshPath(geometry.Mesh.Filename); err != nil {
+			return nil, mgl32.Mat4{}, err
+		}
+		if scale, err = parseURDFVec3(geometry.Mesh.Scale, mgl32.Vec3{1, 1, 1}); err != nil {
+			return nil, mgl32.Mat4{}, err
+		}
+	default:
+		return nil, mgl32.Mat4{}, fmt.Errorf("Visual has no geometry")
+	}
+
+	model, err := loader.loadModel(filename)
+	if err != nil {
+		return nil, mgl32.Mat4{}, err
+	}
+	transform := mgl32.Translate3D(offset.Elem()).
+		Mul4(rotation.Mat4()).
+		Mul4(mgl32.Scale3D(scale.Elem()))
+	return model, transform, nil
+}
+
+// loadCollision builds the collider of a collision, offset in the link's frame.
+// Everything but spheres is turned into a convex hull, as that can be rotated
+func (loader *urdfLoader) loadCollision(collision urdfShape) (ChildCollider, error) {
+	offset, rotation, err := collision.Origin.transform()
+	if err != nil {
+		return ChildCollider{}, err
+	}
+
+	geometry := collision.Geometry
+	points := []mgl32.Vec3{}
+	switch {
+	case geometry.Sphere != nil:
+		return ChildCollider{Offset: offset, Collider: SphereCollider{Radius: geometry.Sphere.Radius}}, nil
+	case geometry.Box != nil:
+		size, err := parseURDFVec3(geometry.Box.Size, mgl32.Vec3{})
+		if err != nil {
+			return ChildCollider{}, err
+		}
+		for i := 0; i < 8; i++ {
+			corner := mgl32.Vec3{float32(i&1) - 0.5, float32(i>>1&1) - 0.5, float32(i>>2&1) - 0.5}
+			points = append(points, mgl32.Vec3{corner[0] * size[0], corner[1] * size[1], corner[2] * size[2]})
+		}
+	case geometry.Cylinder != nil:
+		r, h := geometry.Cylinder.Radius, geometry.Cylinder.Length*0.5
+		for i := 0; i < 16; i++ {
+			angle := float64(i) * math.Pi / 8.0
+			x, y := r*float32(math.Cos(angle)), r*float32(math.Sin(angle))
+			points = append(points, mgl32.Vec3{x, y, -h}, mgl32.Vec3{x, y, h})
+		}
+	case geometry.Mesh != nil:
+		filename, err := loader.meshPath(geometry.Mesh.Filename)
+		if err != nil {
+			return ChildCollider{}, err
+		}
+		scale, err := parseURDFVec3(geometry.Mesh.Scale, mgl32.Vec3{1, 1, 1})
+		if err != nil {
+			return ChildCollider{}, err
+		}
+		mesh, err := loader.loadMesh(filename)
+		if err != nil {
+			return ChildCollider{}, err
+		}
+		for _, v := range mesh.Vertices {
+			points = append(points, mgl32.Vec3{v[0] * scale[0], v[1] * scale[1], v[2] * scale[2]})
+		}
+	default:
+		return ChildCollider{}, fmt.Errorf("Collision has no geometry")
+	}
+
+	for i := range points {
+		points[i] = rotation.Rotate(points[i])
+	}
+	hull, err := NewConvexHullCollider(points)
+	if err != nil {
+		return ChildCollider{}, err
+	}
+	return ChildCollider{Offset: offset, Collider: hull}, nil
+}
+
+// loadModel loads filename the first time it is asked for
+func (loader *urdfLoader) loadModel(filename string) (*Model, error) {
+	if model, ok := loader.models[filename]; ok {
+		return model, nil
+	}
+	model, err := NewModelFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	loader.models[filename] = model
+	return model, nil
+}
+
+// loadMesh loads the Vertices and Triangles of filename, without anything to
+// draw it with unless it is also used by a visual
+func (loader *urdfLoader) loadMesh(filename string) (*Model, error) {
+	if model, ok := loader.models[filename]; ok {
+		return model, nil
+	}
+	if mesh, ok := loader.meshes[filename]; ok {
+		return mesh, nil
+	}
+	mesh, err := NewMeshFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	loader.meshes[filename] = mesh
+	return mesh, nil
+}
+
+// meshPath finds the file a mesh filename refers to. Plain paths are relative
+// to the URDF file. Robot descriptions usually live in a ROS package, so
+// package:// paths are looked for from the URDF's folder upwards
+func (loader *urdfLoader) meshPath(name string) (string, error) {
+	if strings.ToLower(filepath.Ext(name)) != ".obj" {
+		return "", fmt.Errorf("Mesh '%v' isn't an OBJ file", name)
+	}
+
+	name = strings.TrimPrefix(name, "file://")
+	if !strings.HasPrefix(name, "package://") {
+		if filepath.IsAbs(name) {
+			return name, nil
+		}
+		return filepath.Join(loader.dir, name), nil
+	}
+
+	path := strings.TrimPrefix(name, "package://")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("Malformed mesh path '%v'", name)
+	}
+	dir, err := filepath.Abs(loader.dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, path)
+		if filepath.Base(dir) == parts[0] {
+			candidate = filepath.Join(dir, parts[1])
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("Can't find mesh '%v'", name)
+		}
+		dir = parent
+	}
+}
+
+// transform returns the offset and rotation of an origin, which may be missing
+func (origin *urdfOrigin) transform() (mgl32.Vec3, mgl32.Quat, error) {
+	if origin == nil {
+		return mgl32.Vec3{}, mgl32.QuatIdent(), nil
+	}
+	offset, err := parseURDFVec3(origin.XYZ, mgl32.Vec3{})
+	if err != nil {
+		return offset, mgl32.QuatIdent(), err
+	}
+	rpy, err := parseURDFVec3(origin.RPY, mgl32.Vec3{})
+	if err != nil {
+		return offset, mgl32.QuatIdent(), err
+	}
+
+	// Roll, pitch and yaw turn about the fixed X, Y and Z axes in that order
+	rotation := mgl32.QuatRotate(rpy[2], mgl32.Vec3{0, 0, 1}).
+		Mul(mgl32.QuatRotate(rpy[1], mgl32.Vec3{0, 1, 0})).
+		Mul(mgl32.QuatRotate(rpy[0], mgl32.Vec3{1, 0, 0}))
+	return offset, rotation, nil
+}
+
+// parseURDFVec3 parses 3 numbers split by spaces, or returns fallback if s is
+// empty
+func parseURDFVec3(s string, fallback mgl32.Vec3) (mgl32.Vec3, error) {
+	if strings.TrimSpace(s) == "" {
+		return fallback, nil
+	}
+	v := mgl32.Vec3{}
+	count, err := fmt.Sscanf(s, "%f %f %f", &v[0], &v[1], &v[2])
+	if err != nil || count != 3 {
+		return fallback, fmt.Errorf("Malformed vector '%v'", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestParseURDFVec3(t *testing.T) {
+	fallback := mgl32.Vec3{7, 8, 9}
+	tests := []struct {
+		s    string
+		want mgl32.Vec3
+		err  bool
+	}{
+		{"1 2 3", mgl32.Vec3{1, 2, 3}, false},
+		{"  -0.5 1e2 .25 ", mgl32.Vec3{-0.5, 100, 0.25}, false},
+		{"", fallback, false},
+		{"   ", fallback, false},
+		{"1 2", fallback, true},
+		{"a b c", fallback, true},
+	}
+	for _, test := range tests {
+		got, err := parseURDFVec3(test.s, fallback)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("parseURDFVec3(%q) = %v, %v, want %v and an error: %v", test.s, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestURDFOriginTransform(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin *urdfOrigin
+		offset mgl32.Vec3
+		// Where the X and Y axes end up
+		x, y mgl32.Vec3
+		err  bool
+	}{
+		{"missing", nil, mgl32.Vec3{}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 1, 0}, false},
+		{"offset", &urdfOrigin{XYZ: "1 2 3"}, mgl32.Vec3{1, 2, 3}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 1, 0}, false},
+		{"roll", &urdfOrigin{RPY: "1.5707963 0 0"}, mgl32.Vec3{}, mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, 1}, false},
+		{"pitch", &urdfOrigin{RPY: "0 1.5707963 0"}, mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, 1, 0}, false},
+		{"yaw", &urdfOrigin{RPY: "0 0 1.5707963"}, mgl32.Vec3{}, mgl32.Vec3{0, 1, 0}, mgl32.Vec3{-1, 0, 0}, false},
+		// Roll first, then pitch, about the fixed axes
+		{"roll then pitch", &urdfOrigin{RPY: "1.5707963 1.5707963 0"}, mgl32.Vec3{}, mgl32.Vec3{0, 0, -1}, mgl32.Vec3{1, 0, 0}, false},
+		{"bad xyz", &urdfOrigin{XYZ: "1 2"}, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, true},
+		{"bad rpy", &urdfOrigin{RPY: "x"}, mgl32.Vec3{}, mgl32.Vec3{}, mgl32.Vec3{}, true},
+	}
+	for _, test := range tests {
+		offset, rotation, err := test.origin.transform()
+		if (err != nil) != test.err {
+			t.Errorf("%s: err = %v, want an error: %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		x, y := rotation.Rotate(mgl32.Vec3{1, 0, 0}), rotation.Rotate(mgl32.Vec3{0, 1, 0})
+		if offset != test.offset || x.Sub(test.x).Len() > 1e-5 || y.Sub(test.y).Len() > 1e-5 {
+			t.Errorf("%s: offset %v turning X to %v and Y to %v, want %v, %v and %v", test.name, offset, x, y, test.offset, test.x, test.y)
+		}
+	}
+}
+
+// writeTestURDF writes a URDF file to a temporary folder, and returns its path
+func writeTestURDF(t *testing.T, urdf string) string {
+	dir, err := ioutil.TempDir("", "urdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	filename := filepath.Join(dir, "robot.urdf")
+	if err := ioutil.WriteFile(filename, []byte(urdf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadURDF(t *testing.T) {
+	// Collisions only, as visuals need a GL context to load
+	filename := writeTestURDF(t, `<?xml version="1.0"?>
+<robot name="pole">
+  <link name="base">
+    <collision><geometry><box size="2 2 1"/></geometry></collision>
+  </link>
+  <joint name="hinge" type="revolute">
+    <parent link="base"/>
+    <child link="pole"/>
+    <origin xyz="0 0 1"/>
+    <axis xyz="0 1 0"/>
+    <limit lower="0.5" upper="1"/>
+    <dynamics damping="3"/>
+  </joint>
+  <link name="pole">
+    <inertial>
+      <origin xyz="0 0 2"/>
+      <mass value="4"/>
+      <inertia ixx="1" iyy="2" izz="3" ixy="0" ixz="0" iyz="0"/>
+    </inertial>
+    <collision>
+      <origin xyz="0 0 2"/>
+      <geometry><sphere radius="0.5"/></geometry>
+    </collision>
+  </link>
+  <joint name="tip_joint" type="fixed">
+    <parent link="pole"/>
+    <child link="tip"/>
+    <origin xyz="0 0 4"/>
+  </joint>
+  <link name="tip"/>
+</robot>`)
+
+	robot, err := LoadURDF(filename, mgl32.Vec3{0, 1, 0}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if robot.Name != "pole" || len(robot.Articulation.Links) != 3 || len(robot.Joints) != 2 {
+		t.Fatalf("loaded %q with %d links and %d joints", robot.Name, len(robot.Articulation.Links), len(robot.Joints))
+	}
+
+	hinge := robot.Joints["hinge"]
+	if hinge != robot.Links["pole"] || hinge.Joint != RevoluteJoint || hinge.Damping != 3 {
+		t.Errorf("hinge is a %v joint with damping %v", hinge.Joint, hinge.Damping)
+	}
+	// It starts at its lower limit, as 0 is outside of them
+	if !hinge.Limited || hinge.Lower != 0.5 || hinge.Upper != 1 || hinge.Position != 0.5 {
+		t.Errorf("hinge limited %v from %v to %v, at %v", hinge.Limited, hinge.Lower, hinge.Upper, hinge.Position)
+	}
+	if body := hinge.Actor.RigidBody; body.Mass != 4 || body.CenterOfMass != (mgl32.Vec3{0, 0, 2}) {
+		t.Errorf("pole has mass %v at %v", body.Mass, body.CenterOfMass)
+	}
+	if tip := robot.Links["tip"].Actor.RigidBody; tip.Mass != 0.001 || tip.Collider != nil {
+		t.Errorf("empty link has mass %v and collider %v", tip.Mass, tip.Collider)
+	}
+	if base := robot.Links["base"].Actor.RigidBody; base.Mass != math.MaxFloat32 {
+		t.Errorf("fixed base has mass %v", base.Mass)
+	}
+
+	// Z up in the file is Y up in the World, and the hinge leans the pole
+	// over by its angle
+	if p := hinge.Actor.Transform.Position; p.Sub(mgl32.Vec3{0, 2, 0}).Len() > 1e-5 {
+		t.Errorf("hinge is at %v, want (0, 2, 0)", p)
+	}
+	s, c := float32(math.Sin(0.5)), float32(math.Cos(0.5))
+	want := mgl32.Vec3{4 * s, 2 + 4*c, 0}
+	if p := robot.Links["tip"].Actor.Transform.Position; p.Sub(want).Len() > 1e-4 {
+		t.Errorf("tip is at %v, want %v", p, want)
+	}
+}
+
+func TestLoadURDFErrors(t *testing.T) {
+	link := func(name string) string {
+		return `<link name="` + name + `"/>`
+	}
+	joint := func(name, kind, parent, child string) string {
+		return `<joint name="` + name + `" type="` + kind + `"><parent link="` + parent + `"/><child link="` + child + `"/></joint>`
+	}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no links", ``},
+		{"two roots", link("a") + link("b")},
+		{"unknown link", link("a") + joint("j", "fixed", "a", "b")},
+		{"two parents", link("a") + link("b") + link("c") + joint("j", "fixed", "a", "c") + joint("k", "fixed", "b", "c")},
+		{"unsupported joint", link("a") + link("b") + joint("j", "floating", "a", "b")},
+		{"zero axis", link("a") + link("b") + `<joint name="j" type="revolute"><parent link="a"/><child link="b"/><axis xyz="0 0 0"/></joint>`},
+		{"no geometry", `<link name="a"><collision/></link>`},
+		{"not a mesh", `<link name="a"><collision><geometry><mesh filename="a.stl"/></geometry></collision></link>`},
+	}
+	for _, test := range tests {
+		filename := writeTestURDF(t, `<robot name="r">`+test.body+`</robot>`)
+		if _, err := LoadURDF(filename, mgl32.Vec3{}, true); err == nil {
+			t.Errorf("%s: loaded without an error", test.name)
+		}
+	}
+
+	if _, err := LoadURDF(filepath.Join(os.TempDir(), "missing.urdf"), mgl32.Vec3{}, true); err == nil {
+		t.Errorf("loaded a missing file")
+	}
+}
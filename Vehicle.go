@@ -0,0 +1,386 @@
+package main
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// TireCurve is a simplified Pacejka "magic formula", giving the grip of a
+// tire as a fraction of the load on it for a given slip
+type TireCurve struct {
+	// Stiffness is how quickly grip builds up with slip
+	Stiffness float32
+	// Shape controls how much grip drops off past the peak
+	Shape float32
+	// Peak is the most grip the tire can have
+	Peak float32
+}
+
+// Evaluate returns the grip at slip, with the same sign as slip
+func (curve TireCurve) Evaluate(slip float32) float32 {
+	return curve.Peak * float32(math.Sin(float64(curve.Shape)*math.Atan(float64(curve.Stiffness*slip))))
+}
+
+// Wheel is a ray cast down from the chassis of a Vehicle, held up by a
+// spring
+type Wheel struct {
+	// Position the suspension hangs from, in the chassis' local space
+	Position mgl32.Vec3
+	Radius   float32
+	// Mass of the wheel, which only affects how quickly it spins up
+	Mass float32
+
+	// SuspensionLength is the furthest the wheel hangs below Position
+	SuspensionLength    float32
+	SuspensionStiffness float32
+	SuspensionDamping   float32
+
+	// Steered wheels turn with Vehicle.Steering, and Driven wheels are
+	// turned by the engine
+	Steered bool
+	Driven  bool
+
+	// Grounded is whether the wheel touched Ground last Update, at
+	// ContactPoint
+	Grounded      bool
+	Ground        *RigidBody
+	ContactPoint  mgl32.Vec3
+	ContactNormal mgl32.Vec3
+	// Compression is how far the suspension is pushed in, from 0 to
+	// SuspensionLength, and SuspensionForce how hard it pushes back
+	Compression     float32
+	SuspensionForce float32
+	// SteerAngle is in radians, SpinVelocity is about the axle in the same
+	// units as AngularVelocity, and Rotation is the total spin for drawing
+	SteerAngle   float32
+	SpinVelocity float32
+	Rotation     float32
+	// SlipRatio is how much faster the tire turns than the ground moves, and
+	// SlipAngle is the angle between where it points and where it's going
+	SlipRatio float32
+	SlipAngle float32
+
+	// suspension pushes the chassis at ContactPoint and grip at gripPoint,
+	// once every wheel has worked out its own
+	suspension mgl32.Vec3
+	grip       mgl32.Vec3
+	gripPoint  mgl32.Vec3
+}
+
+// Vehicle is a chassis RigidBody carried on raycast wheels. The chassis'
+// Actor must be added to the World separately
+type Vehicle struct {
+	Chassis *RigidBody
+	Wheels  []*Wheel
+	// Forward and Up are the directions of the chassis, in local space
+	Forward mgl32.Vec3
+	Up      mgl32.Vec3
+
+	// Throttle from -1 for full reverse to 1 for full power, Brake from 0 to
+	// 1, and Steering from -1 to 1, turning anti-clockwise about Up
+	Throttle float32
+	Brake    float32
+	Steering float32
+
+	// MaxSteerAngle is how far Steered wheels turn, in radians
+	MaxSteerAngle float32
+	// EngineTorque is shared between the Driven wheels, BrakeTorque is for
+	// each wheel
+	EngineTorque float32
+	BrakeTorque  float32
+
+	// LongitudinalFriction is the grip along the tire for a SlipRatio, and
+	// LateralFriction the grip across it for a SlipAngle
+	LongitudinalFriction TireCurve
+	LateralFriction      TireCurve
+	// RollInfluence is how much grip tips the chassis, from 0 for as if it
+	// pushed the center of mass to 1 for pushing where the tire touches
+	RollInfluence float32
+
+	// CollisionMask has a bit set for every layer the wheels touch
+	CollisionMask uint32
+
+	// WheelModel is drawn for every wheel, scaled to its radius
+	WheelModel *Model
+}
+
+// NewVehicle creates a Vehicle with no wheels, driving along Z
+func NewVehicle(chassis *RigidBody) *Vehicle {
+	return &Vehicle{
+		Chassis: chassis,
+		Wheels:  []*Wheel{},
+		Forward: mgl32.Vec3{0, 0, 1},
+		Up:      mgl32.Vec3{0, 1, 0},
+
+		MaxSteerAngle: mgl32.DegToRad(30.0),
+		EngineTorque:  chassis.Mass,
+		BrakeTorque:   chassis.Mass,
+
+		LongitudinalFriction: TireCurve{Stiffness: 10.0, Shape: 1.9, Peak: 1.0},
+		LateralFriction:      TireCurve{Stiffness: 8.0, Shape: 1.3, Peak: 1.0},
+		RollInfluence:        0.1,
+
+		CollisionMask: AllLayers,
+	}
+}
+
+// AddWheel adds a wheel hanging from position in the chassis' local space.
+// The suspension defaults to holding up a quarter of the chassis' weight
+// halfway along its length
+func (vehicle *Vehicle) AddWheel(position mgl32.Vec3, radius float32, steered, driven bool) *Wheel {
+	share := vehicle.Chassis.Mass / 4.0
+	wheel := &Wheel{
+		Position:         position,
+		Radius:           radius,
+		Mass:             share * 0.1,
+		SuspensionLength: radius,
+		Steered:          steered,
+		Driven:           driven,
+	}
+	wheel.SuspensionStiffness = share * 9.81 / (wheel.SuspensionLength * 0.5)
+	wheel.SuspensionDamping = wheel.SuspensionStiffness * wheel.SuspensionLength
+	vehicle.Wheels = append(vehicle.Wheels, wheel)
+	return wheel
+}
+
+// Speed returns how fast the chassis is moving forwards
+func (vehicle *Vehicle) Speed() float32 {
+	transform := &vehicle.Chassis.Parent.Transform
+	return vehicle.Chassis.Velocity.Dot(transform.Rotation.Rotate(vehicle.Forward).Normalize())
+}
+
+// Update casts every wheel against the world, and pushes the chassis with
+// their suspension and tire forces
+func (vehicle *Vehicle) Update(w *World, delta, elapsed float32) {
+	if elapsed <= 0.0 {
+		return
+	}
+
+	driven := 0
+	for _, wheel := range vehicle.Wheels {
+		if wheel.Driven {
+			driven++
+		}
+	}
+
+	for _, wheel := range vehicle.Wheels {
+		torque := float32(0.0)
+		if wheel.Driven {
+			torque = vehicle.EngineTorque * vehicle.Throttle / float32(driven)
+		}
+		wheel.SteerAngle = 0.0
+		if wheel.Steered {
+			wheel.SteerAngle = vehicle.Steering * vehicle.MaxSteerAngle
+		}
+
+		vehicle.updateWheel(w, wheel, torque, elapsed)
+		wheel.Rotation += wheel.SpinVelocity * delta
+	}
+
+	// Every wheel measures the chassis before any of them push it, otherwise
+	// the first ones spin it and the rest see the wrong velocity
+	for _, wheel := range vehicle.Wheels {
+		if !wheel.Grounded {
+			continue
+		}
+		vehicle.Chassis.ApplyForceAtPosition(wheel.suspension.Mul(elapsed), wheel.ContactPoint, Impulse)
+		vehicle.Chassis.ApplyForceAtPosition(wheel.grip.Mul(elapsed), wheel.gripPoint, Impulse)
+		if wheel.Ground.InverseMass() != 0.0 {
+			wheel.Ground.ApplyForceAtPosition(wheel.suspension.Add(wheel.grip).Mul(-elapsed), wheel.ContactPoint, Impulse)
+		}
+	}
+}
+
+// updateWheel spins a wheel up with the engine and down with the brakes, then
+// finds where it touches the ground and the force it pushes the chassis with
+func (vehicle *Vehicle) updateWheel(w *World, wheel *Wheel, torque, elapsed float32) {
+	// Turning the wheel first lets the tire force below hold it back in the
+	// same step, rather than it running away past the peak of the curve
+	inertia := 0.5 * wheel.Mass * wheel.Radius * wheel.Radius
+	wheel.SpinVelocity += torque / inertia * elapsed
+	braking := vehicle.BrakeTorque * vehicle.Brake / inertia * elapsed
+	if float32(math.Abs(float64(wheel.SpinVelocity))) <= braking {
+		wheel.SpinVelocity = 0.0
+	} else if wheel.SpinVelocity > 0.0 {
+		wheel.SpinVelocity -= braking
+	} else {
+		wheel.SpinVelocity += braking
+	}
+
+	chassis := vehicle.Chassis
+	transform := &chassis.Parent.Transform
+	up := transform.Rotation.Rotate(vehicle.Up).Normalize()
+	origin := transform.Position.Add(transform.Rotation.Rotate(wheel.Position))
+
+	wheel.Grounded = false
+	wheel.Ground = nil
+	wheel.Compression = 0.0
+	wheel.SuspensionForce = 0.0
+	wheel.SlipRatio = 0.0
+	wheel.SlipAngle = 0.0
+	wheel.suspension = mgl32.Vec3{}
+	wheel.grip = mgl32.Vec3{}
+
+	var hit RaycastHit
+	for _, h := range w.RaycastAll(origin, up.Mul(-1.0), wheel.SuspensionLength+wheel.Radius, vehicle.CollisionMask) {
+		if h.Body != chassis {
+			hit = h
+			wheel.Grounded = true
+			break
+		}
+	}
+	if !wheel.Grounded {
+		return
+	}
+
+	wheel.Ground = hit.Body
+	wheel.ContactPoint = hit.Point
+	wheel.ContactNormal = hit.Normal
+	normal := hit.Normal
+
+	// How fast the chassis is moving over the ground, where the wheel is
+	velocity := pointVelocity(chassis, hit.Point).Sub(pointVelocity(hit.Body, hit.Point))
+
+	compression := wheel.SuspensionLength - (hit.Distance - wheel.Radius)
+	wheel.Compression = float32(math.Min(math.Max(float64(compression), 0.0), float64(wheel.SuspensionLength)))
+	load := wheel.SuspensionStiffness*wheel.Compression - wheel.SuspensionDamping*velocity.Dot(normal)
+	if load <= 0.0 {
+		return
+	}
+	wheel.SuspensionForce = load
+
+	// Directions along and across the tire, flat against the ground
+	forward := transform.Rotation.Rotate(vehicle.Forward)
+	forward = mgl32.QuatRotate(wheel.SteerAngle, up).Rotate(forward)
+	forward = forward.Sub(normal.Mul(forward.Dot(normal)))
+	if forward.Len() == 0.0 {
+		return
+	}
+	forward = forward.Normalize()
+	side := normal.Cross(forward)
+
+	// Grip pushes from part way up to the center of mass, so hard cornering
+	// doesn't tip the chassis over and set it rocking
+	rise := chassis.WorldCenterOfMass().Sub(hit.Point).Dot(normal) * (1.0 - vehicle.RollInfluence)
+	wheel.gripPoint = hit.Point.Add(normal.Mul(rise))
+
+	// Slip is measured against a minimum speed, so it doesn't blow up when
+	// stopped
+	const minSpeed = 0.05
+	long := velocity.Dot(forward)
+	lat := velocity.Dot(side)
+	speed := float32(math.Max(math.Abs(float64(long)), minSpeed))
+	wheel.SlipRatio = (wheel.SpinVelocity*wheel.Radius - long) / speed
+	wheel.SlipAngle = float32(math.Atan2(float64(lat), float64(speed)))
+
+	fx := vehicle.LongitudinalFriction.Evaluate(wheel.SlipRatio) * load
+	fy := -vehicle.LateralFriction.Evaluate(wheel.SlipAngle) * load
+
+	// Don't push harder than it takes to stop slipping this step, or the
+	// tire overshoots and jitters at low speed. Sideways, every wheel shares
+	// the work of stopping the chassis
+	alongMass := wheel.Radius*wheel.Radius/inertia + pointInverseMass(chassis, wheel.gripPoint, forward)
+	acrossMass := pointInverseMass(chassis, wheel.gripPoint, side) * float32(len(vehicle.Wheels))
+	stick := (wheel.SpinVelocity*wheel.Radius - long) / (alongMass * elapsed)
+	if math.Abs(float64(stick)) <= float64(vehicle.LongitudinalFriction.Peak*load) {
+		// The wheel spins up far quicker than the chassis, so while the tire
+		// has the grip it rolls without slipping, and only follows the curve
+		// once the engine or brakes overpower it
+		fx = stick
+	} else {
+		fx = clampMagnitude(fx, stick)
+	}
+	if acrossMass > 0.0 {
+		fy = clampMagnitude(fy, -lat/(acrossMass*elapsed))
+	}
+
+	// The tire only has so much grip to share between both directions
+	peak := float32(math.Max(float64(vehicle.LongitudinalFriction.Peak), float64(vehicle.LateralFriction.Peak))) * load
+	if total := float32(math.Sqrt(float64(fx*fx + fy*fy))); total > peak {
+		fx *= peak / total
+		fy *= peak / total
+	}
+
+	wheel.suspension = normal.Mul(load)
+	wheel.grip = forward.Mul(fx).Add(side.Mul(fy))
+
+	// The road pushes back on the tire as hard as the tire pushes the road
+	wheel.SpinVelocity -= fx * wheel.Radius / inertia * elapsed
+	wheel.SlipRatio = (wheel.SpinVelocity*wheel.Radius - long) / speed
+}
+
+// Render draws WheelModel where every wheel hangs, turned by its steering and
+// spin. The chassis is drawn by its own Actor
+func (vehicle *Vehicle) Render(shader *Shader) {
+	if vehicle.WheelModel == nil {
+		return
+	}
+
+	shader.Use()
+	transform := &vehicle.Chassis.Parent.Transform
+	axle := vehicle.Up.Cross(vehicle.Forward).Normalize()
+	for _, wheel := range vehicle.Wheels {
+		drop := wheel.SuspensionLength
+		if wheel.Grounded {
+			drop -= wheel.Compression
+		}
+		hub := wheel.Position.Sub(vehicle.Up.Normalize().Mul(drop))
+
+		t := NewTransform()
+		t.Position = transform.Position.Add(transform.Rotation.Rotate(hub))
+		t.Rotation = transform.Rotation.
+			Mul(mgl32.QuatRotate(wheel.SteerAngle, vehicle.Up.Normalize())).
+			Mul(mgl32.QuatRotate(wheel.Rotation, axle))
+		// Squashed along the axle, so a sphere looks like a tire
+		t.Scale = mgl32.Vec3{wheel.Radius, wheel.Radius, wheel.Radius}.Sub(axle.Mul(wheel.Radius * 0.6))
+
+		model := t.GetMatrix()
+		gl.UniformMatrix4fv(shader.GetUniformLocation("u_Model"), 1, false, &model[0])
+		vehicle.WheelModel.Render(shader)
+	}
+}
+
+// pointVelocity returns the velocity of a point on a body
+func pointVelocity(rb *RigidBody, point mgl32.Vec3) mgl32.Vec3 {
+	if rb.InverseMass() == 0.0 {
+		return mgl32.Vec3{}
+	}
+	return rb.Velocity.Add(rb.AngularVelocity.Cross(point.Sub(rb.WorldCenterOfMass())))
+}
+
+// pointInverseMass returns how easily a point on a body moves along dir,
+// including the body turning about its center of mass
+func pointInverseMass(rb *RigidBody, point, dir mgl32.Vec3) float32 {
+	if rb.InverseMass() == 0.0 {
+		return 0.0
+	}
+	arm := point.Sub(rb.WorldCenterOfMass()).Cross(dir)
+	return rb.InverseMass() + arm.Dot(rb.InverseInertia().Mul3x1(arm))
+}
+
+// clampMagnitude limits value to the size of limit, keeping its sign but
+// dropping to 0 if limit has the opposite sign
+func clampMagnitude(value, limit float32) float32 {
+	if value*limit <= 0.0 {
+		return 0.0
+	}
+	if math.Abs(float64(value)) > math.Abs(float64(limit)) {
+		return limit
+	}
+	return value
+}
+
+func (w *World) AddVehicle(vehicle *Vehicle) {
+	w.Vehicles = append(w.Vehicles, vehicle)
+}
+
+func (w *World) RemoveVehicle(vehicle *Vehicle) {
+	for i := range w.Vehicles {
+		if w.Vehicles[i] == vehicle {
+			w.Vehicles = append(w.Vehicles[:i], w.Vehicles[i+1:]...)
+			return
+		}
+	}
+}
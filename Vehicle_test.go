@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestTireCurve(t *testing.T) {
+	curve := TireCurve{Stiffness: 10, Shape: 1.9, Peak: 1}
+	// The magic formula peaks where atan(Stiffness*slip) = pi/(2*Shape)
+	peakSlip := float32(math.Tan(math.Pi/(2*1.9))) / 10
+
+	tests := []struct {
+		name string
+		slip float32
+		want float32
+	}{
+		{"no slip", 0, 0},
+		{"peak", peakSlip, 1},
+		{"reverse peak", -peakSlip, -1},
+		{"sliding", 100, float32(math.Sin(1.9 * math.Atan(1000)))},
+	}
+	for _, test := range tests {
+		if got := curve.Evaluate(test.slip); math.Abs(float64(got-test.want)) > 1e-4 {
+			t.Errorf("%s: Evaluate(%v) = %v, want %v", test.name, test.slip, got, test.want)
+		}
+	}
+
+	// Grip builds up to the peak, then drops off
+	if curve.Evaluate(peakSlip/2) >= 1 || curve.Evaluate(peakSlip*4) >= 1 {
+		t.Errorf("grip away from the peak reaches the peak")
+	}
+}
+
+func TestClampMagnitude(t *testing.T) {
+	tests := []struct {
+		value, limit, want float32
+	}{
+		{1, 2, 1},
+		{3, 2, 2},
+		{-3, -2, -2},
+		{-1, -2, -1},
+		{1, -2, 0},
+		{-1, 2, 0},
+		{1, 0, 0},
+	}
+	for _, test := range tests {
+		if got := clampMagnitude(test.value, test.limit); got != test.want {
+			t.Errorf("clampMagnitude(%v, %v) = %v, want %v", test.value, test.limit, got, test.want)
+		}
+	}
+}
+
+// newTestVehicle adds a four wheel drive car to the World, with its chassis
+// at position
+func newTestVehicle(w *World, position mgl32.Vec3) *Vehicle {
+	chassis := newTestBox(w, position, mgl32.Vec3{2, 1, 4})
+	chassis.RigidBody.SetDensity(0.2)
+	vehicle := NewVehicle(chassis.RigidBody)
+	vehicle.EngineTorque = chassis.RigidBody.Mass * 4
+	vehicle.BrakeTorque = chassis.RigidBody.Mass * 4
+	for _, x := range []float32{-2.2, 2.2} {
+		vehicle.AddWheel(mgl32.Vec3{x, -1, 3}, 1.2, true, true)
+		vehicle.AddWheel(mgl32.Vec3{x, -1, -3}, 1.2, false, true)
+	}
+	w.AddVehicle(vehicle)
+	return vehicle
+}
+
+func TestVehicleRests(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	vehicle := newTestVehicle(w, mgl32.Vec3{0, 3, 0})
+	for i := 0; i < 300; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	// The chassis hangs on its suspension, with the wheels sharing its weight
+	position := vehicle.Chassis.Parent.Transform.Position
+	if math.Abs(float64(position.X())) > 1e-3 || math.Abs(float64(position.Z())) > 1e-3 {
+		t.Errorf("chassis drifted to %v", position)
+	}
+	total := float32(0.0)
+	for i, wheel := range vehicle.Wheels {
+		if !wheel.Grounded || wheel.Compression <= 0 || wheel.Compression >= wheel.SuspensionLength {
+			t.Errorf("wheel %d: grounded %v, compressed by %v of %v", i, wheel.Grounded, wheel.Compression, wheel.SuspensionLength)
+		}
+		total += wheel.SuspensionForce
+	}
+	weight := vehicle.Chassis.Mass * 9.81
+	if math.Abs(float64(total-weight)) > 1e-3*float64(weight) {
+		t.Errorf("suspension holds up %v, want the weight %v", total, weight)
+	}
+
+	w.Update(1.0, 1.0/60.0)
+	if moved := vehicle.Chassis.Parent.Transform.Position.Sub(position).Len(); moved > 1e-4 {
+		t.Errorf("chassis is still moving by %v a frame", moved)
+	}
+}
+
+func TestVehicleDrives(t *testing.T) {
+	w := NewWorld()
+	newTestFloor(w)
+	vehicle := newTestVehicle(w, mgl32.Vec3{0, 3, 0})
+	for i := 0; i < 60; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+
+	vehicle.Throttle = 1
+	for i := 0; i < 30; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	position := vehicle.Chassis.Parent.Transform.Position
+	if vehicle.Speed() <= 0.1 || position.Z() <= 1 || position.Y() < 2 {
+		t.Fatalf("chassis at %v moving at %v, want it driving forwards along z", position, vehicle.Speed())
+	}
+	if math.Abs(float64(position.X())) > 0.1 {
+		t.Errorf("chassis drifted sideways to %v", position)
+	}
+
+	vehicle.Throttle = 0
+	vehicle.Brake = 1
+	for i := 0; i < 300; i++ {
+		w.Update(1.0, 1.0/60.0)
+	}
+	if speed := vehicle.Speed(); math.Abs(float64(speed)) > 0.01 {
+		t.Errorf("chassis still moving at %v after braking", speed)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"sort"
+)
+
+// SimulationStats holds counters from the most recent World.Update
+type SimulationStats struct {
+	Manifolds int
+	Contacts  int
+	// Contacts that were matched to one from the previous frame, and warm
+	// started with its impulse
+	CacheHits   int
+	CacheMisses int
+}
+
+// CacheHitRate returns the fraction of contacts that were warm started
+func (stats SimulationStats) CacheHitRate() float32 {
+	if stats.Contacts == 0 {
+		return 0.0
+	}
+	return float32(stats.CacheHits) / float32(stats.Contacts)
+}
+
+type bodyPair struct {
+	A *RigidBody
+	B *RigidBody
+}
+
+// World owns the Actors being simulated, and resolves collisions between them
+type World struct {
+	Actors          []*Actor
+	ForceFields     []ForceField
+	Cloths          []*Cloth
+	SoftBodies      []*SoftBody
+	Ropes           []*Rope
+	Fluids          []*Fluid
+	ParticleSystems []*ParticleSystem
+	Vehicles        []*Vehicle
+	Characters      []*CharacterController
+	Articulations   []*Articulation
+	Sensors         []Sensor
+
+	// Number of times the contact solver iterates over every contact
+	SolverIterations int
+	// Seed the solver with the impulses found last frame
+	WarmStarting bool
+
+	// NBodyGravity makes every body attract every other body, based on their
+	// Mass, approximated with a Barnes-Hut tree
+	NBodyGravity          bool
+	GravitationalConstant float32
+	// How far away a group of bodies has to be, relative to its size, before
+	// it is treated as a single body. 0 is exact, higher is faster
+	BarnesHutTheta float32
+	// Stops the force from exploding when two bodies pass through each other
+	GravitySoftening float32
+
+	Stats SimulationStats
+
+	// InstancedShader draws the particles of Fluids and ParticleSystems,
+	// they aren't drawn without it
+	InstancedShader *Shader
+
+	// Called for every pair of bodies that starts touching, keeps touching,
+	// or stops touching, in addition to the callbacks on each RigidBody
+	OnCollisionEnter CollisionFunc
+	OnCollisionStay  CollisionFunc
+	OnCollisionExit  CollisionFunc
+
+	// Called for every pair of bodies that starts or stops overlapping, where
+	// either of them is a trigger
+	OnTriggerEnter CollisionFunc
+	OnTriggerExit  CollisionFunc
+
+	manifolds map[bodyPair]*ContactManifold
+	active    []*ContactManifold
+
+	triggers map[bodyPair]*ContactManifold
+	overlaps []*ContactManifold
+
+	broadphase      *Broadphase
+	broadphaseDirty bool
+
+	octree *Octree
+}
+
+// NewWorld creates a new World with appropriate defaults
+func NewWorld() *World {
+	return &World{
+		Actors:           []*Actor{},
+		ForceFields:      []ForceField{},
+		Cloths:           []*Cloth{},
+		SoftBodies:       []*SoftBody{},
+		Ropes:            []*Rope{},
+		Fluids:           []*Fluid{},
+		ParticleSystems:  []*ParticleSystem{},
+		Vehicles:         []*Vehicle{},
+		Characters:       []*CharacterController{},
+		Articulations:    []*Articulation{},
+		Sensors:          []Sensor{},
+		SolverIterations: 20,
+		WarmStarting:     true,
+
+		GravitationalConstant: 1.0,
+		BarnesHutTheta:        0.5,
+		GravitySoftening:      1.0,
+		manifolds:             map[bodyPair]*ContactManifold{},
+		active:                []*ContactManifold{},
+		triggers:              map[bodyPair]*ContactManifold{},
+		overlaps:              []*ContactManifold{},
+		broadphase:            NewBroadphase(),
+		octree:                NewOctree(),
+	}
+}
+
+// Cleanup frees up resources
+func (w *World) Cleanup() {
+	for i := range w.Actors {
+		w.Actors[i].Cleanup()
+		w.Actors[i] = nil
+	}
+	w.Actors = []*Actor{}
+	for i := range w.Cloths {
+		w.Cloths[i].Cleanup()
+		w.Cloths[i] = nil
+	}
+	w.Cloths = []*Cloth{}
+	for i := range w.SoftBodies {
+		w.SoftBodies[i].Cleanup()
+		w.SoftBodies[i] = nil
+	}
+	w.SoftBodies = []*SoftBody{}
+	for i := range w.Ropes {
+		w.Ropes[i].Cleanup()
+		w.Ropes[i] = nil
+	}
+	w.Ropes = []*Rope{}
+	for i := range w.Fluids {
+		w.Fluids[i].Cleanup()
+		w.Fluids[i] = nil
+	}
+	w.Fluids = []*Fluid{}
+	for i := range w.ParticleSystems {
+		w.ParticleSystems[i].Cleanup()
+		w.ParticleSystems[i] = nil
+	}
+	w.ParticleSystems = []*ParticleSystem{}
+	w.Vehicles = []*Vehicle{}
+	w.Characters = []*CharacterController{}
+	w.Articulations = []*Articulation{}
+	w.Sensors = []Sensor{}
+	w.manifolds = map[bodyPair]*ContactManifold{}
+	w.active = []*ContactManifold{}
+	w.triggers = map[bodyPair]*ContactManifold{}
+	w.overlaps = []*ContactManifold{}
+	w.broadphaseDirty = true
+}
+
+func (w *World) AddActor(actor *Actor) {
+	w.Actors = append(w.Actors, actor)
+	w.broadphaseDirty = true
+}
+
+// updateBroadphase rebuilds the Broadphase if Actors have been added since it
+// was last built. Queries see bodies where they were at the last Update
+func (w *World) updateBroadphase() {
+	if w.broadphaseDirty {
+		w.broadphase.Build(w.Actors)
+		w.broadphaseDirty = false
+	}
+}
+
+func (w *World) Update(delta, elapsed float32) {
+	w.applyForceFields(elapsed)
+	if w.NBodyGravity {
+		w.applyNBodyGravity(elapsed)
+	}
+	for i := range w.Vehicles {
+		w.Vehicles[i].Update(w, delta, elapsed)
+	}
+	for i := range w.Characters {
+		w.Characters[i].Update(w, delta, elapsed)
+	}
+
+	for i := 0; i < len(w.Actors); i++ {
+		w.Actors[i].Update(delta, elapsed)
+	}
+	// Articulations move their links to where their joints allow, which
+	// overrides where the links moved to on their own
+	for i := range w.Articulations {
+		w.Articulations[i].Update(delta, elapsed)
+	}
+
+	w.Stats = SimulationStats{}
+	manifolds := map[bodyPair]*ContactManifold{}
+	active := []*ContactManifold{}
+	triggers := map[bodyPair]*ContactManifold{}
+	overlaps := []*ContactManifold{}
+
+	w.broadphase.Build(w.Actors)
+	w.broadphaseDirty = false
+
+	links := map[*RigidBody]bool{}
+	for _, articulation := range w.Articulations {
+		for _, link := range articulation.Links {
+			links[link.Actor.RigidBody] = true
+		}
+	}
+
+	candidates := []int{}
+	for i := 0; i < len(w.Actors); i++ {
+		a := w.Actors[i].RigidBody
+		if a.Collider == nil {
+			continue
+		}
+
+		// Only take pairs where the other body comes later, so every pair is
+		// found once and always in the same order
+		candidates = candidates[:0]
+		w.broadphase.QueryAABB(a.Bounds(), func(b *RigidBody, j int) bool {
+			if j > i {
+				candidates = append(candidates, j)
+			}
+			return true
+		})
+		sort.Ints(candidates)
+
+		for _, j := range candidates {
+			b := w.Actors[j].RigidBody
+			if !a.ShouldCollide(b) {
+				continue
+			}
+
+			m := a.CheckCollide(b)
+			if m == nil {
+				continue
+			}
+
+			key := bodyPair{a, b}
+			if a.IsTrigger || b.IsTrigger {
+				triggers[key] = m
+				overlaps = append(overlaps, m)
+				continue
+			}
+
+			m.articulated = links[a] || links[b]
+			if w.WarmStarting {
+				w.Stats.CacheHits += m.matchContacts(w.manifolds[key])
+			}
+			w.Stats.Manifolds++
+			w.Stats.Contacts += len(m.Contacts)
+
+			manifolds[key] = m
+			active = append(active, m)
+		}
+	}
+	w.Stats.CacheMisses = w.Stats.Contacts - w.Stats.CacheHits
+
+	for _, m := range active {
+		m.preStep()
+	}
+	for _, m := range active {
+		m.warmStart()
+	}
+	for it := 0; it < w.SolverIterations; it++ {
+		for _, m := range active {
+			m.solve()
+		}
+		// Articulations pass on what the contacts did to their links every
+		// iteration, so the contacts see the rest of the links move too
+		for i := range w.Articulations {
+			w.Articulations[i].resolveContacts()
+		}
+	}
+	// Overlap is fixed once the velocities are solved, by moving the bodies
+	// apart rather than speeding them up
+	for it := 0; it < w.SolverIterations; it++ {
+		for _, m := range active {
+			m.solvePush()
+		}
+	}
+	for _, m := range active {
+		m.A.applyPush(delta)
+		m.B.applyPush(delta)
+	}
+
+	previous := w.manifolds
+	previousActive := w.active
+	w.manifolds = manifolds
+	w.active = active
+
+	previousTriggers := w.triggers
+	previousOverlaps := w.overlaps
+	w.triggers = triggers
+	w.overlaps = overlaps
+
+	w.fireCollisionEvents(previous, previousActive)
+	w.fireTriggerEvents(previousTriggers, previousOverlaps)
+
+	for i := range w.Cloths {
+		w.Cloths[i].Update(w, delta, elapsed)
+	}
+	for i := range w.SoftBodies {
+		w.SoftBodies[i].Update(w, delta, elapsed)
+	}
+	for i := range w.Ropes {
+		w.Ropes[i].Update(w, delta, elapsed)
+	}
+	for i := range w.Fluids {
+		w.Fluids[i].Update(w, delta, elapsed)
+	}
+	for i := range w.ParticleSystems {
+		w.ParticleSystems[i].Update(delta, elapsed)
+	}
+
+	// Sensors read everything once it has finished moving
+	for i := range w.Sensors {
+		w.Sensors[i].Update(w, delta, elapsed)
+	}
+}
+
+func (w *World) fireCollisionEvents(previous map[bodyPair]*ContactManifold, previousActive []*ContactManifold) {
+	for _, m := range w.active {
+		event := newCollisionEvent(m, false)
+		if previous[bodyPair{m.A, m.B}] == nil {
+			fireCollisionEvent(event, w.OnCollisionEnter, m.A.OnCollisionEnter, m.B.OnCollisionEnter)
+		} else {
+			fireCollisionEvent(event, w.OnCollisionStay, m.A.OnCollisionStay, m.B.OnCollisionStay)
+		}
+	}
+
+	for _, m := range previousActive {
+		if w.manifolds[bodyPair{m.A, m.B}] == nil {
+			event := newCollisionEvent(m, true)
+			fireCollisionEvent(event, w.OnCollisionExit, m.A.OnCollisionExit, m.B.OnCollisionExit)
+		}
+	}
+}
+
+func (w *World) fireTriggerEvents(previous map[bodyPair]*ContactManifold, previousOverlaps []*ContactManifold) {
+	for _, m := range w.overlaps {
+		if previous[bodyPair{m.A, m.B}] == nil {
+			event := newCollisionEvent(m, false)
+			fireCollisionEvent(event, w.OnTriggerEnter, m.A.OnTriggerEnter, m.B.OnTriggerEnter)
+		}
+	}
+
+	for _, m := range previousOverlaps {
+		if w.triggers[bodyPair{m.A, m.B}] == nil {
+			event := newCollisionEvent(m, true)
+			fireCollisionEvent(event, w.OnTriggerExit, m.A.OnTriggerExit, m.B.OnTriggerExit)
+		}
+	}
+}
+
+func (w *World) Render(shader *Shader) {
+	for i := 0; i < len(w.Actors); i++ {
+		w.Actors[i].Render(shader)
+	}
+	for i := range w.Cloths {
+		w.Cloths[i].Render(shader)
+	}
+	for i := range w.SoftBodies {
+		w.SoftBodies[i].Render(shader)
+	}
+	for i := range w.Ropes {
+		w.Ropes[i].Render(shader)
+	}
+	for i := range w.Vehicles {
+		w.Vehicles[i].Render(shader)
+	}
+	if w.InstancedShader != nil {
+		for i := range w.Fluids {
+			w.Fluids[i].Render(w.InstancedShader)
+		}
+		for i := range w.ParticleSystems {
+			w.ParticleSystems[i].Render(w.InstancedShader)
+		}
+	}
+}
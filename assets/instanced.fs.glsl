@@ -0,0 +1,11 @@
+#version 330 core
+
+in vec3 p_Normal;
+in vec4 p_Color;
+
+out vec4 o_Color;
+
+void main() {
+    float light = 0.4 + 0.6 * max(dot(normalize(p_Normal), normalize(vec3(0.3, 1.0, 0.5))), 0.0);
+    o_Color = vec4(p_Color.rgb * light, p_Color.a);
+}
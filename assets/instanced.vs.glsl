@@ -0,0 +1,18 @@
+#version 330 core
+
+uniform mat4 u_View;
+uniform mat4 u_Projection;
+
+layout(location = 0) in vec3 a_Vertex;
+layout(location = 1) in vec3 a_Normal;
+layout(location = 3) in vec4 a_Offset;
+layout(location = 4) in vec4 a_Color;
+
+out vec3 p_Normal;
+out vec4 p_Color;
+
+void main() {
+    p_Normal = a_Normal;
+    p_Color = a_Color;
+    gl_Position = u_Projection * u_View * vec4(a_Vertex * a_Offset.w + a_Offset.xyz, 1.0);
+}